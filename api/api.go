@@ -1,8 +1,6 @@
 package api
 
-import "golang.org/x/exp/constraints"
-
-type Map[K constraints.Ordered, V any] interface {
+type Map[K any, V any] interface {
 	// 获取
 	Get(k K) (elem V)
 	// 获取
@@ -19,14 +17,47 @@ type Map[K constraints.Ordered, V any] interface {
 	Range(callback func(k K, v V) bool)
 }
 
-type SortedMap[K constraints.Ordered, V any] interface {
+type SortedMap[K any, V any] interface {
 	Map[K, V]
 	TopMin(limit int, callback func(k K, v V) bool)
 	TopMax(limit int, callback func(k K, v V) bool)
+	// 小于等于k的最大元素
+	Floor(k K) (key K, val V, ok bool)
+	// 大于等于k的最小元素
+	Ceiling(k K) (key K, val V, ok bool)
+	// 小于k的最大元素
+	Lower(k K) (key K, val V, ok bool)
+	// 大于k的最小元素
+	Higher(k K) (key K, val V, ok bool)
+	// 升序遍历lo到hi之间的元素, loInclusive和hiInclusive控制是否包含lo和hi
+	RangeBetween(lo, hi K, loInclusive, hiInclusive bool, callback func(k K, v V) bool)
+	// 从hi到lo降序遍历
+	RangePrevBetween(lo, hi K, loInclusive, hiInclusive bool, callback func(k K, v V) bool)
+}
+
+// 有序容器的迭代器
+// 刚创建的迭代器没有指向任何元素, 需要先调用Seek, First或者Last定位
+type Iterator[K any, V any] interface {
+	// 定位到第一个大于等于k的元素
+	Seek(k K) bool
+	// 定位到第一个元素
+	First() bool
+	// 定位到最后一个元素
+	Last() bool
+	// 移动到下一个元素
+	Next() bool
+	// 移动到上一个元素
+	Prev() bool
+	// 是否指向一个元素
+	Valid() bool
+	Key() K
+	// 返回当前元素现在的值, 定位之后Set修改过也能看到新的值
+	// 当前元素被删除之后返回的是删除前的值
+	Value() V
 }
 
 // TODO
-type Set[K constraints.Ordered] interface {
+type Set[K any] interface {
 	Set(k K)
 }
 
@@ -37,6 +68,10 @@ type Trie[V any] interface {
 	GetWithBool(k string) (v V, found bool)
 	Delete(k string)
 	Len() int
+	// 返回是s前缀的key里面最长的一个
+	LongestPrefixOf(s string) (key string, v V, ok bool)
+	// 从短到长遍历所有是s前缀的key, callback返回false就停止遍历
+	PrefixesOf(s string, callback func(k string, v V) bool)
 }
 
 type CMaper[K comparable, V any] interface {
@@ -47,3 +82,20 @@ type CMaper[K comparable, V any] interface {
 	Range(f func(key K, value V) bool)
 	Store(key K, value V)
 }
+
+// 在锁里面完成的复合操作, 替代Load之后再Store的写法
+type AtomicCMaper[K comparable, V any] interface {
+	CMaper[K, V]
+	// 用fn的返回值更新key, delete为true时删除key, 返回保存的值和key是否存在
+	Compute(key K, fn func(old V, loaded bool) (newValue V, delete bool)) (actual V, ok bool)
+	// key存在时用fn的返回值更新key
+	Update(key K, fn func(old V) V) (newValue V, ok bool)
+	// 保存新值, 返回以前的值
+	Swap(key K, value V) (previous V, loaded bool)
+	// 当前值等于old时替换成new
+	CompareAndSwap(key K, old, new V) (swapped bool)
+	// 当前值等于old时删除
+	CompareAndDelete(key K, old V) (deleted bool)
+	// key不存在时保存fn的返回值, fn只在需要的时候调用
+	LoadOrCompute(key K, fn func() V) (actual V, loaded bool)
+}
@@ -6,6 +6,7 @@ package avltree
 // https://github.com/skywind3000/avlmini
 import (
 	"fmt"
+	"iter"
 
 	"github.com/antlabs/gstl/api"
 	"github.com/antlabs/gstl/cmp"
@@ -13,15 +14,18 @@ import (
 	"golang.org/x/exp/constraints"
 )
 
-var _ api.SortedMap[int, int] = (*AvlTree[int, int])(nil)
+var (
+	_ api.SortedMap[int, int]    = (*AvlTree[int, int])(nil)
+	_ api.SortedMap[[]byte, int] = (*AvlTreeFunc[[]byte, int])(nil)
+)
 
 // 元素
-type pair[K constraints.Ordered, V any] struct {
+type pair[K any, V any] struct {
 	val V
 	key K
 }
 
-type node[K constraints.Ordered, V any] struct {
+type node[K any, V any] struct {
 	left   *node[K, V]
 	right  *node[K, V]
 	parent *node[K, V]
@@ -57,7 +61,7 @@ func (n *node[K, V]) link(parent *node[K, V], link **node[K, V]) {
 	*link = n
 }
 
-type root[K constraints.Ordered, V any] struct {
+type root[K any, V any] struct {
 	node *node[K, V]
 }
 
@@ -173,22 +177,120 @@ func (r *root[K, V]) rotateRight(node *node[K, V]) *node[K, V] {
 	r.childReplace(node, left, parent)
 	node.parent = left
 
-	return node
+	return left
 }
 
-// avl tree的结构
+// avl tree的结构, K必须是有序类型, 零值可以直接使用
+// 其他类型的key(比如[]byte, time.Time, 结构体)使用NewWithCompare创建AvlTreeFunc
 type AvlTree[K constraints.Ordered, V any] struct {
+	AvlTreeFunc[K, V]
+}
+
+// 使用比较函数的avl tree, K可以是任意类型, 必须使用NewWithCompare创建, 零值不能使用
+type AvlTreeFunc[K any, V any] struct {
 	length int
 	root   root[K, V]
+	// 每次新加或者删除节点都会加1, 迭代器用它判断树是否被修改过
+	version int
+	// key的比较函数
+	compare func(a, b K) int
 }
 
 // 构造函数
 func New[K constraints.Ordered, V any]() *AvlTree[K, V] {
-	return &AvlTree[K, V]{}
+	return &AvlTree[K, V]{AvlTreeFunc: AvlTreeFunc[K, V]{compare: cmp.Compare[K]}}
+}
+
+// 使用自定义的比较函数初始化, key可以是任意类型
+// compare(a, b) a < b 返回负数, a == b 返回0, a > b 返回正数
+func NewWithCompare[K any, V any](compare func(a, b K) int) *AvlTreeFunc[K, V] {
+	return &AvlTreeFunc[K, V]{compare: compare}
+}
+
+// 零值的AvlTree没有比较函数, 使用cmp.Compare
+func (a *AvlTree[K, V]) lazyinit() {
+	if a.compare == nil {
+		a.compare = cmp.Compare[K]
+	}
+}
+
+// Get, Set, Delete是最常用的操作, K是有序类型时直接用==和<比较, 不经过比较函数
+func (a *AvlTree[K, V]) Get(k K) (v V) {
+	v, _ = a.GetWithBool(k)
+	return
+}
+
+// 从avl tree找到需要的值
+func (a *AvlTree[K, V]) GetWithBool(k K) (v V, ok bool) {
+	n := a.root.node
+	for n != nil {
+		if n.key == k {
+			return n.val, true
+		}
+
+		if k > n.key {
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+
+	return
+}
+
+func (a *AvlTree[K, V]) Set(k K, v V) {
+	_, _ = a.SetWithPrev(k, v)
+}
+
+// 设置接口, 如果有值, 把prev值带返回, 并且被替换, 没有就新加
+func (a *AvlTree[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
+	a.lazyinit()
+	link := &a.root.node
+	var parent *node[K, V]
+
+	for *link != nil {
+		parent = *link
+		if parent.key == k {
+			prev = parent.val
+			parent.val = v
+			return prev, true
+		}
+
+		if parent.key < k {
+			link = &parent.right
+		} else {
+			link = &parent.left
+		}
+	}
+
+	a.insertNode(parent, link, k, v)
+	return
+}
+
+func (a *AvlTree[K, V]) Delete(k K) {
+	a.Remove(k)
+}
+
+func (a *AvlTree[K, V]) Remove(k K) *AvlTree[K, V] {
+	n := a.root.node
+	for n != nil {
+		if n.key == k {
+			a.removeNode(n)
+			return a
+		}
+
+		if k > n.key {
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+
+	return a
 }
 
 // 第一个节点
-func (a *AvlTree[K, V]) First() (v V, ok bool) {
+func (a *AvlTreeFunc[K, V]) First() (v V, ok bool) {
 	n := a.root.node
 	if n == nil {
 		ok = false
@@ -203,7 +305,7 @@ func (a *AvlTree[K, V]) First() (v V, ok bool) {
 }
 
 // 最后一个节点
-func (a *AvlTree[K, V]) Last() (v V, ok bool) {
+func (a *AvlTreeFunc[K, V]) Last() (v V, ok bool) {
 	n := a.root.node
 	if n == nil {
 		ok = false
@@ -218,20 +320,21 @@ func (a *AvlTree[K, V]) Last() (v V, ok bool) {
 }
 
 // Get
-func (a *AvlTree[K, V]) Get(k K) (v V) {
+func (a *AvlTreeFunc[K, V]) Get(k K) (v V) {
 	v, _ = a.GetWithBool(k)
 	return
 }
 
 // 从avl tree找到需要的值
-func (a *AvlTree[K, V]) GetWithBool(k K) (v V, ok bool) {
+func (a *AvlTreeFunc[K, V]) GetWithBool(k K) (v V, ok bool) {
 	n := a.root.node
 	for n != nil {
-		if n.key == k {
+		c := a.compare(k, n.key)
+		if c == 0 {
 			return n.val, true
 		}
 
-		if k > n.key {
+		if c > 0 {
 			n = n.right
 		} else {
 			n = n.left
@@ -241,35 +344,42 @@ func (a *AvlTree[K, V]) GetWithBool(k K) (v V, ok bool) {
 	return
 }
 
-func (a *AvlTree[K, V]) Set(k K, v V) {
+func (a *AvlTreeFunc[K, V]) Set(k K, v V) {
 	_, _ = a.SetWithPrev(k, v)
 }
 
 // 设置接口, 如果有值, 把prev值带返回, 并且被替换, 没有就新加
-func (a *AvlTree[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
+func (a *AvlTreeFunc[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
 	link := &a.root.node
 	var parent *node[K, V]
-	node := &node[K, V]{pair: pair[K, V]{key: k, val: v}}
 
 	for *link != nil {
 		parent = *link
-		if parent.key == k {
+		c := a.compare(parent.key, k)
+		if c == 0 {
 			prev = parent.val
 			parent.val = v
 			return prev, true
 		}
 
-		if parent.key < k {
+		if c < 0 {
 			link = &parent.right
 		} else {
 			link = &parent.left
 		}
 	}
 
+	a.insertNode(parent, link, k, v)
+	return
+}
+
+// 在link的位置插入新节点, parent是它的父节点
+func (a *AvlTreeFunc[K, V]) insertNode(parent *node[K, V], link **node[K, V], k K, v V) {
+	node := &node[K, V]{pair: pair[K, V]{key: k, val: v}}
 	node.link(parent, link)
 	a.root.postInsert(node)
 	a.length++
-	return
+	a.version++
 }
 
 func (r *root[K, V]) rebalance(node *node[K, V]) {
@@ -294,18 +404,19 @@ func (r *root[K, V]) rebalance(node *node[K, V]) {
 	}
 }
 
-func (a *AvlTree[K, V]) Delete(k K) {
+func (a *AvlTreeFunc[K, V]) Delete(k K) {
 	a.Remove(k)
 }
 
-func (a *AvlTree[K, V]) Remove(k K) *AvlTree[K, V] {
+func (a *AvlTreeFunc[K, V]) Remove(k K) *AvlTreeFunc[K, V] {
 	n := a.root.node
 	for n != nil {
-		if n.key == k {
+		c := a.compare(k, n.key)
+		if c == 0 {
 			goto found
 		}
 
-		if k > n.key {
+		if c > 0 {
 			n = n.right
 		} else {
 			n = n.left
@@ -315,6 +426,12 @@ func (a *AvlTree[K, V]) Remove(k K) *AvlTree[K, V] {
 	return a
 
 found:
+	a.removeNode(n)
+	return a
+}
+
+// 删除节点n
+func (a *AvlTreeFunc[K, V]) removeNode(n *node[K, V]) {
 	var child, parent *node[K, V]
 	if n.left != nil && n.right != nil {
 		old := n
@@ -324,7 +441,8 @@ found:
 		}
 		// 待会儿old被删除时, 使用n贴到old原来的位置
 
-		child = n.left
+		// n是右子树最左边的节点, 只可能有右孩子
+		child = n.right
 		parent = n.parent
 		if child != nil {
 			// child 这条线不再n 节点
@@ -365,7 +483,8 @@ found:
 	if parent != nil {
 		a.root.rebalance(parent)
 	}
-	return a
+	a.length--
+	a.version++
 }
 
 func (n *node[K, V]) rangeInner(callback func(k K, v V) bool) bool {
@@ -418,7 +537,7 @@ func (n *node[K, V]) rangePrevInner(callback func(k K, v V) bool) bool {
 }
 
 // 遍历avl tree
-func (a *AvlTree[K, V]) Range(callback func(k K, v V) bool) {
+func (a *AvlTreeFunc[K, V]) Range(callback func(k K, v V) bool) {
 	// 遍历
 	if a.root.node == nil {
 		return
@@ -429,7 +548,7 @@ func (a *AvlTree[K, V]) Range(callback func(k K, v V) bool) {
 }
 
 // 遍历avl tree
-func (a *AvlTree[K, V]) RangePrev(callback func(k K, v V) bool) {
+func (a *AvlTreeFunc[K, V]) RangePrev(callback func(k K, v V) bool) {
 	// 遍历
 	if a.root.node == nil {
 		return
@@ -439,7 +558,7 @@ func (a *AvlTree[K, V]) RangePrev(callback func(k K, v V) bool) {
 	return
 }
 
-func (a *AvlTree[K, V]) TopMax(limit int, callback func(k K, v V) bool) {
+func (a *AvlTreeFunc[K, V]) TopMax(limit int, callback func(k K, v V) bool) {
 	a.RangePrev(func(k K, v V) bool {
 
 		if limit <= 0 {
@@ -455,7 +574,7 @@ func (a *AvlTree[K, V]) TopMax(limit int, callback func(k K, v V) bool) {
 	})
 }
 
-func (a *AvlTree[K, V]) TopMin(limit int, callback func(k K, v V) bool) {
+func (a *AvlTreeFunc[K, V]) TopMin(limit int, callback func(k K, v V) bool) {
 
 	a.Range(func(k K, v V) bool {
 
@@ -472,11 +591,11 @@ func (a *AvlTree[K, V]) TopMin(limit int, callback func(k K, v V) bool) {
 	})
 }
 
-func (a *AvlTree[K, V]) Len() int {
+func (a *AvlTreeFunc[K, V]) Len() int {
 	return a.length
 }
 
-func (a *AvlTree[K, V]) Draw() {
+func (a *AvlTreeFunc[K, V]) Draw() {
 	if a.root.node == nil {
 		return
 	}
@@ -514,3 +633,203 @@ func (n *node[K, V]) draw(root *node[K, V]) {
 
 	}
 }
+
+// 返回小于等于k的最大元素
+func (a *AvlTreeFunc[K, V]) Floor(k K) (key K, val V, ok bool) {
+	return a.floorNode(k).result()
+}
+
+// 返回大于等于k的最小元素
+func (a *AvlTreeFunc[K, V]) Ceiling(k K) (key K, val V, ok bool) {
+	return a.ceilingNode(k).result()
+}
+
+// 返回小于k的最大元素
+func (a *AvlTreeFunc[K, V]) Lower(k K) (key K, val V, ok bool) {
+	return a.lowerNode(k).result()
+}
+
+// 返回大于k的最小元素
+func (a *AvlTreeFunc[K, V]) Higher(k K) (key K, val V, ok bool) {
+	return a.higherNode(k).result()
+}
+
+func (a *AvlTreeFunc[K, V]) floorNode(k K) (found *node[K, V]) {
+	n := a.root.node
+	for n != nil {
+		c := a.compare(n.key, k)
+		if c == 0 {
+			return n
+		}
+
+		if c < 0 {
+			found = n
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+
+	return
+}
+
+func (a *AvlTreeFunc[K, V]) ceilingNode(k K) (found *node[K, V]) {
+	n := a.root.node
+	for n != nil {
+		c := a.compare(n.key, k)
+		if c == 0 {
+			return n
+		}
+
+		if c > 0 {
+			found = n
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+
+	return
+}
+
+func (a *AvlTreeFunc[K, V]) lowerNode(k K) (found *node[K, V]) {
+	n := a.root.node
+	for n != nil {
+		if a.compare(n.key, k) < 0 {
+			found = n
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+
+	return
+}
+
+func (a *AvlTreeFunc[K, V]) higherNode(k K) (found *node[K, V]) {
+	n := a.root.node
+	for n != nil {
+		if a.compare(n.key, k) > 0 {
+			found = n
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+
+	return
+}
+
+// 把节点转成返回值, 空节点ok为false
+func (n *node[K, V]) result() (key K, val V, ok bool) {
+	if n == nil {
+		return
+	}
+	return n.key, n.val, true
+}
+
+// 中序遍历的后继节点
+func (n *node[K, V]) next() *node[K, V] {
+	if n.right != nil {
+		n = n.right
+		for n.left != nil {
+			n = n.left
+		}
+		return n
+	}
+
+	for n.parent != nil && n == n.parent.right {
+		n = n.parent
+	}
+	return n.parent
+}
+
+// 中序遍历的前驱节点
+func (n *node[K, V]) prev() *node[K, V] {
+	if n.left != nil {
+		n = n.left
+		for n.right != nil {
+			n = n.right
+		}
+		return n
+	}
+
+	for n.parent != nil && n == n.parent.left {
+		n = n.parent
+	}
+	return n.parent
+}
+
+// 升序遍历lo到hi之间的元素, loInclusive和hiInclusive控制是否包含lo和hi
+// 定位lo是O(log n), callback 返回false就停止遍历
+func (a *AvlTreeFunc[K, V]) RangeBetween(lo, hi K, loInclusive, hiInclusive bool, callback func(k K, v V) bool) {
+	var n *node[K, V]
+	if loInclusive {
+		n = a.ceilingNode(lo)
+	} else {
+		n = a.higherNode(lo)
+	}
+
+	for ; n != nil; n = n.next() {
+		if c := a.compare(n.key, hi); c > 0 || !hiInclusive && c == 0 {
+			return
+		}
+
+		if !callback(n.key, n.val) {
+			return
+		}
+	}
+}
+
+// 从hi到lo降序遍历, loInclusive和hiInclusive控制是否包含lo和hi
+// 定位hi是O(log n), callback 返回false就停止遍历
+func (a *AvlTreeFunc[K, V]) RangePrevBetween(lo, hi K, loInclusive, hiInclusive bool, callback func(k K, v V) bool) {
+	var n *node[K, V]
+	if hiInclusive {
+		n = a.floorNode(hi)
+	} else {
+		n = a.lowerNode(hi)
+	}
+
+	for ; n != nil; n = n.prev() {
+		if c := a.compare(n.key, lo); c < 0 || !loInclusive && c == 0 {
+			return
+		}
+
+		if !callback(n.key, n.val) {
+			return
+		}
+	}
+}
+
+// 返回key和value的迭代器, 可以配合for range使用
+func (a *AvlTreeFunc[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		a.Range(yield)
+	}
+}
+
+// 返回key的迭代器
+func (a *AvlTreeFunc[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		a.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+// 返回value的迭代器
+func (a *AvlTreeFunc[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		a.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
+
+// 从大到小返回key和value的迭代器
+func (a *AvlTreeFunc[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		a.RangePrev(yield)
+	}
+}
@@ -1,10 +1,13 @@
 package avltree
 
 import (
+	"bytes"
 	"fmt"
+	"math/rand"
 	"testing"
 
 	"github.com/antlabs/gstl/cmp"
+	"github.com/antlabs/gstl/internal/sortedtest"
 	"github.com/antlabs/gstl/vec"
 	"github.com/stretchr/testify/assert"
 )
@@ -218,3 +221,166 @@ func Test_RanePrev(t *testing.T) {
 	assert.Equal(t, gotKey, dataRev)
 	assert.Equal(t, gotVal, dataRev)
 }
+
+// 测试Floor, Ceiling, Lower, Higher
+func Test_AvlTree_FloorCeiling(t *testing.T) {
+	sortedtest.FloorCeiling(t, New[int, int])
+}
+
+// 随机插入删除之后, 迭代器和查询结果都和参考结果一样, 并且结构没有被破坏
+func Test_AvlTree_SetDeleteRandom(t *testing.T) {
+	sortedtest.SetDeleteRandom(t, New[int, int], func(b *AvlTree[int, int]) {
+		checkBalance(t, b.root.node)
+	})
+}
+
+// NewWithCompare创建的树, Set和Delete走的是另一份查找代码, 同样检查平衡
+func Test_AvlTree_SetDeleteRandomFunc(t *testing.T) {
+	sortedtest.SetDeleteRandom(t, func() *AvlTreeFunc[int, int] {
+		return NewWithCompare[int, int](cmp.Compare[int])
+	}, func(b *AvlTreeFunc[int, int]) {
+		checkBalance(t, b.root.node)
+	})
+}
+
+// 测试RangeBetween和RangePrevBetween
+func Test_AvlTree_RangeBetween(t *testing.T) {
+	sortedtest.RangeBetween(t, New[int, int])
+}
+
+// 检查avl tree的高度和平衡因子
+func checkBalance(t *testing.T, n *node[int, int]) int {
+	if n == nil {
+		return 0
+	}
+
+	lh := checkBalance(t, n.left)
+	rh := checkBalance(t, n.right)
+	assert.Equal(t, n.height, cmp.Max(lh, rh)+1, fmt.Sprintf("key:%d", n.key))
+	assert.True(t, lh-rh <= 1 && lh-rh >= -1, fmt.Sprintf("key:%d, lh:%d, rh:%d", n.key, lh, rh))
+	if n.left != nil {
+		assert.Equal(t, n.left.parent, n)
+	}
+	if n.right != nil {
+		assert.Equal(t, n.right.parent, n)
+	}
+	return cmp.Max(lh, rh) + 1
+}
+
+// 随机插入删除, 删除会走到有两个孩子节点的分支
+func Test_AvlTree_DeleteRandom(t *testing.T) {
+	b := New[int, int]()
+	max := 1000
+	r := rand.New(rand.NewSource(1))
+	keys := r.Perm(max)
+	for _, k := range keys {
+		b.Set(k, k)
+	}
+	checkBalance(t, b.root.node)
+
+	deleted := map[int]bool{}
+	for _, k := range keys[:max/2] {
+		b.Delete(k)
+		deleted[k] = true
+	}
+
+	// 删除不存在的key, 长度不变
+	b.Delete(max + 1)
+
+	assert.Equal(t, b.Len(), max-max/2)
+	checkBalance(t, b.root.node)
+	for i := 0; i < max; i++ {
+		_, ok := b.GetWithBool(i)
+		assert.Equal(t, ok, !deleted[i], fmt.Sprintf("index:%d", i))
+	}
+}
+
+// 测试迭代器
+func Test_AvlTree_Iterator(t *testing.T) {
+	sortedtest.Iterator(t, New[int, int])
+}
+
+// 迭代的过程中修改容器
+func Test_AvlTree_IteratorMutation(t *testing.T) {
+	sortedtest.IteratorMutation(t, New[int, int])
+}
+
+// 使用两个迭代器合并两个有序容器
+func Test_AvlTree_IteratorMerge(t *testing.T) {
+	sortedtest.IteratorMerge(t, New[int, int])
+}
+
+// 测试range over func
+func Test_AvlTree_Iter(t *testing.T) {
+	sortedtest.Iter(t, New[int, int])
+}
+
+// key不是Ordered类型, 使用自定义的比较函数
+func Test_AvlTree_NewWithCompare(t *testing.T) {
+	sortedtest.NewWithCompare(t, NewWithCompare[sortedtest.Point, int])
+}
+
+// 反过来的比较函数, 和Ordered版本的结果顺序相反
+func Test_AvlTree_NewWithCompareReverse(t *testing.T) {
+	sortedtest.NewWithCompareReverse(t, NewWithCompare[int, int])
+}
+
+type myString string
+
+// 零值的AvlTree可以直接使用, 读操作不需要先调用Set
+func Test_AvlTree_ZeroValue(t *testing.T) {
+	var b AvlTree[myString, int]
+	assert.Equal(t, b.Get("a"), 0)
+	_, ok := b.GetWithBool("a")
+	assert.False(t, ok)
+	_, ok = b.First()
+	assert.False(t, ok)
+	_, ok = b.Last()
+	assert.False(t, ok)
+	_, _, ok = b.Floor("a")
+	assert.False(t, ok)
+	_, _, ok = b.Ceiling("a")
+	assert.False(t, ok)
+	_, _, ok = b.Lower("a")
+	assert.False(t, ok)
+	_, _, ok = b.Higher("a")
+	assert.False(t, ok)
+
+	count := 0
+	callback := func(myString, int) bool {
+		count++
+		return true
+	}
+	b.Range(callback)
+	b.RangePrev(callback)
+	b.TopMin(1, callback)
+	b.TopMax(1, callback)
+	b.RangeBetween("a", "b", true, true, callback)
+	b.RangePrevBetween("a", "b", true, true, callback)
+	for range b.All() {
+		count++
+	}
+	assert.Equal(t, count, 0)
+	assert.False(t, b.Iterator().Seek("a"))
+	b.Delete("a")
+
+	for i, s := range []myString{"c", "a", "b"} {
+		b.Set(s, i)
+	}
+
+	var got []myString
+	for k := range b.Keys() {
+		got = append(got, k)
+	}
+	assert.Equal(t, got, []myString{"a", "b", "c"})
+	assert.Equal(t, b.Get("c"), 0)
+}
+
+// 非有序类型的key需要使用NewWithCompare
+func Test_AvlTree_NewWithCompareBytes(t *testing.T) {
+	a := NewWithCompare[[]byte, int](bytes.Compare)
+	a.Set([]byte("b"), 2)
+	a.Set([]byte("a"), 1)
+	assert.Equal(t, a.Get([]byte("a")), 1)
+	assert.Equal(t, a.Remove([]byte("a")).Len(), 1)
+}
@@ -6,32 +6,48 @@ package btree
 // https://github.com/tidwall/btree
 import (
 	"fmt"
+	"iter"
 
 	"github.com/antlabs/gstl/api"
+	"github.com/antlabs/gstl/cmp"
 	"github.com/antlabs/gstl/must"
 	"github.com/antlabs/gstl/vec"
 	"golang.org/x/exp/constraints"
 )
 
-var _ api.SortedMap[int, int] = (*Btree[int, int])(nil)
+var (
+	_ api.SortedMap[int, int]    = (*Btree[int, int])(nil)
+	_ api.SortedMap[[]byte, int] = (*BtreeFunc[[]byte, int])(nil)
+)
+
 var notFound = "not found element"
 
-// btree头结点
+// btree头结点, K必须是有序类型, 必须使用New创建, 零值不能使用
+// 其他类型的key(比如[]byte, time.Time, 结构体)使用NewWithCompare创建BtreeFunc
 type Btree[K constraints.Ordered, V any] struct {
+	BtreeFunc[K, V]
+}
+
+// 使用比较函数的btree, K可以是任意类型, 必须使用NewWithCompare创建, 零值不能使用
+type BtreeFunc[K any, V any] struct {
 	count    int         //当前元素个数
 	root     *node[K, V] // root结点指针
 	maxItems int
 	minItems int
+	// 每次新加或者删除元素都会加1, 迭代器用它判断树是否被修改过
+	version int
+	// key的比较函数
+	compare func(a, b K) int
 }
 
 // 元素
-type pair[K constraints.Ordered, V any] struct {
+type pair[K any, V any] struct {
 	val V
 	key K
 }
 
 // btree树的结点的组成
-type node[K constraints.Ordered, V any] struct {
+type node[K any, V any] struct {
 	items    *vec.Vec[pair[K, V]]  //存放元素的节点
 	children *vec.Vec[*node[K, V]] //孩子节点
 }
@@ -41,31 +57,38 @@ func (n *node[K, V]) leaf() bool {
 }
 
 func New[K constraints.Ordered, V any](degree int) *Btree[K, V] {
+	return &Btree[K, V]{BtreeFunc: *NewWithCompare[K, V](degree, cmp.Compare[K])}
+}
+
+// 使用自定义的比较函数初始化, key可以是任意类型
+// compare(a, b) a < b 返回负数, a == b 返回0, a > b 返回正数
+func NewWithCompare[K any, V any](degree int, compare func(a, b K) int) *BtreeFunc[K, V] {
 
 	if degree == 0 {
 		degree = 128 //拍脑袋给的, 需要压测下
 	}
 
 	maxItems := degree*2 - 1 // max items per node. max children is +1
-	return &Btree[K, V]{
+	return &BtreeFunc[K, V]{
 		maxItems: maxItems,
 		minItems: maxItems / 2,
+		compare:  compare,
 	}
 }
 
 // 返回btree中元素的个数
-func (b *Btree[K, V]) Len() int {
+func (b *BtreeFunc[K, V]) Len() int {
 	return b.count
 }
 
 // 设置接口, 如果有这个值, 有值就替换, 没有就新加
-func (b *Btree[K, V]) Set(k K, v V) {
+func (b *BtreeFunc[K, V]) Set(k K, v V) {
 
 	_, _ = b.SetWithPrev(k, v)
 }
 
 // 新建一个节点
-func (b *Btree[K, V]) newNode(leaf bool) (n *node[K, V]) {
+func (b *BtreeFunc[K, V]) newNode(leaf bool) (n *node[K, V]) {
 	n = &node[K, V]{}
 	if !leaf {
 		n.children = vec.New[*node[K, V]]()
@@ -74,22 +97,34 @@ func (b *Btree[K, V]) newNode(leaf bool) (n *node[K, V]) {
 }
 
 // 新建叶子节点
-func (b *Btree[K, V]) newLeaf() *node[K, V] {
+func (b *BtreeFunc[K, V]) newLeaf() *node[K, V] {
 	return b.newNode(true)
 }
 
-func (b *Btree[K, V]) find(n *node[K, V], key K) (index int, found bool) {
+// 在节点里二分查找key, 找到返回key的位置, 找不到返回第一个比key大的元素的位置
+// 直接写二分, 每一步只调用一次比较函数, 不用再包一层SearchFunc的闭包
+func (b *BtreeFunc[K, V]) find(n *node[K, V], key K) (index int, found bool) {
+	items := *n.items
+	i, j := 0, len(items)
+	for i < j {
+		h := int(uint(i+j) >> 1)
+		c := b.compare(key, items[h].key)
+		if c == 0 {
+			return h, true
+		}
 
-	index = n.items.SearchFunc(func(elem pair[K, V]) bool { return key < elem.key })
-	if index > 0 && n.items.Get(index-1).key >= key {
-		return index - 1, true
+		if c > 0 {
+			i = h + 1
+		} else {
+			j = h
+		}
 	}
 
-	return index, false
+	return i, false
 }
 
 // 分裂结点
-func (b *Btree[K, V]) nodeSplit(n *node[K, V]) (right *node[K, V], median pair[K, V]) {
+func (b *BtreeFunc[K, V]) nodeSplit(n *node[K, V]) (right *node[K, V], median pair[K, V]) {
 	i := b.maxItems / 2
 	//fmt.Printf("nodeSplit:%#v i(%d):len(%d)\n", n.items, i, n.items.Len())
 	median = n.items.Get(i)
@@ -112,7 +147,7 @@ func (b *Btree[K, V]) nodeSplit(n *node[K, V]) (right *node[K, V], median pair[K
 }
 
 // 把k/v的值放到结点里面
-func (b *Btree[K, V]) nodeSet(n *node[K, V], item pair[K, V]) (prev V, replaced bool, needSplit bool) {
+func (b *BtreeFunc[K, V]) nodeSet(n *node[K, V], item pair[K, V]) (prev V, replaced bool, needSplit bool) {
 	i, found := b.find(n, item.key)
 	// 找到位置直接替换
 	if found {
@@ -155,7 +190,7 @@ func (b *Btree[K, V]) nodeSet(n *node[K, V], item pair[K, V]) (prev V, replaced
 }
 
 // 设置接口, 如果有值, 把prev值带返回, 并且被替换, 没有就新加
-func (b *Btree[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
+func (b *BtreeFunc[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
 	item := pair[K, V]{key: k, val: v}
 	// 如果是每一个节点, 直接加入到root节点
 	if b.root == nil {
@@ -165,6 +200,7 @@ func (b *Btree[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
 		}
 		b.root.items.Push(item)
 		b.count = 1
+		b.version++
 		return
 	}
 
@@ -192,18 +228,19 @@ func (b *Btree[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
 		return prev, true
 	}
 	b.count++
+	b.version++
 	return
 }
 
 // 获取值, 忽略找不到的情况
-func (b *Btree[K, V]) Get(k K) (v V) {
+func (b *BtreeFunc[K, V]) Get(k K) (v V) {
 	v, _ = b.GetWithBool(k)
 	return
 }
 
 // 找到ok为true
 // 找不到ok为false
-func (b *Btree[K, V]) GetWithBool(k K) (v V, ok bool) {
+func (b *BtreeFunc[K, V]) GetWithBool(k K) (v V, ok bool) {
 	if b.root == nil {
 		return
 	}
@@ -224,12 +261,12 @@ func (b *Btree[K, V]) GetWithBool(k K) (v V, ok bool) {
 }
 
 // 删除接口
-func (b *Btree[K, V]) Delete(k K) {
+func (b *BtreeFunc[K, V]) Delete(k K) {
 	b.DeleteWithPrev(k)
 }
 
 // 删除接口, 返回旧值
-func (b *Btree[K, V]) DeleteWithPrev(k K) (prev V, deleted bool) {
+func (b *BtreeFunc[K, V]) DeleteWithPrev(k K) (prev V, deleted bool) {
 	if b.root == nil {
 		return
 	}
@@ -248,13 +285,14 @@ func (b *Btree[K, V]) DeleteWithPrev(k K) (prev V, deleted bool) {
 	}
 
 	b.count--
+	b.version++
 	if b.count == 0 {
 		b.root = nil
 	}
 	return prevPair.val, true
 }
 
-func (b *Btree[K, V]) delete(n *node[K, V], max bool, k K) (prev pair[K, V], deleted bool) {
+func (b *BtreeFunc[K, V]) delete(n *node[K, V], max bool, k K) (prev pair[K, V], deleted bool) {
 
 	var i int
 	var found bool
@@ -304,7 +342,7 @@ func (b *Btree[K, V]) delete(n *node[K, V], max bool, k K) (prev pair[K, V], del
 	return prev, true
 }
 
-func (b *Btree[K, V]) rebalance(n *node[K, V], i int) {
+func (b *BtreeFunc[K, V]) rebalance(n *node[K, V], i int) {
 	if i == n.items.Len() {
 		i--
 	}
@@ -375,7 +413,7 @@ func (b *Btree[K, V]) rebalance(n *node[K, V], i int) {
 }
 
 // 遍历b tree
-func (b *Btree[K, V]) Range(callback func(k K, v V) bool) {
+func (b *BtreeFunc[K, V]) Range(callback func(k K, v V) bool) {
 	// 遍历
 	if b.root == nil {
 		return
@@ -386,7 +424,7 @@ func (b *Btree[K, V]) Range(callback func(k K, v V) bool) {
 }
 
 // 返回最小的n个值, 升序返回, 比如0,1,2,3
-func (b *Btree[K, V]) TopMin(limit int, callback func(k K, v V) bool) {
+func (b *BtreeFunc[K, V]) TopMin(limit int, callback func(k K, v V) bool) {
 	b.Range(func(k K, v V) bool {
 		if limit <= 0 {
 			return false
@@ -397,7 +435,7 @@ func (b *Btree[K, V]) TopMin(limit int, callback func(k K, v V) bool) {
 	})
 }
 
-func (b *Btree[K, V]) Draw() {
+func (b *BtreeFunc[K, V]) Draw() {
 	if b.root == nil {
 		return
 	}
@@ -470,6 +508,12 @@ func (n *node[K, V]) rangeInner(callback func(k K, v V) bool) bool {
 
 // 从后向前倒序遍历b tree
 func (b *Btree[K, V]) RangePrev(callback func(k K, v V) bool) *Btree[K, V] {
+	b.BtreeFunc.RangePrev(callback)
+	return b
+}
+
+// 从后向前倒序遍历b tree
+func (b *BtreeFunc[K, V]) RangePrev(callback func(k K, v V) bool) *BtreeFunc[K, V] {
 	// 遍历
 	if b.root == nil {
 		return b
@@ -480,7 +524,7 @@ func (b *Btree[K, V]) RangePrev(callback func(k K, v V) bool) *Btree[K, V] {
 }
 
 // 返回最大的n个值, 降序返回, 10, 9, 8, 7
-func (b *Btree[K, V]) TopMax(limit int, callback func(k K, v V) bool) {
+func (b *BtreeFunc[K, V]) TopMax(limit int, callback func(k K, v V) bool) {
 	b.RangePrev(func(k K, v V) bool {
 		if limit <= 0 {
 			return false
@@ -518,3 +562,214 @@ func (n *node[K, V]) rangePrevInner(callback func(k K, v V) bool) bool {
 
 	return true
 }
+
+// 返回小于等于k的最大元素
+func (b *BtreeFunc[K, V]) Floor(k K) (key K, val V, ok bool) {
+	var found *pair[K, V]
+	for n := b.root; n != nil; {
+		// items[:i]都小于等于k
+		i := n.items.SearchFunc(func(elem pair[K, V]) bool { return b.compare(k, elem.key) < 0 })
+		if i > 0 {
+			found = n.items.GetPtr(i - 1)
+			if b.compare(found.key, k) == 0 {
+				break
+			}
+		}
+
+		if n.leaf() {
+			break
+		}
+		n = n.children.Get(i)
+	}
+
+	return found.result()
+}
+
+// 返回大于等于k的最小元素
+func (b *BtreeFunc[K, V]) Ceiling(k K) (key K, val V, ok bool) {
+	var found *pair[K, V]
+	for n := b.root; n != nil; {
+		// items[:i]都小于k
+		i := n.items.SearchFunc(func(elem pair[K, V]) bool { return b.compare(k, elem.key) <= 0 })
+		if i < n.items.Len() {
+			found = n.items.GetPtr(i)
+			if b.compare(found.key, k) == 0 {
+				break
+			}
+		}
+
+		if n.leaf() {
+			break
+		}
+		n = n.children.Get(i)
+	}
+
+	return found.result()
+}
+
+// 返回小于k的最大元素
+func (b *BtreeFunc[K, V]) Lower(k K) (key K, val V, ok bool) {
+	var found *pair[K, V]
+	for n := b.root; n != nil; {
+		// items[:i]都小于k
+		i := n.items.SearchFunc(func(elem pair[K, V]) bool { return b.compare(k, elem.key) <= 0 })
+		if i > 0 {
+			found = n.items.GetPtr(i - 1)
+		}
+
+		if n.leaf() {
+			break
+		}
+		n = n.children.Get(i)
+	}
+
+	return found.result()
+}
+
+// 返回大于k的最小元素
+func (b *BtreeFunc[K, V]) Higher(k K) (key K, val V, ok bool) {
+	var found *pair[K, V]
+	for n := b.root; n != nil; {
+		// items[:i]都小于等于k
+		i := n.items.SearchFunc(func(elem pair[K, V]) bool { return b.compare(k, elem.key) < 0 })
+		if i < n.items.Len() {
+			found = n.items.GetPtr(i)
+		}
+
+		if n.leaf() {
+			break
+		}
+		n = n.children.Get(i)
+	}
+
+	return found.result()
+}
+
+// 把元素转成返回值, 空指针ok为false
+func (p *pair[K, V]) result() (key K, val V, ok bool) {
+	if p == nil {
+		return
+	}
+	return p.key, p.val, true
+}
+
+// 升序遍历lo到hi之间的元素, loInclusive和hiInclusive控制是否包含lo和hi
+// 定位lo是O(log n), callback 返回false就停止遍历
+func (b *BtreeFunc[K, V]) RangeBetween(lo, hi K, loInclusive, hiInclusive bool, callback func(k K, v V) bool) {
+	if b.root == nil {
+		return
+	}
+
+	b.rangeBetweenInner(b.root, lo, hi, loInclusive, hiInclusive, callback)
+}
+
+// 从hi到lo降序遍历, loInclusive和hiInclusive控制是否包含lo和hi
+// 定位hi是O(log n), callback 返回false就停止遍历
+func (b *BtreeFunc[K, V]) RangePrevBetween(lo, hi K, loInclusive, hiInclusive bool, callback func(k K, v V) bool) {
+	if b.root == nil {
+		return
+	}
+
+	b.rangePrevBetweenInner(b.root, lo, hi, loInclusive, hiInclusive, callback)
+}
+
+// 返回false表示已经超过hi, 或者callback要求停止
+func (b *BtreeFunc[K, V]) rangeBetweenInner(n *node[K, V], lo, hi K, loInclusive, hiInclusive bool, callback func(k K, v V) bool) bool {
+	// 跳过所有小于lo的元素, 对应的孩子节点也不用访问
+	i := n.items.SearchFunc(func(elem pair[K, V]) bool {
+		if loInclusive {
+			return b.compare(lo, elem.key) <= 0
+		}
+		return b.compare(lo, elem.key) < 0
+	})
+
+	for l := n.items.Len(); i < l; i++ {
+		if !n.leaf() {
+			if !b.rangeBetweenInner(n.children.Get(i), lo, hi, loInclusive, hiInclusive, callback) {
+				return false
+			}
+		}
+
+		item := n.items.GetPtr(i)
+		if c := b.compare(item.key, hi); c > 0 || !hiInclusive && c == 0 {
+			return false
+		}
+
+		if !callback(item.key, item.val) {
+			return false
+		}
+	}
+
+	if !n.leaf() {
+		return b.rangeBetweenInner(must.TakeOneDiscardBool(n.children.Last()), lo, hi, loInclusive, hiInclusive, callback)
+	}
+	return true
+}
+
+// 返回false表示已经小于lo, 或者callback要求停止
+func (b *BtreeFunc[K, V]) rangePrevBetweenInner(n *node[K, V], lo, hi K, loInclusive, hiInclusive bool, callback func(k K, v V) bool) bool {
+	// items[i:]都大于hi, 对应的孩子节点也不用访问
+	i := n.items.SearchFunc(func(elem pair[K, V]) bool {
+		if hiInclusive {
+			return b.compare(hi, elem.key) < 0
+		}
+		return b.compare(hi, elem.key) <= 0
+	})
+
+	if !n.leaf() {
+		if !b.rangePrevBetweenInner(n.children.Get(i), lo, hi, loInclusive, hiInclusive, callback) {
+			return false
+		}
+	}
+
+	for i--; i >= 0; i-- {
+		item := n.items.GetPtr(i)
+		if c := b.compare(item.key, lo); c < 0 || !loInclusive && c == 0 {
+			return false
+		}
+
+		if !callback(item.key, item.val) {
+			return false
+		}
+
+		if !n.leaf() {
+			if !b.rangePrevBetweenInner(n.children.Get(i), lo, hi, loInclusive, hiInclusive, callback) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// 返回key和value的迭代器, 可以配合for range使用
+func (b *BtreeFunc[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		b.Range(yield)
+	}
+}
+
+// 返回key的迭代器
+func (b *BtreeFunc[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		b.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+// 返回value的迭代器
+func (b *BtreeFunc[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		b.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
+
+// 从大到小返回key和value的迭代器
+func (b *BtreeFunc[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		b.RangePrev(yield)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/antlabs/gstl/cmp"
+	"github.com/antlabs/gstl/internal/sortedtest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -378,3 +379,120 @@ func Test_Btree_Delete2(t *testing.T) {
 
 	}
 }
+
+// 度数是2的btree, 元素不多就会分裂和合并
+func newBtree() *Btree[int, int] {
+	return New[int, int](2)
+}
+
+// 测试Floor, Ceiling, Lower, Higher
+func Test_Btree_FloorCeiling(t *testing.T) {
+	sortedtest.FloorCeiling(t, newBtree)
+}
+
+// 测试RangeBetween和RangePrevBetween
+func Test_Btree_RangeBetween(t *testing.T) {
+	sortedtest.RangeBetween(t, newBtree)
+}
+
+// 测试迭代器
+func Test_Btree_Iterator(t *testing.T) {
+	sortedtest.Iterator(t, newBtree)
+}
+
+// 迭代的过程中修改容器
+func Test_Btree_IteratorMutation(t *testing.T) {
+	sortedtest.IteratorMutation(t, newBtree)
+}
+
+// 检查btree的结构: 非根结点的元素个数在[minItems, maxItems]之间, 叶子在同一层, 孩子比元素多一个
+func checkNode[K any, V any](t *testing.T, b *BtreeFunc[K, V], n *node[K, V], depth int, leafDepth *int) {
+	if n != b.root {
+		assert.GreaterOrEqual(t, n.items.Len(), b.minItems)
+	}
+	assert.LessOrEqual(t, n.items.Len(), b.maxItems)
+	if n.leaf() {
+		if *leafDepth == -1 {
+			*leafDepth = depth
+		}
+		assert.Equal(t, depth, *leafDepth)
+		return
+	}
+
+	assert.Equal(t, n.children.Len(), n.items.Len()+1)
+	for i := 0; i < n.children.Len(); i++ {
+		checkNode(t, b, n.children.Get(i), depth+1, leafDepth)
+	}
+}
+
+// 随机混合Set和Delete, 每一步都用迭代器和排好序的参考结果对比, 并且结构没有被破坏
+// 向左兄弟借元素时用到vec.Pop, 以前Pop没有真正弹出元素, 混合Set和Delete后会出现重复的key
+func Test_Btree_SetDeleteRandom(t *testing.T) {
+	for _, degree := range []int{2, 3, 4, 8} {
+		newMap := func() *Btree[int, int] {
+			return New[int, int](degree)
+		}
+		sortedtest.SetDeleteRandom(t, newMap, func(b *Btree[int, int]) {
+			if b.root != nil {
+				leafDepth := -1
+				checkNode(t, &b.BtreeFunc, b.root, 0, &leafDepth)
+			}
+		})
+	}
+}
+
+// 比如degree是2, 插入4, 3, 2, 1, 0之后删除4, 右边的孩子不够时会向左边的兄弟借元素(left.items.Pop和left.children.Pop)
+// 借走的元素要从左边删掉, 否则同一个key会同时出现在左边和父节点里面
+func Test_Btree_DeleteBorrowLeft(t *testing.T) {
+	for _, degree := range []int{2, 3} {
+		b := New[int, int](degree)
+		max := 100
+		// 倒序插入, 左边的孩子比右边的多
+		for i := max - 1; i >= 0; i-- {
+			b.Set(i, i)
+		}
+
+		for i := max - 1; i >= 0; i-- {
+			b.Delete(i)
+			if b.root != nil {
+				leafDepth := -1
+				checkNode(t, &b.BtreeFunc, b.root, 0, &leafDepth)
+			}
+
+			var keys []int
+			b.Range(func(k, _ int) bool {
+				keys = append(keys, k)
+				return true
+			})
+			assert.Equal(t, len(keys), i, "degree:%d", degree)
+			assert.Equal(t, b.Len(), i, "degree:%d", degree)
+			for j, k := range keys {
+				assert.Equal(t, k, j, "degree:%d", degree)
+			}
+		}
+	}
+}
+
+// 使用两个迭代器合并两个有序容器
+func Test_Btree_IteratorMerge(t *testing.T) {
+	sortedtest.IteratorMerge(t, newBtree)
+}
+
+// 测试range over func
+func Test_Btree_Iter(t *testing.T) {
+	sortedtest.Iter(t, newBtree)
+}
+
+// key不是Ordered类型, 使用自定义的比较函数
+func Test_Btree_NewWithCompare(t *testing.T) {
+	sortedtest.NewWithCompare(t, func(compare func(a, b sortedtest.Point) int) *BtreeFunc[sortedtest.Point, int] {
+		return NewWithCompare[sortedtest.Point, int](2, compare)
+	})
+}
+
+// 反过来的比较函数, 和Ordered版本的结果顺序相反
+func Test_Btree_NewWithCompareReverse(t *testing.T) {
+	sortedtest.NewWithCompareReverse(t, func(compare func(a, b int) int) *BtreeFunc[int, int] {
+		return NewWithCompare[int, int](2, compare)
+	})
+}
@@ -0,0 +1,223 @@
+package btree
+
+// apache 2.0 antlabs
+import (
+	"github.com/antlabs/gstl/api"
+)
+
+var _ api.Iterator[int, int] = (*Iterator[int, int])(nil)
+
+// 迭代器从root到当前元素经过的路径
+// 最上面一层(栈顶)的index指向当前元素, 其它层的index指向正在访问的孩子节点
+type frame[K any, V any] struct {
+	n     *node[K, V]
+	index int
+}
+
+// Btree的迭代器, 可以暂停, 恢复, 双向移动
+// 迭代器创建之后如果Btree被修改(新加或者删除元素), 节点可能会分裂或者合并, 迭代器保存的路径就失效了.
+// 这时迭代器不会出错, 下次调用Next/Prev时会根据当前的key重新定位到它的后继/前驱(O(log n)).
+// Value返回的是当前的值(Set修改过可以看到新的值), 如果当前元素已经被删除, Key/Value返回的还是删除前的数据
+type Iterator[K any, V any] struct {
+	b       *BtreeFunc[K, V]
+	stack   []frame[K, V]
+	item    pair[K, V]
+	version int
+}
+
+// 创建一个迭代器, 使用之前需要先调用Seek, First或者Last定位
+func (b *BtreeFunc[K, V]) Iterator() *Iterator[K, V] {
+	return &Iterator[K, V]{b: b}
+}
+
+func (it *Iterator[K, V]) reset() {
+	it.stack = it.stack[:0]
+	it.version = it.b.version
+}
+
+func (it *Iterator[K, V]) push(n *node[K, V], index int) {
+	it.stack = append(it.stack, frame[K, V]{n: n, index: index})
+}
+
+func (it *Iterator[K, V]) top() *frame[K, V] {
+	return &it.stack[len(it.stack)-1]
+}
+
+// 保存当前元素, 返回迭代器是否有效
+func (it *Iterator[K, V]) load() bool {
+	if len(it.stack) == 0 {
+		return false
+	}
+
+	top := it.top()
+	it.item = top.n.items.Get(top.index)
+	return true
+}
+
+// 栈顶节点的元素向后访问完了, 回到父节点, 父节点的index正好指向下一个元素
+func (it *Iterator[K, V]) upNext() bool {
+	for len(it.stack) > 0 && it.top().index >= it.top().n.items.Len() {
+		it.stack = it.stack[:len(it.stack)-1]
+	}
+	return it.load()
+}
+
+// 栈顶节点的元素向前访问完了, 回到父节点, 父节点的index-1是上一个元素
+func (it *Iterator[K, V]) upPrev() bool {
+	for len(it.stack) > 0 && it.top().index < 0 {
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) > 0 {
+			it.top().index--
+		}
+	}
+	return it.load()
+}
+
+// 从n开始一直往左下走到叶子节点
+func (it *Iterator[K, V]) leftmost(n *node[K, V]) {
+	for !n.leaf() {
+		it.push(n, 0)
+		n = n.children.Get(0)
+	}
+	it.push(n, 0)
+}
+
+// 从n开始一直往右下走到叶子节点
+func (it *Iterator[K, V]) rightmost(n *node[K, V]) {
+	for !n.leaf() {
+		it.push(n, n.items.Len())
+		n = n.children.Get(n.items.Len())
+	}
+	it.push(n, n.items.Len()-1)
+}
+
+// 定位到第一个大于k(inclusive为true时是大于等于k)的元素
+func (it *Iterator[K, V]) seek(k K, inclusive bool) bool {
+	it.reset()
+	for n := it.b.root; n != nil; {
+		i := n.items.SearchFunc(func(elem pair[K, V]) bool {
+			if inclusive {
+				return it.b.compare(k, elem.key) <= 0
+			}
+			return it.b.compare(k, elem.key) < 0
+		})
+
+		it.push(n, i)
+		if inclusive && i < n.items.Len() && it.b.compare(n.items.Get(i).key, k) == 0 {
+			return it.load()
+		}
+
+		if n.leaf() {
+			break
+		}
+		n = n.children.Get(i)
+	}
+
+	return it.upNext()
+}
+
+// 定位到最后一个小于k的元素
+func (it *Iterator[K, V]) seekLower(k K) bool {
+	it.reset()
+	for n := it.b.root; n != nil; {
+		i := n.items.SearchFunc(func(elem pair[K, V]) bool { return it.b.compare(k, elem.key) <= 0 })
+		if n.leaf() {
+			it.push(n, i-1)
+			break
+		}
+
+		it.push(n, i)
+		n = n.children.Get(i)
+	}
+
+	return it.upPrev()
+}
+
+// 定位到第一个大于等于k的元素
+func (it *Iterator[K, V]) Seek(k K) bool {
+	return it.seek(k, true)
+}
+
+// 定位到第一个元素
+func (it *Iterator[K, V]) First() bool {
+	it.reset()
+	if it.b.root == nil {
+		return false
+	}
+
+	it.leftmost(it.b.root)
+	return it.upNext()
+}
+
+// 定位到最后一个元素
+func (it *Iterator[K, V]) Last() bool {
+	it.reset()
+	if it.b.root == nil {
+		return false
+	}
+
+	it.rightmost(it.b.root)
+	return it.upPrev()
+}
+
+// 移动到下一个元素, 没有下一个元素返回false
+func (it *Iterator[K, V]) Next() bool {
+	if len(it.stack) == 0 {
+		return false
+	}
+
+	if it.version != it.b.version {
+		return it.seek(it.item.key, false)
+	}
+
+	top := it.top()
+	top.index++
+	if !top.n.leaf() {
+		// 下一个元素在右边孩子节点的最左边
+		it.leftmost(top.n.children.Get(top.index))
+	}
+	return it.upNext()
+}
+
+// 移动到上一个元素, 没有上一个元素返回false
+func (it *Iterator[K, V]) Prev() bool {
+	if len(it.stack) == 0 {
+		return false
+	}
+
+	if it.version != it.b.version {
+		return it.seekLower(it.item.key)
+	}
+
+	top := it.top()
+	if !top.n.leaf() {
+		// 上一个元素在左边孩子节点的最右边
+		it.rightmost(top.n.children.Get(top.index))
+		return it.upPrev()
+	}
+
+	top.index--
+	return it.upPrev()
+}
+
+// 是否指向一个元素
+func (it *Iterator[K, V]) Valid() bool {
+	return len(it.stack) > 0
+}
+
+// 返回当前元素的key, 调用之前需要保证Valid()为true
+func (it *Iterator[K, V]) Key() K {
+	return it.item.key
+}
+
+// 返回当前元素的value, 调用之前需要保证Valid()为true
+func (it *Iterator[K, V]) Value() V {
+	if it.version == it.b.version {
+		top := it.top()
+		it.item.val = top.n.items.Get(top.index).val
+	} else if v, ok := it.b.GetWithBool(it.item.key); ok {
+		// 路径已经失效, 根据key重新查找
+		it.item.val = v
+	}
+	return it.item.val
+}
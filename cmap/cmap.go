@@ -1,125 +1,415 @@
 package cmap
 
 import (
-	"reflect"
+	"context"
+	"iter"
 	"runtime"
 	"sync"
-	"unsafe"
+	"sync/atomic"
 
 	"github.com/antlabs/gstl/api"
-	xxhash "github.com/cespare/xxhash/v2"
-	"golang.org/x/exp/constraints"
+	"github.com/antlabs/gstl/cmp"
+	"github.com/antlabs/gstl/hasher"
+	"github.com/antlabs/gstl/rbtree"
+	"github.com/antlabs/gstl/rhashmap"
 )
 
-var _ api.CMaper[int, int] = (*CMap[int, int])(nil)
+var _ api.AtomicCMaper[int, int] = (*CMap[int, int])(nil)
 
-type Pair[K constraints.Ordered, V any] struct {
+type Pair[K comparable, V any] struct {
 	Key K
 	Val V
 }
 
-type CMap[K constraints.Ordered, V any] struct {
-	bucket   []Item[K, V]
-	keySize  int
-	isKeyStr bool
+type CMap[K comparable, V any] struct {
+	// Reshard的时候会换成新的分片表
+	table  atomic.Pointer[table[K, V]]
+	hasher hasher.Hasher[K]
+	// 创建分片里面的map
+	newMap func() api.Map[K, V]
+	// 同一时间只能有一个Reshard
+	reshardMu sync.Mutex
+	// 过期相关的字段, 见ttl.go
+	expire[K, V]
 }
 
-type Item[K constraints.Ordered, V any] struct {
+// 分片表
+type table[K comparable, V any] struct {
+	bucket []Item[K, V]
+}
+
+type Item[K comparable, V any] struct {
 	rw sync.RWMutex
 	m  api.Map[K, V]
+	// Reshard的时候分片迁移完成之后指向新的分片表, 需要去新表里面操作
+	next *table[K, V]
+	// 设置了过期时间的key, value是过期时间(UnixNano), 没有使用ttl时是nil
+	expires map[K]int64
+}
+
+// 返回hash值对应的分片
+func (t *table[K, V]) item(hash uint64) *Item[K, V] {
+	return &t.bucket[hash%uint64(len(t.bucket))]
+}
+
+func New[K comparable, V any]() (c *CMap[K, V]) {
+	return NewWithOpt[K, V]()
 }
 
-func New[K constraints.Ordered, V any]() (c *CMap[K, V]) {
+// 初始化一个cmap并且可以设置选项
+func NewWithOpt[K comparable, V any](opts ...Option) (c *CMap[K, V]) {
+	var conf config
+	for _, o := range opts {
+		o.apply(&conf)
+	}
+
 	c = &CMap[K, V]{}
-	c.init(0)
+	c.initHasher(&conf)
+	c.initNewMap(&conf)
+	c.initExpire(&conf)
+	c.init(conf.shardCount)
 	return c
 }
 
-func (c *CMap[K, V]) init(n int) {
+// 根据配置选择计算hash值的方式
+// 优先使用WithHasher, 其次是WithHashFunc, 都没有设置就使用key类型默认的hasher
+func (c *CMap[K, V]) initHasher(conf *config) {
+	if conf.keyHasher != nil {
+		kh, ok := conf.keyHasher.(hasher.Hasher[K])
+		if !ok {
+			panic("cmap: the key type of WithHasher does not match the map")
+		}
+		c.hasher = kh
+		return
+	}
+
+	c.hasher = hasher.New[K](conf.hashFunc)
+}
+
+// 根据配置选择分片里面的map
+// 优先使用WithShardMap, 其次是WithBackend
+func (c *CMap[K, V]) initNewMap(conf *config) {
+	if conf.newMap != nil {
+		newMap, ok := conf.newMap.(func() api.Map[K, V])
+		if !ok {
+			panic("cmap: the type of WithShardMap does not match the map")
+		}
+		c.newMap = newMap
+		return
+	}
+
+	switch conf.backend {
+	case RHashMap:
+		c.newMap = func() api.Map[K, V] { return rhashmap.New[K, V]() }
+	case RBTree:
+		compare := rbtreeCompare[K](conf)
+		c.newMap = func() api.Map[K, V] { return rbtree.NewWithCompare[K, V](compare) }
+	default:
+		c.newMap = func() api.Map[K, V] { return newStdMap[K, V]() }
+	}
+}
+
+// RBTree分片使用的比较函数, 优先使用WithCompare
+// 没有设置时, 只有K是内置的整数, 浮点数或者字符串类型才有默认的比较函数
+func rbtreeCompare[K comparable](conf *config) func(a, b K) int {
+	if conf.compare != nil {
+		compare, ok := conf.compare.(func(a, b K) int)
+		if !ok {
+			panic("cmap: the type of WithCompare does not match the key")
+		}
+		return compare
+	}
+
+	var compare any
+	switch any(*new(K)).(type) {
+	case int:
+		compare = cmp.Compare[int]
+	case int8:
+		compare = cmp.Compare[int8]
+	case int16:
+		compare = cmp.Compare[int16]
+	case int32:
+		compare = cmp.Compare[int32]
+	case int64:
+		compare = cmp.Compare[int64]
+	case uint:
+		compare = cmp.Compare[uint]
+	case uint8:
+		compare = cmp.Compare[uint8]
+	case uint16:
+		compare = cmp.Compare[uint16]
+	case uint32:
+		compare = cmp.Compare[uint32]
+	case uint64:
+		compare = cmp.Compare[uint64]
+	case uintptr:
+		compare = cmp.Compare[uintptr]
+	case float32:
+		compare = cmp.Compare[float32]
+	case float64:
+		compare = cmp.Compare[float64]
+	case string:
+		compare = cmp.Compare[string]
+	default:
+		panic("cmap: the RBTree backend needs WithCompare for this key type")
+	}
+	return compare.(func(a, b K) int)
+}
+
+// 默认的分片数
+func defaultShardCount() int {
 	np := runtime.GOMAXPROCS(0)
 	if np <= 0 {
 		np = 8
 	}
+	return np
+}
 
-	if n > 0 {
-		np = n
+func (c *CMap[K, V]) init(n int) {
+	if c.hasher == nil {
+		c.hasher = hasher.New[K](nil)
 	}
 
-	c.bucket = make([]Item[K, V], np)
+	if c.newMap == nil {
+		c.initNewMap(&config{})
+	}
+
+	if c.now == nil {
+		c.initExpire(&config{})
+	}
 
-	for i := range c.bucket {
-		c.bucket[i].m = newStdMap[K, V]()
+	if n <= 0 {
+		n = defaultShardCount()
 	}
 
+	c.table.Store(c.newTable(n))
+}
+
+func (c *CMap[K, V]) newTable(n int) *table[K, V] {
+	t := &table[K, V]{bucket: make([]Item[K, V], n)}
+	for i := range t.bucket {
+		t.bucket[i].m = c.newMap()
+	}
+	return t
 }
 
 // 计算hash值
 func (c *CMap[K, V]) calHash(k K) uint64 {
-	var key string
+	return c.hasher.Hash(k)
+}
 
-	if c.isKeyStr {
-		// 直接赋值会报错, 使用unsafe绕过编译器检查
-		key = *(*string)(unsafe.Pointer(&k))
-	} else {
-		// 因为xxhash.Sum64String 接收string, 所以要把非string类型变量当成string类型来处理
-		key = *(*string)(unsafe.Pointer(&reflect.StringHeader{
-			Data: uintptr(unsafe.Pointer(&k)),
-			Len:  c.keySize,
-		}))
+// 找到key所在的分片并且加锁, write为true时加写锁
+// 分片已经迁移到新表时, 去新表里面找
+// 加写锁时会先删除已经过期的key
+func (c *CMap[K, V]) lockItem(key K, write bool) *Item[K, V] {
+	hash := c.calHash(key)
+	t := c.table.Load()
+	for {
+		item := t.item(hash)
+		item.lock(write)
+		if item.next == nil {
+			if write {
+				c.expireIfNeeded(item, key)
+			}
+			return item
+		}
+		item.unlock(write)
+		t = item.next
 	}
+}
 
-	return xxhash.Sum64String(key)
+func (item *Item[K, V]) lock(write bool) {
+	if write {
+		item.rw.Lock()
+		return
+	}
+	item.rw.RLock()
 }
 
-// 保存key的类型和key的长度
-func (h *CMap[K, V]) keyTypeAndKeySize() {
-	var k K
-	switch (interface{})(k).(type) {
-	case string:
-		h.isKeyStr = true
-	default:
-		h.keySize = int(unsafe.Sizeof(k))
+func (item *Item[K, V]) unlock(write bool) {
+	if write {
+		item.rw.Unlock()
+		return
 	}
+	item.rw.RUnlock()
 }
 
-// 找到索引
-func (c *CMap[K, V]) findIndex(key K) *Item[K, V] {
-	index := c.calHash(key) % uint64(len(c.bucket))
-	return &c.bucket[index]
+// 返回分片数
+func (c *CMap[K, V]) ShardCount() int {
+	return len(c.table.Load().bucket)
+}
+
+// 在线修改分片数, 一个分片一个分片的迁移, 迁移某个分片的时候只阻塞这个分片的读写
+// 迁移过程中其它分片可以正常读写, 多个Reshard会排队执行
+// 正在进行的Range, Len等遍历不会等待Reshard完成, 每次只持有一个分片的读锁
+// Reshard期间Range的callback里面不要再访问CMap, 可能会和迁移互相等待
+func (c *CMap[K, V]) Reshard(n int) {
+	if n <= 0 {
+		n = defaultShardCount()
+	}
+
+	c.reshardMu.Lock()
+	defer c.reshardMu.Unlock()
+
+	old := c.table.Load()
+	if len(old.bucket) == n {
+		return
+	}
+
+	newTable := c.newTable(n)
+	for i := range old.bucket {
+		item := &old.bucket[i]
+		item.rw.Lock()
+		item.m.Range(func(k K, v V) bool {
+			dst := newTable.item(c.calHash(k))
+			dst.rw.Lock()
+			dst.m.Set(k, v)
+			if deadline, ok := item.expires[k]; ok {
+				dst.setExpire(k, deadline)
+			}
+			dst.rw.Unlock()
+			return true
+		})
+		item.m = nil
+		item.expires = nil
+		item.next = newTable
+		item.rw.Unlock()
+	}
+
+	// 所有的分片都迁移完成, 新的操作直接访问新表
+	c.table.Store(newTable)
+}
+
+// 按分片遍历, visit在分片的读锁里面调用, after在释放读锁之后调用(可以为nil), 返回false表示停止遍历
+// filter不为nil时, 分片里面只需要遍历filter返回true的key
+// 遇到已经迁移的分片不等待Reshard, 先记下来接着遍历旧表里面还没有迁移的分片,
+// 正在被加写锁(比如正在迁移)的分片放到最后再等, 这样不会跟在Reshard后面一个分片一个分片的等,
+// 旧表遍历完之后, 再一个分片一个分片的去新表里面遍历从已经迁移的分片过去的key,
+// 在旧表里面遍历过的分片之后才迁移过去的key会被filter跳过, 这样每个key只会遍历一次
+func (c *CMap[K, V]) rangeItems(t *table[K, V], filter func(hash uint64) bool,
+	visit func(item *Item[K, V], filter func(hash uint64) bool) bool, after func() bool) bool {
+
+	var next *table[K, V]
+	// 下标是旧表的分片, true表示遍历到的时候已经迁移到next
+	var moved []bool
+	// 持有分片的读锁, 返回false表示停止遍历
+	rangeItem := func(i int, item *Item[K, V]) bool {
+		if item.next == nil {
+			ok := visit(item, filter)
+			item.rw.RUnlock()
+			return ok && (after == nil || after())
+		}
+
+		// 一个表只会被Reshard一次, 所有分片的next都是同一个表
+		if next == nil {
+			next = item.next
+			moved = make([]bool, len(t.bucket))
+		}
+		moved[i] = true
+		item.rw.RUnlock()
+		return true
+	}
+
+	var busy []int
+	for i := range t.bucket {
+		item := &t.bucket[i]
+		if !item.rw.TryRLock() {
+			busy = append(busy, i)
+			continue
+		}
+		if !rangeItem(i, item) {
+			return false
+		}
+	}
+
+	for _, i := range busy {
+		item := &t.bucket[i]
+		item.rw.RLock()
+		if !rangeItem(i, item) {
+			return false
+		}
+	}
+
+	if next == nil {
+		return true
+	}
+
+	n := uint64(len(t.bucket))
+	return c.rangeItems(next, func(hash uint64) bool {
+		return moved[hash%n] && (filter == nil || filter(hash))
+	}, visit, after)
+}
+
+// 遍历一个分片里面的元素, 需要持有分片的锁, 跳过已经过期的key
+func (c *CMap[K, V]) rangeItem(item *Item[K, V], filter func(hash uint64) bool, f func(key K, value V) bool) bool {
+	ok := true
+	now := c.itemNow(item)
+	item.m.Range(func(k K, v V) bool {
+		if filter != nil && !filter(c.calHash(k)) {
+			return true
+		}
+		// 过期的key等后面删除, 这里跳过
+		if item.expiredAt(k, now) {
+			return true
+		}
+		ok = f(k, v)
+		return ok
+	})
+	return ok
+}
+
+// 一个分片一个分片的复制, 释放锁之后再调用f
+func (c *CMap[K, V]) rangeSnapshot(f func(pairs []Pair[K, V]) bool) {
+	var pairs []Pair[K, V]
+	c.rangeItems(c.table.Load(), nil, func(item *Item[K, V], filter func(hash uint64) bool) bool {
+		pairs = pairs[:0]
+		return c.rangeItem(item, filter, func(key K, value V) bool {
+			pairs = append(pairs, Pair[K, V]{Key: key, Val: value})
+			return true
+		})
+	}, func() bool {
+		return f(pairs)
+	})
 }
 
 // 删除
 func (c *CMap[K, V]) Delete(key K) {
-	item := c.findIndex(key)
-	item.rw.Lock()
-	item.m.Delete(key)
+	item := c.lockItem(key, true)
+	item.delete(key)
 	item.rw.Unlock()
 }
 
+// 加载, key已经过期时会删除(lazy expire)
 func (c *CMap[K, V]) Load(key K) (value V, ok bool) {
-	item := c.findIndex(key)
-	item.rw.RLock()
+	item := c.lockItem(key, false)
 	value, ok = item.m.GetWithBool(key)
+	expired := ok && item.expiredAt(key, c.itemNow(item))
 	item.rw.RUnlock()
+	if !expired {
+		return
+	}
+
+	// 换成写锁删除过期的key, 释放读锁之后可能已经被重新设置了
+	item = c.lockItem(key, true)
+	value, ok = item.m.GetWithBool(key)
+	item.rw.Unlock()
 	return
 }
 
 func (c *CMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
-	item := c.findIndex(key)
-	item.rw.Lock()
+	item := c.lockItem(key, true)
 	value, loaded = item.m.GetWithBool(key)
 	if !loaded {
 		item.rw.Unlock()
 		return
 	}
-	item.m.Delete(key)
+	item.delete(key)
 	item.rw.Unlock()
 	return
 }
 
 func (c *CMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
-	item := c.findIndex(key)
-	item.rw.Lock()
+	item := c.lockItem(key, true)
 	actual, loaded = item.m.GetWithBool(key)
 	if !loaded {
 		actual = value
@@ -128,56 +418,82 @@ func (c *CMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
 		return
 	}
 
-	actual, loaded = item.m.GetWithBool(key)
 	item.rw.Unlock()
 	return
 }
 
+// 遍历, 每次只持有一个分片的读锁
+// 遍历到Reshard已经迁移的分片时, 不会等待Reshard完成, 去新表里面遍历这个分片迁移过去的key
 func (c *CMap[K, V]) Range(f func(key K, value V) bool) {
-	for i := 0; i < len(c.bucket); i++ {
-		item := &c.bucket[i]
-		item.rw.RLock()
-		item.m.Range(f)
-		item.rw.RUnlock()
-	}
+	c.rangeItems(c.table.Load(), nil, func(item *Item[K, V], filter func(hash uint64) bool) bool {
+		return c.rangeItem(item, filter, f)
+	}, nil)
 }
 
+// 返回所有元素的channel, 基于Snapshot实现, channel的容量和副本的长度一样, 返回之前已经写完并且关闭
+// 不会启动goroutine, 提前退出循环不会泄漏, 元素很多的时候使用IterContext或者SnapshotIter
 func (c *CMap[K, V]) Iter() (rv chan Pair[K, V]) {
+	snap := c.Snapshot()
+	rv = make(chan Pair[K, V], len(snap))
+	for _, p := range snap {
+		rv <- p
+	}
+	close(rv)
+	return rv
+}
 
-	rv = make(chan Pair[K, V])
-	var wg sync.WaitGroup
-
-	wg.Add(len(c.bucket))
-
+// 返回所有元素的channel, 一个分片一个分片的复制, 发送的时候不持有锁
+// 提前退出循环时调用cancel, 后台的goroutine会退出并且关闭channel
+// 提前退出循环又没有调用cancel的话, 后台的goroutine会一直阻塞在发送上, 造成泄漏
+func (c *CMap[K, V]) IterContext(ctx context.Context) <-chan Pair[K, V] {
+	rv := make(chan Pair[K, V])
 	go func() {
-		wg.Wait()
-		close(rv)
+		defer close(rv)
+		c.rangeSnapshot(func(pairs []Pair[K, V]) bool {
+			for _, p := range pairs {
+				select {
+				case rv <- p:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		})
 	}()
+	return rv
+}
 
-	for i := 0; i < len(c.bucket); i++ {
-		item := &c.bucket[i]
-		go func(item *Item[K, V]) {
-
-			defer wg.Done()
-			item.rw.RLock()
-			item.m.Range(func(key K, value V) bool {
-				rv <- Pair[K, V]{Key: key, Val: value}
-				return true
-			})
-			item.rw.RUnlock()
+// 返回所有元素的副本, 每个分片是一致的, 不同的分片不是同一个时刻的
+func (c *CMap[K, V]) Snapshot() []Pair[K, V] {
+	all := make([]Pair[K, V], 0, c.Len())
+	c.rangeSnapshot(func(pairs []Pair[K, V]) bool {
+		all = append(all, pairs...)
+		return true
+	})
+	return all
+}
 
-		}(item)
+// 返回key和value的迭代器, 一个分片一个分片的复制, yield的时候不持有锁
+// 和All不同的是循环体里面可以修改map
+func (c *CMap[K, V]) SnapshotIter() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		c.rangeSnapshot(func(pairs []Pair[K, V]) bool {
+			for _, p := range pairs {
+				if !yield(p.Key, p.Val) {
+					return false
+				}
+			}
+			return true
+		})
 	}
-	return rv
-
 }
 
+// 保存, 会清除以前设置的过期时间
 func (c *CMap[K, V]) Store(key K, value V) {
-	item := c.findIndex(key)
-	item.rw.Lock()
+	item := c.lockItem(key, true)
 	item.m.Set(key, value)
+	item.persist(key)
 	item.rw.Unlock()
-	return
 }
 
 // TODO 优化
@@ -188,16 +504,10 @@ func (c *CMap[K, V]) Keys() []K {
 		return nil
 	}
 
-	for i := 0; i < len(c.bucket); i++ {
-
-		item := &c.bucket[i]
-		item.rw.RLock()
-		item.m.Range(func(key K, value V) bool {
-			all = append(all, key)
-			return true
-		})
-		item.rw.RUnlock()
-	}
+	c.Range(func(key K, value V) bool {
+		all = append(all, key)
+		return true
+	})
 	return all
 }
 
@@ -208,26 +518,155 @@ func (c *CMap[K, V]) Values() []V {
 		return nil
 	}
 
-	for i := 0; i < len(c.bucket); i++ {
+	c.Range(func(key K, value V) bool {
+		all = append(all, value)
+		return true
+	})
+	return all
+}
 
-		item := &c.bucket[i]
-		item.rw.RLock()
-		item.m.Range(func(key K, value V) bool {
-			all = append(all, value)
+// 返回元素个数, 可能包含已经过期但是还没有删除的key
+func (c *CMap[K, V]) Len() int {
+	l := 0
+	c.rangeItems(c.table.Load(), nil, func(item *Item[K, V], filter func(hash uint64) bool) bool {
+		if filter == nil {
+			l += item.m.Len()
+			return true
+		}
+
+		// Reshard中, 新表的分片里面只有一部分key需要数
+		item.m.Range(func(k K, _ V) bool {
+			if filter(c.calHash(k)) {
+				l++
+			}
 			return true
 		})
-		item.rw.RUnlock()
+		return true
+	}, nil)
+	return l
+}
+
+// 返回key和value的迭代器, 可以配合for range使用
+// 和Range一样, 遍历的过程中持有读锁, 循环体里面不能修改map
+func (c *CMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		c.Range(yield)
 	}
-	return all
 }
 
-func (c *CMap[K, V]) Len() int {
-	l := 0
-	for i := 0; i < len(c.bucket); i++ {
-		item := &c.bucket[i]
-		item.rw.RLock()
-		l += item.m.Len()
-		item.rw.RUnlock()
+// 返回key的迭代器, 和All一样遍历的过程中持有读锁
+// Keys已经返回[]K, 为了不破坏已有的调用, 迭代器版本叫KeysSeq
+func (c *CMap[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		c.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
 	}
-	return l
+}
+
+// 返回value的迭代器, 和All一样遍历的过程中持有读锁
+// Values已经返回[]V, 为了不破坏已有的调用, 迭代器版本叫ValuesSeq
+func (c *CMap[K, V]) ValuesSeq() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		c.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
+
+// 在分片的写锁里面调用fn, 用fn的返回值更新key, delete为true时删除key
+// 返回保存的值和key是否存在, fn里面不能再访问CMap
+// 更新不会改变key的过期时间
+func (c *CMap[K, V]) Compute(key K, fn func(old V, loaded bool) (newValue V, delete bool)) (actual V, ok bool) {
+	item := c.lockItem(key, true)
+	defer item.rw.Unlock()
+
+	old, loaded := item.m.GetWithBool(key)
+	newValue, del := fn(old, loaded)
+	if del {
+		if loaded {
+			item.delete(key)
+		}
+		return
+	}
+
+	item.m.Set(key, newValue)
+	return newValue, true
+}
+
+// key存在时在分片的写锁里面调用fn, 用fn的返回值更新key, 不会改变key的过期时间
+func (c *CMap[K, V]) Update(key K, fn func(old V) V) (newValue V, ok bool) {
+	item := c.lockItem(key, true)
+	defer item.rw.Unlock()
+
+	old, ok := item.m.GetWithBool(key)
+	if !ok {
+		return
+	}
+
+	newValue = fn(old)
+	item.m.Set(key, newValue)
+	return newValue, true
+}
+
+// 保存新值, 返回以前的值, 和Store一样会清除以前设置的过期时间
+func (c *CMap[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	item := c.lockItem(key, true)
+	previous, loaded = item.m.SetWithPrev(key, value)
+	item.persist(key)
+	item.rw.Unlock()
+	return
+}
+
+// 当前值等于old时替换成new, 和sync.Map一样, V的动态类型必须是可比较的, 否则会panic
+// 替换成功时和Store, Swap一样会清除以前设置的过期时间
+func (c *CMap[K, V]) CompareAndSwap(key K, old, new V) (swapped bool) {
+	item := c.lockItem(key, true)
+	defer item.rw.Unlock()
+
+	cur, ok := item.m.GetWithBool(key)
+	if !ok || any(cur) != any(old) {
+		return false
+	}
+
+	item.m.Set(key, new)
+	item.persist(key)
+	return true
+}
+
+// 当前值等于old时删除, 和sync.Map一样, V的动态类型必须是可比较的, 否则会panic
+func (c *CMap[K, V]) CompareAndDelete(key K, old V) (deleted bool) {
+	item := c.lockItem(key, true)
+	defer item.rw.Unlock()
+
+	cur, ok := item.m.GetWithBool(key)
+	if !ok || any(cur) != any(old) {
+		return false
+	}
+
+	item.delete(key)
+	return true
+}
+
+// key存在返回现有的值, 不存在时在分片的写锁里面调用fn, 保存fn的返回值
+// 适合创建成本比较高的值, 并发调用时fn只会执行一次
+func (c *CMap[K, V]) LoadOrCompute(key K, fn func() V) (actual V, loaded bool) {
+	// 大多数情况key已经存在, 先用读锁查一次
+	item := c.lockItem(key, false)
+	actual, loaded = item.m.GetWithBool(key)
+	loaded = loaded && !item.expiredAt(key, c.itemNow(item))
+	item.rw.RUnlock()
+	if loaded {
+		return
+	}
+
+	item = c.lockItem(key, true)
+	defer item.rw.Unlock()
+	if actual, loaded = item.m.GetWithBool(key); loaded {
+		return
+	}
+
+	actual = fn()
+	item.m.Set(key, actual)
+	return actual, false
 }
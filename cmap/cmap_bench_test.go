@@ -367,9 +367,7 @@ func BenchmarkDeleteCollision(b *testing.B) {
 func BenchmarkXXHash(b *testing.B) {
 
 	for i := 0; i < b.N; i++ {
-		key := *(*string)(unsafe.Pointer(&reflect.StringHeader{
-			Data: uintptr(unsafe.Pointer(&i)),
-			Len:  8}))
+		key := unsafe.String((*byte)(unsafe.Pointer(&i)), 8)
 
 		xxhash.Sum64String(key)
 	}
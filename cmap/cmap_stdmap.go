@@ -2,16 +2,15 @@ package cmap
 
 import (
 	"github.com/antlabs/gstl/api"
-	"golang.org/x/exp/constraints"
 )
 
 var _ api.Map[int, int] = (*stdmap[int, int])(nil)
 
-type stdmap[K constraints.Ordered, V any] struct {
+type stdmap[K comparable, V any] struct {
 	m map[K]V
 }
 
-func newStdMap[K constraints.Ordered, V any]() *stdmap[K, V] {
+func newStdMap[K comparable, V any]() *stdmap[K, V] {
 	return &stdmap[K, V]{m: make(map[K]V)}
 }
 
@@ -1,11 +1,19 @@
 package cmap
 
 import (
+	"context"
 	"fmt"
+	"runtime"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/antlabs/gstl/api"
+	"github.com/antlabs/gstl/cmp"
+	"github.com/antlabs/gstl/hasher"
+	"github.com/antlabs/gstl/rhashmap"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -182,3 +190,674 @@ func Test_Values(t *testing.T) {
 	m2 := New[string, string]()
 	assert.Equal(t, len(m2.Keys()), 0)
 }
+
+// 测试range over func
+func Test_All(t *testing.T) {
+	m := New[int, int]()
+	max := 100
+	for i := 0; i < max; i++ {
+		m.Store(i, i*10)
+	}
+
+	all := map[int]int{}
+	for k, v := range m.All() {
+		all[k] = v
+	}
+	assert.Equal(t, len(all), max)
+	for k, v := range all {
+		assert.Equal(t, v, k*10)
+	}
+
+	n := 0
+	for range m.All() {
+		n++
+		if n == 3 {
+			break
+		}
+	}
+	assert.Equal(t, n, 3)
+}
+
+// 测试KeysSeq和ValuesSeq
+func Test_KeysSeq_ValuesSeq(t *testing.T) {
+	m := New[int, int]()
+	max := 100
+	for i := 0; i < max; i++ {
+		m.Store(i, i*10)
+	}
+
+	var keys, values []int
+	for k := range m.KeysSeq() {
+		keys = append(keys, k)
+	}
+	for v := range m.ValuesSeq() {
+		values = append(values, v)
+	}
+	sort.Ints(keys)
+	sort.Ints(values)
+	for i := 0; i < max; i++ {
+		assert.Equal(t, keys[i], i)
+		assert.Equal(t, values[i], i*10)
+	}
+
+	n := 0
+	for range m.KeysSeq() {
+		n++
+		if n == 3 {
+			break
+		}
+	}
+	assert.Equal(t, n, 3)
+}
+
+type structKey struct {
+	name string
+	id   int
+}
+
+// struct类型的key, 里面的string按内容比较
+func Test_StructKey(t *testing.T) {
+	m := New[structKey, int]()
+	for i := 0; i < 1000; i++ {
+		m.Store(structKey{name: fmt.Sprint("name", i), id: i}, i)
+	}
+
+	assert.Equal(t, m.Len(), 1000)
+	for i := 0; i < 1000; i++ {
+		v, ok := m.Load(structKey{name: fmt.Sprint("name", i), id: i})
+		assert.True(t, ok)
+		assert.Equal(t, v, i)
+	}
+}
+
+// key会分散到不同的bucket
+func Test_Distribution(t *testing.T) {
+	m := New[int, int]()
+	m.init(8)
+	for i := 0; i < 1000; i++ {
+		m.Store(i, i)
+	}
+
+	bucket := m.table.Load().bucket
+	for i := range bucket {
+		assert.NotEqual(t, bucket[i].m.Len(), 0)
+	}
+}
+
+func Test_WithHasher(t *testing.T) {
+	m := NewWithOpt[structKey, int](WithHasher[structKey](hasher.Func[structKey](func(k structKey) uint64 {
+		return uint64(k.id)
+	})))
+	m.Store(structKey{name: "a", id: 1}, 1)
+	m.Store(structKey{name: "b", id: 1}, 2)
+
+	v, _ := m.Load(structKey{name: "a", id: 1})
+	assert.Equal(t, v, 1)
+	// 相同的hash值在同一个bucket里面
+	item := m.lockItem(structKey{name: "a", id: 1}, false)
+	assert.Equal(t, item.m.Len(), 2)
+	item.rw.RUnlock()
+
+	m2 := NewWithOpt[string, int](WithHashFunc(func(str string) uint64 { return 0 }))
+	m2.Store("a", 1)
+	m2.Store("b", 2)
+	assert.Equal(t, m2.table.Load().bucket[0].m.Len(), 2)
+}
+
+func Test_WithShardCount(t *testing.T) {
+	m := NewWithOpt[int, int](WithShardCount(3))
+	assert.Equal(t, m.ShardCount(), 3)
+
+	m = NewWithOpt[int, int](WithShardCount(0))
+	assert.Equal(t, m.ShardCount(), defaultShardCount())
+}
+
+func Test_WithBackend(t *testing.T) {
+	for _, b := range []Backend{StdMap, RHashMap, RBTree} {
+		m := NewWithOpt[int, int](WithBackend(b), WithShardCount(4))
+		for i := 0; i < 1000; i++ {
+			m.Store(i, i)
+		}
+		m.Delete(0)
+		assert.Equal(t, m.Len(), 999)
+		v, ok := m.Load(10)
+		assert.True(t, ok)
+		assert.Equal(t, v, 10)
+	}
+
+	// 只有一个分片的时候, rbtree是有序的
+	m := NewWithOpt[int, int](WithBackend(RBTree), WithShardCount(1))
+	need := []int{}
+	for i := 100; i > 0; i-- {
+		m.Store(i, i)
+		need = append([]int{i}, need...)
+	}
+	assert.Equal(t, m.Keys(), need)
+
+	// key不是内置的有序类型, 需要WithCompare
+	assert.Panics(t, func() { NewWithOpt[structKey, int](WithBackend(RBTree)) })
+	assert.Panics(t, func() { NewWithOpt[myInt, int](WithBackend(RBTree)) })
+	assert.Panics(t, func() { NewWithOpt[int, int](WithBackend(RBTree), WithCompare(cmp.Compare[int8])) })
+
+	m1 := NewWithOpt[myInt, int](WithBackend(RBTree), WithShardCount(1), WithCompare(cmp.Compare[myInt]))
+	for i := myInt(3); i > 0; i-- {
+		m1.Store(i, int(i))
+	}
+	assert.Equal(t, m1.Keys(), []myInt{1, 2, 3})
+}
+
+type myInt int
+
+func Test_WithShardMap(t *testing.T) {
+	n := 0
+	m := NewWithOpt[int, int](WithShardCount(2), WithShardMap(func() api.Map[int, int] {
+		n++
+		return rhashmap.New[int, int]()
+	}))
+	assert.Equal(t, n, 2)
+	m.Store(1, 1)
+	v, _ := m.Load(1)
+	assert.Equal(t, v, 1)
+
+	assert.Panics(t, func() {
+		NewWithOpt[string, int](WithShardMap(func() api.Map[int, int] { return nil }))
+	})
+}
+
+func Test_Reshard(t *testing.T) {
+	m := NewWithOpt[int, int](WithShardCount(2), WithBackend(RHashMap))
+	max := 1000
+	for i := 0; i < max; i++ {
+		m.Store(i, i)
+	}
+
+	for _, n := range []int{16, 3, 3, 1} {
+		m.Reshard(n)
+		assert.Equal(t, m.ShardCount(), n)
+		assert.Equal(t, m.Len(), max)
+		for i := 0; i < max; i++ {
+			v, ok := m.Load(i)
+			assert.True(t, ok)
+			assert.Equal(t, v, i)
+		}
+	}
+}
+
+// Reshard的时候并发读写, 一直存在的key不会丢失, Range也只会返回一次
+func Test_Reshard_Concurrent(t *testing.T) {
+	m := NewWithOpt[int, int](WithShardCount(4))
+	max := 1000
+	for i := 0; i < max; i++ {
+		m.Store(i, i)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			m.Reshard([]int{3, 7, 16, 1}[i%4])
+		}
+		close(done)
+	}()
+	go func() {
+		defer wg.Done()
+		// 不影响0到max的key
+		for i := max; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			m.Store(i, i)
+			m.Delete(i)
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			wg.Wait()
+			assert.Equal(t, m.Len(), max)
+			return
+		default:
+		}
+
+		for i := 0; i < max; i += 10 {
+			v, ok := m.Load(i)
+			assert.True(t, ok)
+			assert.Equal(t, v, i)
+		}
+
+		seen := make(map[int]int)
+		m.Range(func(k, _ int) bool {
+			seen[k]++
+			return true
+		})
+		for i := 0; i < max; i++ {
+			assert.Equal(t, seen[i], 1, i)
+		}
+	}
+}
+
+// Set的时候会sleep的map, 用来模拟很慢的Reshard
+type slowMap struct {
+	*stdmap[int, int]
+	slow *atomic.Bool
+	sets *atomic.Int64
+}
+
+func (s slowMap) Set(k, v int) {
+	if s.slow.Load() {
+		s.sets.Add(1)
+		time.Sleep(time.Millisecond)
+	}
+	s.stdmap.Set(k, v)
+}
+
+// Range和Len遍历到已经迁移的分片时不会等待整个Reshard完成
+func Test_Reshard_NotBlockRange(t *testing.T) {
+	var slow atomic.Bool
+	var sets atomic.Int64
+	m := NewWithOpt[int, int](WithShardCount(4), WithShardMap(func() api.Map[int, int] {
+		return slowMap{stdmap: newStdMap[int, int](), slow: &slow, sets: &sets}
+	}))
+	max := 400
+	for i := 0; i < max; i++ {
+		m.Store(i, i)
+	}
+
+	slow.Store(true)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Reshard(8)
+	}()
+
+	// 等第一个分片迁移完成
+	for sets.Load() <= int64(max/4) {
+		time.Sleep(time.Millisecond)
+	}
+
+	seen := make(map[int]int)
+	m.Range(func(k, _ int) bool {
+		seen[k]++
+		return true
+	})
+	assert.Equal(t, m.Len(), max)
+
+	select {
+	case <-done:
+		t.Fatal("Range and Len waited for Reshard")
+	default:
+	}
+
+	assert.Equal(t, len(seen), max)
+	for i := 0; i < max; i++ {
+		assert.Equal(t, seen[i], 1, i)
+	}
+	<-done
+	assert.Equal(t, m.ShardCount(), 8)
+}
+
+func Test_Compute(t *testing.T) {
+	m := New[string, int]()
+	// 不存在的时候新建
+	v, ok := m.Compute("a", func(old int, loaded bool) (int, bool) {
+		assert.False(t, loaded)
+		return old + 1, false
+	})
+	assert.True(t, ok)
+	assert.Equal(t, v, 1)
+
+	v, ok = m.Compute("a", func(old int, loaded bool) (int, bool) {
+		assert.True(t, loaded)
+		return old + 1, false
+	})
+	assert.True(t, ok)
+	assert.Equal(t, v, 2)
+
+	// 删除
+	_, ok = m.Compute("a", func(old int, loaded bool) (int, bool) {
+		return 0, true
+	})
+	assert.False(t, ok)
+	_, ok = m.Load("a")
+	assert.False(t, ok)
+
+	_, ok = m.Update("a", func(old int) int { return old + 1 })
+	assert.False(t, ok)
+	m.Store("a", 1)
+	v, ok = m.Update("a", func(old int) int { return old + 1 })
+	assert.True(t, ok)
+	assert.Equal(t, v, 2)
+}
+
+func Test_SwapAndCompare(t *testing.T) {
+	m := New[string, int]()
+	prev, loaded := m.Swap("a", 1)
+	assert.False(t, loaded)
+	assert.Equal(t, prev, 0)
+
+	prev, loaded = m.Swap("a", 2)
+	assert.True(t, loaded)
+	assert.Equal(t, prev, 1)
+
+	assert.False(t, m.CompareAndSwap("a", 1, 3))
+	assert.False(t, m.CompareAndSwap("b", 0, 3))
+	assert.True(t, m.CompareAndSwap("a", 2, 3))
+	v, _ := m.Load("a")
+	assert.Equal(t, v, 3)
+
+	assert.False(t, m.CompareAndDelete("a", 2))
+	assert.True(t, m.CompareAndDelete("a", 3))
+	_, ok := m.Load("a")
+	assert.False(t, ok)
+}
+
+// 并发调用时fn只执行一次, 计数不会丢失
+func Test_LoadOrCompute_Concurrent(t *testing.T) {
+	m := New[int, int]()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	calls := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.LoadOrCompute(j, func() int {
+					mu.Lock()
+					calls++
+					mu.Unlock()
+					return j
+				})
+				m.Compute(-1, func(old int, _ bool) (int, bool) {
+					return old + 1, false
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, calls, 1000)
+	v, _ := m.Load(-1)
+	assert.Equal(t, v, 8000)
+	v, loaded := m.LoadOrCompute(10, func() int { return -10 })
+	assert.True(t, loaded)
+	assert.Equal(t, v, 10)
+}
+
+// 测试用的时钟, 调用add之后时间才会变化
+type fakeClock struct {
+	ns atomic.Int64
+}
+
+func (f *fakeClock) now() time.Time {
+	return time.Unix(0, f.ns.Load())
+}
+
+func (f *fakeClock) add(d time.Duration) {
+	f.ns.Add(int64(d))
+}
+
+func Test_StoreWithTTL_Lazy(t *testing.T) {
+	var clock fakeClock
+	evicted := map[string]int{}
+	m := NewWithOpt[string, int](WithClock(clock.now), WithExpireInterval(-1),
+		WithOnEvict(func(k string, v int) { evicted[k] = v }))
+	defer m.Close()
+
+	m.StoreWithTTL("a", 1, time.Second)
+	m.StoreWithTTL("b", 2, 2*time.Second)
+	m.StoreWithTTL("c", 3, time.Second)
+	m.StoreWithTTL("d", 4, 0)
+	m.Store("e", 5)
+
+	v, ok := m.Load("a")
+	assert.True(t, ok)
+	assert.Equal(t, v, 1)
+	_, ok = m.Load("d")
+	assert.False(t, ok)
+
+	// Store清除过期时间, Compute不改变过期时间
+	m.Store("c", 30)
+	m.Compute("b", func(old int, _ bool) (int, bool) { return old * 10, false })
+
+	clock.add(time.Second)
+	_, ok = m.Load("a")
+	assert.False(t, ok)
+	assert.Equal(t, evicted, map[string]int{"a": 1})
+
+	clock.add(time.Second)
+	// 遍历的时候跳过过期的key
+	got := map[string]int{}
+	m.Range(func(k string, v int) bool {
+		got[k] = v
+		return true
+	})
+	assert.Equal(t, got, map[string]int{"c": 30, "e": 5})
+
+	// 写操作也会先删除过期的key
+	_, loaded := m.LoadOrStore("b", 200)
+	assert.False(t, loaded)
+	assert.Equal(t, evicted, map[string]int{"a": 1, "b": 20})
+	assert.Equal(t, m.Len(), 3)
+}
+
+func Test_StoreWithTTL_Active(t *testing.T) {
+	var clock fakeClock
+	var evicted atomic.Int64
+	m := NewWithOpt[int, int](WithClock(clock.now), WithExpireInterval(-1), WithShardCount(4),
+		WithOnEvict(func(int, int) { evicted.Add(1) }))
+	defer m.Close()
+
+	max := 1000
+	for i := 0; i < max; i++ {
+		m.StoreWithTTL(i, i, time.Duration(i%2+1)*time.Second)
+	}
+	m.Store(-1, -1)
+
+	// 没有过期的key不会删除
+	assert.Equal(t, m.activeExpireCycle(time.Hour), 0)
+
+	// 一半的key过期
+	clock.add(time.Second)
+	for m.Len() > max/2+1 {
+		m.activeExpireCycle(time.Hour)
+	}
+	assert.Equal(t, evicted.Load(), int64(max/2))
+
+	// 全部过期, 过期的比例高时一次就能删除大部分的key
+	clock.add(time.Second)
+	assert.Greater(t, m.activeExpireCycle(time.Hour), max/2*9/10)
+	for m.Len() > 1 {
+		m.activeExpireCycle(time.Hour)
+	}
+	assert.Equal(t, evicted.Load(), int64(max))
+	assert.Equal(t, m.Keys(), []int{-1})
+}
+
+// CompareAndSwap成功时和Store, Swap一样清除过期时间, 失败时不改变过期时间
+func Test_StoreWithTTL_CompareAndSwap(t *testing.T) {
+	var clock fakeClock
+	m := NewWithOpt[string, int](WithClock(clock.now), WithExpireInterval(-1))
+	defer m.Close()
+
+	m.StoreWithTTL("a", 1, time.Second)
+	m.StoreWithTTL("b", 1, time.Second)
+	assert.True(t, m.CompareAndSwap("a", 1, 2))
+	assert.False(t, m.CompareAndSwap("b", 9, 10))
+
+	clock.add(time.Second)
+	v, ok := m.Load("a")
+	assert.True(t, ok)
+	assert.Equal(t, v, 2)
+	_, ok = m.Load("b")
+	assert.False(t, ok)
+
+	// 过期的key不能再被替换
+	m.StoreWithTTL("c", 1, time.Second)
+	clock.add(time.Second)
+	assert.False(t, m.CompareAndSwap("c", 1, 2))
+	_, ok = m.Load("c")
+	assert.False(t, ok)
+}
+
+// 后台goroutine删除过期的key, Close之后停止
+func Test_StoreWithTTL_Background(t *testing.T) {
+	var clock fakeClock
+	m := NewWithOpt[int, int](WithClock(clock.now), WithExpireInterval(time.Millisecond))
+	for i := 0; i < 100; i++ {
+		m.StoreWithTTL(i, i, time.Second)
+	}
+
+	clock.add(time.Second)
+	assert.Eventually(t, func() bool { return m.Len() == 0 }, time.Second, time.Millisecond)
+	m.Close()
+	m.Close()
+
+	// Close之后只有lazy过期
+	m.StoreWithTTL(1, 1, time.Second)
+	clock.add(time.Second)
+	assert.Equal(t, m.Len(), 1)
+	_, ok := m.Load(1)
+	assert.False(t, ok)
+	assert.Equal(t, m.Len(), 0)
+}
+
+// Reshard的时候过期时间跟着迁移
+func Test_StoreWithTTL_Reshard(t *testing.T) {
+	var clock fakeClock
+	m := NewWithOpt[int, int](WithClock(clock.now), WithExpireInterval(-1), WithShardCount(2))
+	for i := 0; i < 100; i++ {
+		m.StoreWithTTL(i, i, time.Second)
+	}
+	m.Reshard(7)
+
+	clock.add(time.Second)
+	for i := 0; i < 100; i++ {
+		_, ok := m.Load(i)
+		assert.False(t, ok)
+	}
+	assert.Equal(t, m.Len(), 0)
+}
+
+// 提前退出循环, 调用cancel之后后台goroutine退出, 写操作不会被阻塞
+func Test_IterContext(t *testing.T) {
+	m := New[int, int]()
+	max := 100
+	for i := 0; i < max; i++ {
+		m.Store(i, i)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := m.IterContext(ctx)
+	<-ch
+	cancel()
+
+	// 没有读完的时候也可以写
+	m.Store(max, max)
+	for range ch {
+	}
+
+	// Iter提前退出也不会阻塞写
+	for range m.Iter() {
+		break
+	}
+	m.Store(max+1, max+1)
+	assert.Equal(t, m.Len(), max+2)
+
+	got := map[int]int{}
+	for p := range m.IterContext(context.Background()) {
+		got[p.Key] = p.Val
+	}
+	assert.Equal(t, len(got), max+2)
+}
+
+// Iter提前退出循环, 不会留下goroutine
+func Test_IterEarlyBreak(t *testing.T) {
+	m := New[int, int]()
+	for i := 0; i < 100; i++ {
+		m.Store(i, i)
+	}
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		for range m.Iter() {
+			break
+		}
+	}
+	assert.Equal(t, runtime.NumGoroutine(), before)
+
+	n := 0
+	for range m.Iter() {
+		n++
+	}
+	assert.Equal(t, n, 100)
+}
+
+// Iter的时候有其它goroutine在写, 新加的元素超过channel的容量也不会阻塞, 已有的元素都能读到
+func Test_IterConcurrentStore(t *testing.T) {
+	m := New[int, int]()
+	max := 1000
+	for i := 0; i < max; i++ {
+		m.Store(i, i)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := max; i < max*10; i++ {
+			m.Store(i, i)
+		}
+	}()
+
+	for n := 0; n < 10; n++ {
+		got := make(map[int]int)
+		for p := range m.Iter() {
+			got[p.Key] = p.Val
+		}
+		for i := 0; i < max; i++ {
+			assert.Equal(t, got[i], i)
+		}
+	}
+	wg.Wait()
+
+	assert.Equal(t, len(m.Iter()), max*10)
+}
+
+func Test_Snapshot(t *testing.T) {
+	m := New[int, int]()
+	max := 100
+	for i := 0; i < max; i++ {
+		m.Store(i, i)
+	}
+
+	pairs := m.Snapshot()
+	assert.Equal(t, len(pairs), max)
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	for i, p := range pairs {
+		assert.Equal(t, p, Pair[int, int]{Key: i, Val: i})
+	}
+
+	// 循环体里面可以修改map
+	n := 0
+	for k, v := range m.SnapshotIter() {
+		assert.Equal(t, k, v)
+		m.Delete(k)
+		m.Store(k+max, k)
+		n++
+	}
+	assert.Equal(t, n, max)
+	assert.Equal(t, m.Len(), max)
+
+	n = 0
+	for range m.SnapshotIter() {
+		n++
+		break
+	}
+	assert.Equal(t, n, 1)
+}
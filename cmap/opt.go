@@ -0,0 +1,152 @@
+package cmap
+
+// apache 2.0 antlabs
+import (
+	"time"
+
+	"github.com/antlabs/gstl/api"
+	"github.com/antlabs/gstl/hasher"
+)
+
+type config struct {
+	hashFunc func(str string) uint64
+	// WithHasher设置的hasher.Hasher[K]
+	keyHasher any
+	// 分片数
+	shardCount int
+	backend    Backend
+	// WithShardMap设置的func() api.Map[K, V]
+	newMap any
+	// WithCompare设置的func(a, b K) int
+	compare any
+	now     func() time.Time
+	// WithOnEvict设置的func(k K, v V)
+	onEvict        any
+	expireInterval time.Duration
+}
+
+type Option interface {
+	apply(*config)
+}
+
+type hashFunc func(str string) uint64
+
+func (h hashFunc) apply(c *config) {
+	c.hashFunc = h
+}
+
+// 设置字符串的hash函数, string, 整数和浮点数类型的key会使用它
+func WithHashFunc(hfunc func(str string) uint64) Option {
+	return hashFunc(hfunc)
+}
+
+type withHasher struct {
+	keyHasher any
+}
+
+func (w withHasher) apply(c *config) {
+	c.keyHasher = w.keyHasher
+}
+
+// 设置key的Hasher, 所有类型的key都可以使用, 优先级比WithHashFunc高
+// K必须和CMap的key类型一样
+func WithHasher[K comparable](h hasher.Hasher[K]) Option {
+	return withHasher{keyHasher: h}
+}
+
+// 分片里面使用的map
+type Backend int
+
+const (
+	// 标准库的map, 默认值
+	StdMap Backend = iota
+	// rhashmap.HashMap
+	RHashMap
+	// rbtree.RBTree, 分片里面的元素是有序的
+	// key是内置的整数, 浮点数或者字符串类型时可以直接使用, 其他类型需要WithCompare设置比较函数
+	RBTree
+)
+
+type shardCount int
+
+func (s shardCount) apply(c *config) {
+	c.shardCount = int(s)
+}
+
+// 设置分片数, 小于等于0时使用GOMAXPROCS
+func WithShardCount(n int) Option {
+	return shardCount(n)
+}
+
+func (b Backend) apply(c *config) {
+	c.backend = b
+}
+
+// 设置分片里面使用的map
+func WithBackend(b Backend) Option {
+	return b
+}
+
+type withCompare struct {
+	compare any
+}
+
+func (w withCompare) apply(c *config) {
+	c.compare = w.compare
+}
+
+// 设置RBTree分片使用的key比较函数
+// compare(a, b) a < b 返回负数, a == b 返回0, a > b 返回正数, K必须和CMap的key类型一样
+func WithCompare[K comparable](compare func(a, b K) int) Option {
+	return withCompare{compare: compare}
+}
+
+type withShardMap struct {
+	newMap any
+}
+
+func (w withShardMap) apply(c *config) {
+	c.newMap = w.newMap
+}
+
+// 自定义分片里面使用的map, newMap每次需要返回一个新的map, 优先级比WithBackend高
+// K和V必须和CMap的类型一样
+func WithShardMap[K comparable, V any](newMap func() api.Map[K, V]) Option {
+	return withShardMap{newMap: newMap}
+}
+
+type withClock func() time.Time
+
+func (w withClock) apply(c *config) {
+	c.now = w
+}
+
+// 设置过期时间使用的时钟, 默认是time.Now, 测试的时候可以换成假的时钟
+func WithClock(now func() time.Time) Option {
+	return withClock(now)
+}
+
+type expireInterval time.Duration
+
+func (e expireInterval) apply(c *config) {
+	c.expireInterval = time.Duration(e)
+}
+
+// 设置后台主动过期的间隔, 默认是100ms, 小于0时只在访问key的时候删除过期的key
+func WithExpireInterval(d time.Duration) Option {
+	return expireInterval(d)
+}
+
+type withOnEvict struct {
+	onEvict any
+}
+
+func (w withOnEvict) apply(c *config) {
+	c.onEvict = w.onEvict
+}
+
+// 设置key过期删除时的回调, 在分片的锁里面调用, 回调里面不能再访问CMap
+// K和V必须和CMap的类型一样
+func WithOnEvict[K comparable, V any](onEvict func(k K, v V)) Option {
+	return withOnEvict{onEvict: onEvict}
+}
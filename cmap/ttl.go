@@ -0,0 +1,223 @@
+package cmap
+
+// apache 2.0 antlabs
+// 参考资料
+// https://github.com/redis/redis/blob/unstable/src/expire.c activeExpireCycle
+// 过期的key有两种删除方式
+// 1. lazy: 访问key的时候发现过期就删除
+// 2. active: 后台goroutine定时从每个分片里面随机取一些设置了过期时间的key, 删除过期的,
+// 过期的比例比较高时继续取, 每次最多使用interval的1/4时间
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 默认的主动过期间隔, 和redis的hz 10一样
+	defaultExpireInterval = 100 * time.Millisecond
+	// 每个分片每次取样的个数
+	expireSamples = 20
+	// 取样里面过期的比例(百分比)小于等于这个值时, 换下一个分片
+	expireAcceptableStale = 10
+)
+
+type expire[K comparable, V any] struct {
+	now     func() time.Time
+	onEvict func(k K, v V)
+	// 主动过期的间隔, 小于0时只有lazy过期
+	expireInterval time.Duration
+
+	expireMu      sync.Mutex
+	expireStarted atomic.Bool
+	closed        bool
+	stop          chan struct{}
+	done          chan struct{}
+	// 下一次主动过期从哪个分片开始
+	expireIdx int
+}
+
+func (c *CMap[K, V]) initExpire(conf *config) {
+	c.now = conf.now
+	if c.now == nil {
+		c.now = time.Now
+	}
+
+	if conf.onEvict != nil {
+		onEvict, ok := conf.onEvict.(func(k K, v V))
+		if !ok {
+			panic("cmap: the type of WithOnEvict does not match the map")
+		}
+		c.onEvict = onEvict
+	}
+
+	c.expireInterval = conf.expireInterval
+	if c.expireInterval == 0 {
+		c.expireInterval = defaultExpireInterval
+	}
+}
+
+// 保存key, ttl之后过期, ttl小于等于0时和Delete一样
+// 第一次调用时启动后台的过期goroutine, 不需要的时候调用Close关闭
+func (c *CMap[K, V]) StoreWithTTL(key K, value V, ttl time.Duration) {
+	item := c.lockItem(key, true)
+	if ttl <= 0 {
+		item.delete(key)
+		item.rw.Unlock()
+		return
+	}
+
+	item.m.Set(key, value)
+	item.setExpire(key, c.now().Add(ttl).UnixNano())
+	item.rw.Unlock()
+
+	c.startExpire()
+}
+
+// 关闭后台的过期goroutine, lazy过期还是有效的, 可以调用多次
+func (c *CMap[K, V]) Close() {
+	c.expireMu.Lock()
+	defer c.expireMu.Unlock()
+	if c.closed {
+		return
+	}
+
+	c.closed = true
+	if c.stop != nil {
+		close(c.stop)
+		<-c.done
+	}
+}
+
+func (c *CMap[K, V]) startExpire() {
+	if c.expireStarted.Load() {
+		return
+	}
+
+	c.expireMu.Lock()
+	defer c.expireMu.Unlock()
+	if c.closed || c.expireInterval < 0 || c.expireStarted.Load() {
+		return
+	}
+
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	c.expireStarted.Store(true)
+	go c.expireLoop(c.stop, c.done)
+}
+
+func (c *CMap[K, V]) expireLoop(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(c.expireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.activeExpireCycle(c.expireInterval / 4)
+		}
+	}
+}
+
+// 主动过期, 从上一次停止的分片开始, 每个分片取样expireSamples个key, 删除过期的
+// 过期的比例超过expireAcceptableStale时继续取样这个分片, 超过timeLimit就停止, 返回删除的个数
+func (c *CMap[K, V]) activeExpireCycle(timeLimit time.Duration) (expired int) {
+	start := time.Now()
+	t := c.table.Load()
+	n := len(t.bucket)
+	for i := 0; i < n; i++ {
+		idx := c.expireIdx % n
+		c.expireIdx = idx + 1
+		item := &t.bucket[idx]
+		for {
+			sampled, deleted := c.expireSample(item)
+			expired += deleted
+			if time.Since(start) > timeLimit {
+				return
+			}
+			if deleted*100 <= sampled*expireAcceptableStale {
+				break
+			}
+		}
+	}
+	return
+}
+
+// 取样一个分片, 返回取样和删除的个数
+func (c *CMap[K, V]) expireSample(item *Item[K, V]) (sampled, deleted int) {
+	item.rw.Lock()
+	defer item.rw.Unlock()
+	// 已经迁移的分片, 在新表里面处理
+	if item.next != nil || len(item.expires) == 0 {
+		return
+	}
+
+	now := c.now().UnixNano()
+	// map的遍历顺序是随机的
+	for k, deadline := range item.expires {
+		if sampled == expireSamples {
+			break
+		}
+		sampled++
+		if now >= deadline {
+			c.evict(item, k)
+			deleted++
+		}
+	}
+	return
+}
+
+// key过期了就删除, 需要持有写锁
+func (c *CMap[K, V]) expireIfNeeded(item *Item[K, V], key K) {
+	if item.expiredAt(key, c.itemNow(item)) {
+		c.evict(item, key)
+	}
+}
+
+// 删除过期的key, 调用OnEvict回调
+func (c *CMap[K, V]) evict(item *Item[K, V], key K) {
+	v := item.m.Get(key)
+	item.delete(key)
+	if c.onEvict != nil {
+		c.onEvict(key, v)
+	}
+}
+
+// 分片里面没有设置过期时间的key时返回0, 不用读时钟
+func (c *CMap[K, V]) itemNow(item *Item[K, V]) int64 {
+	if len(item.expires) == 0 {
+		return 0
+	}
+	return c.now().UnixNano()
+}
+
+// key在now的时候是否已经过期
+func (item *Item[K, V]) expiredAt(key K, now int64) bool {
+	if len(item.expires) == 0 {
+		return false
+	}
+	deadline, ok := item.expires[key]
+	return ok && now >= deadline
+}
+
+func (item *Item[K, V]) setExpire(key K, deadline int64) {
+	if item.expires == nil {
+		item.expires = make(map[K]int64)
+	}
+	item.expires[key] = deadline
+}
+
+// 清除过期时间
+func (item *Item[K, V]) persist(key K) {
+	if len(item.expires) > 0 {
+		delete(item.expires, key)
+	}
+}
+
+// 删除key和过期时间
+func (item *Item[K, V]) delete(key K) {
+	item.m.Delete(key)
+	item.persist(key)
+}
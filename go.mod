@@ -1,6 +1,6 @@
 module github.com/antlabs/gstl
 
-go 1.19
+go 1.24
 
 require (
 	github.com/cespare/xxhash/v2 v2.1.2
@@ -0,0 +1,130 @@
+package hasher
+
+// apache 2.0 antlabs
+// 给hash表使用的hash函数
+// 1. string, 整数, 浮点数使用(可以替换的)字符串hash函数, 默认是xxhash
+// 2. 其它可比较的类型(struct, 数组, 指针等)使用hash/maphash, 和go内置map的hash规则一样,
+// struct里面的string按内容计算, 填充字节不参与计算
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"unsafe"
+
+	xxhash "github.com/cespare/xxhash/v2"
+	"golang.org/x/exp/constraints"
+)
+
+// 计算key的hash值, 相等的key必须返回相同的hash值
+type Hasher[K comparable] interface {
+	Hash(k K) uint64
+}
+
+// 把普通函数转成Hasher
+type Func[K comparable] func(k K) uint64
+
+func (f Func[K]) Hash(k K) uint64 {
+	return f(k)
+}
+
+// 根据K的类型返回默认的Hasher
+// hashFunc用于string, 整数和浮点数类型的key, 为nil时使用xxhash
+// 其它类型使用Comparable
+func New[K comparable](hashFunc func(str string) uint64) Hasher[K] {
+	switch reflect.TypeFor[K]().Kind() {
+	case reflect.String:
+		return Func[K](func(k K) uint64 {
+			// K的底层类型是string, 内存布局一样
+			return stringHash(hashFunc, *(*string)(unsafe.Pointer(&k)))
+		})
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Func[K](func(k K) uint64 {
+			return uint64Hash(hashFunc, loadUint64(unsafe.Pointer(&k), unsafe.Sizeof(k)))
+		})
+	case reflect.Float32:
+		return Func[K](func(k K) uint64 {
+			return float64Hash(hashFunc, float64(*(*float32)(unsafe.Pointer(&k))))
+		})
+	case reflect.Float64:
+		return Func[K](func(k K) uint64 {
+			return float64Hash(hashFunc, *(*float64)(unsafe.Pointer(&k)))
+		})
+	}
+
+	return Comparable[K]()
+}
+
+// string类型的Hasher, hashFunc为nil时使用xxhash
+func String[K ~string](hashFunc func(str string) uint64) Hasher[K] {
+	return Func[K](func(k K) uint64 {
+		return stringHash(hashFunc, string(k))
+	})
+}
+
+// 整数类型的Hasher, hashFunc为nil时使用xxhash
+func Integer[K constraints.Integer](hashFunc func(str string) uint64) Hasher[K] {
+	return Func[K](func(k K) uint64 {
+		return uint64Hash(hashFunc, uint64(k))
+	})
+}
+
+// 浮点数类型的Hasher, hashFunc为nil时使用xxhash
+// +0和-0的hash值相同, NaN和任何值都不相等, 每次返回随机的hash值
+func Float[K constraints.Float](hashFunc func(str string) uint64) Hasher[K] {
+	return Func[K](func(k K) uint64 {
+		return float64Hash(hashFunc, float64(k))
+	})
+}
+
+// 任意可比较类型的Hasher, 基于hash/maphash
+// 每个Hasher使用自己的随机种子
+func Comparable[K comparable]() Hasher[K] {
+	seed := maphash.MakeSeed()
+	return Func[K](func(k K) uint64 {
+		return maphash.Comparable(seed, k)
+	})
+}
+
+func stringHash(hashFunc func(str string) uint64, s string) uint64 {
+	if hashFunc == nil {
+		return xxhash.Sum64String(s)
+	}
+	return hashFunc(s)
+}
+
+func uint64Hash(hashFunc func(str string) uint64, u uint64) uint64 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], u)
+	if hashFunc == nil {
+		return xxhash.Sum64(buf[:])
+	}
+	return hashFunc(string(buf[:]))
+}
+
+func float64Hash(hashFunc func(str string) uint64, f float64) uint64 {
+	switch {
+	case f == 0:
+		// +0和-0相等, 使用相同的hash值
+		f = 0
+	case f != f:
+		// NaN
+		return rand.Uint64()
+	}
+	return uint64Hash(hashFunc, math.Float64bits(f))
+}
+
+// 按大小读取整数, 符号位不影响相等性
+func loadUint64(p unsafe.Pointer, size uintptr) uint64 {
+	switch size {
+	case 1:
+		return uint64(*(*uint8)(p))
+	case 2:
+		return uint64(*(*uint16)(p))
+	case 4:
+		return uint64(*(*uint32)(p))
+	}
+	return *(*uint64)(p)
+}
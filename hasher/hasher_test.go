@@ -0,0 +1,92 @@
+package hasher
+
+// apache 2.0 antlabs
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type myString string
+
+type key struct {
+	name string
+	id   int8
+	// id和age之间有填充字节
+	age int64
+}
+
+func Test_Hasher_String(t *testing.T) {
+	h := New[string](nil)
+	a := strings.Repeat("a", 10)
+	b := strings.Repeat("a", 10)
+	assert.Equal(t, h.Hash(a), h.Hash(b))
+	assert.NotEqual(t, h.Hash("a"), h.Hash("b"))
+
+	// 底层类型是string, 结果和String一样
+	assert.Equal(t, New[myString](nil).Hash("hello"), String[myString](nil).Hash("hello"))
+	assert.Equal(t, New[myString](nil).Hash("hello"), h.Hash("hello"))
+}
+
+func Test_Hasher_Integer(t *testing.T) {
+	h := New[int](nil)
+	assert.Equal(t, h.Hash(1), h.Hash(1))
+	assert.NotEqual(t, h.Hash(1), h.Hash(2))
+	assert.Equal(t, h.Hash(-1), Integer[int](nil).Hash(-1))
+
+	h8 := New[int8](nil)
+	assert.Equal(t, h8.Hash(-1), Integer[uint8](nil).Hash(0xff))
+	assert.NotEqual(t, h8.Hash(-1), h8.Hash(1))
+
+	hb := New[bool](nil)
+	assert.NotEqual(t, hb.Hash(true), hb.Hash(false))
+}
+
+func Test_Hasher_Float(t *testing.T) {
+	for _, h := range []Hasher[float64]{New[float64](nil), Float[float64](nil)} {
+		assert.Equal(t, h.Hash(0), h.Hash(math.Copysign(0, -1)))
+		assert.Equal(t, h.Hash(1.5), h.Hash(1.5))
+		assert.NotEqual(t, h.Hash(1.5), h.Hash(2.5))
+
+		// NaN和任何值都不相等, hash值是随机的
+		nan := math.NaN()
+		assert.NotEqual(t, h.Hash(nan), h.Hash(nan))
+	}
+
+	h32 := New[float32](nil)
+	assert.Equal(t, h32.Hash(0), h32.Hash(float32(math.Copysign(0, -1))))
+	assert.Equal(t, h32.Hash(1.5), New[float64](nil).Hash(1.5))
+}
+
+// struct里面的string按内容计算
+func Test_Hasher_Struct(t *testing.T) {
+	h := New[key](nil)
+	a := key{name: strings.Repeat("a", 10), id: 1, age: 2}
+	b := key{name: strings.Repeat("a", 10), id: 1, age: 2}
+	assert.Equal(t, h.Hash(a), h.Hash(b))
+
+	b.age = 3
+	assert.NotEqual(t, h.Hash(a), h.Hash(b))
+
+	hp := New[*int](nil)
+	x, y := 1, 1
+	assert.Equal(t, hp.Hash(&x), hp.Hash(&x))
+	assert.NotEqual(t, hp.Hash(&x), hp.Hash(&y))
+}
+
+// 设置的hashFunc对string, 整数和浮点数生效
+func Test_Hasher_HashFunc(t *testing.T) {
+	var got []string
+	hashFunc := func(s string) uint64 {
+		got = append(got, s)
+		return uint64(len(s))
+	}
+
+	assert.Equal(t, New[string](hashFunc).Hash("hello"), uint64(5))
+	assert.Equal(t, New[int32](hashFunc).Hash(1), uint64(8))
+	assert.Equal(t, New[float64](hashFunc).Hash(1), uint64(8))
+	assert.Equal(t, len(got), 3)
+	assert.Equal(t, got[0], "hello")
+}
@@ -0,0 +1,103 @@
+// apache 2.0 antlabs
+
+// 有序容器通用的迭代器(游标), 给rbtree和avltree使用
+// 游标记住创建时容器的version, 容器被修改(新加或者删除元素)后游标不会失效,
+// 下次调用Next/Prev时会根据当前的key重新定位到它的后继/前驱(O(log n)), 可以看到修改后的数据.
+// 如果当前元素已经被删除, Key/Value返回的还是删除前的数据
+package cursor
+
+// 容器需要提供的操作, N是节点指针, 零值(nil)表示没有这个节点
+type Source[K any, V any, N comparable] interface {
+	// 每次新加或者删除元素都会变化
+	Version() int
+	// 第一个节点
+	FirstNode() N
+	// 最后一个节点
+	LastNode() N
+	// 第一个大于等于k的节点
+	CeilingNode(k K) N
+	// 第一个大于k的节点
+	HigherNode(k K) N
+	// 最后一个小于k的节点
+	LowerNode(k K) N
+	// n的后继, 容器没有被修改时使用
+	NextNode(n N) N
+	// n的前驱, 容器没有被修改时使用
+	PrevNode(n N) N
+	// 节点的key和value
+	NodeKey(n N) K
+	NodeValue(n N) V
+}
+
+// 可以暂停, 恢复, 双向移动的游标
+type Cursor[K any, V any, N comparable] struct {
+	src     Source[K, V, N]
+	node    N
+	version int
+}
+
+// 创建一个游标, 使用之前需要先调用Seek, First或者Last定位
+func New[K any, V any, N comparable](src Source[K, V, N]) Cursor[K, V, N] {
+	return Cursor[K, V, N]{src: src}
+}
+
+func (c *Cursor[K, V, N]) setNode(n N) bool {
+	c.node = n
+	c.version = c.src.Version()
+	return c.Valid()
+}
+
+// 定位到第一个大于等于k的元素
+func (c *Cursor[K, V, N]) Seek(k K) bool {
+	return c.setNode(c.src.CeilingNode(k))
+}
+
+// 定位到第一个元素
+func (c *Cursor[K, V, N]) First() bool {
+	return c.setNode(c.src.FirstNode())
+}
+
+// 定位到最后一个元素
+func (c *Cursor[K, V, N]) Last() bool {
+	return c.setNode(c.src.LastNode())
+}
+
+// 移动到下一个元素, 没有下一个元素返回false
+func (c *Cursor[K, V, N]) Next() bool {
+	if !c.Valid() {
+		return false
+	}
+
+	if c.version != c.src.Version() {
+		return c.setNode(c.src.HigherNode(c.src.NodeKey(c.node)))
+	}
+	return c.setNode(c.src.NextNode(c.node))
+}
+
+// 移动到上一个元素, 没有上一个元素返回false
+func (c *Cursor[K, V, N]) Prev() bool {
+	if !c.Valid() {
+		return false
+	}
+
+	if c.version != c.src.Version() {
+		return c.setNode(c.src.LowerNode(c.src.NodeKey(c.node)))
+	}
+	return c.setNode(c.src.PrevNode(c.node))
+}
+
+// 是否指向一个元素
+func (c *Cursor[K, V, N]) Valid() bool {
+	var zero N
+	return c.node != zero
+}
+
+// 返回当前元素的key, 调用之前需要保证Valid()为true
+func (c *Cursor[K, V, N]) Key() K {
+	return c.src.NodeKey(c.node)
+}
+
+// 返回当前元素的value, 调用之前需要保证Valid()为true
+func (c *Cursor[K, V, N]) Value() V {
+	return c.src.NodeValue(c.node)
+}
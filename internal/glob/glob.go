@@ -0,0 +1,234 @@
+// apache 2.0 antlabs
+
+// glob模式匹配, 给trie和radix使用
+// 和redis的KEYS命令一样支持
+// 1. ? 匹配任意一个字符
+// 2. * 匹配任意多个字符(包含0个)
+// 3. [abc] 匹配括号里面的任意一个字符, [a-z] 匹配范围, [^a-z]或者[!a-z]取反
+// 4. \ 转义后面的字符
+// 字符都是按rune计算的, 没有闭合的[当成普通字符
+// 匹配的字符串按label读入(见label包), 不合法的utf8字节各自是一个字符
+// 模式编译成一个NFA, 状态是模式里面的位置(用位图保存), 树上每走一个rune就转移一次状态,
+// 状态为空时说明这个子树下面不可能有匹配的key, 可以直接跳过
+package glob
+
+import (
+	"unicode/utf8"
+
+	"github.com/antlabs/gstl/internal/label"
+)
+
+type kind uint8
+
+const (
+	kindLiteral kind = iota
+	kindAny
+	kindStar
+	kindClass
+)
+
+type runeRange struct {
+	lo, hi rune
+}
+
+type token struct {
+	kind   kind
+	r      rune
+	ranges []runeRange
+	negate bool
+}
+
+func (t *token) match(r rune) bool {
+	switch t.kind {
+	case kindLiteral:
+		return t.r == r
+	case kindAny:
+		return true
+	case kindClass:
+		for _, rr := range t.ranges {
+			if rr.lo <= r && r <= rr.hi {
+				return !t.negate
+			}
+		}
+		return t.negate
+	}
+	return false
+}
+
+// 编译之后的模式
+type Pattern struct {
+	tokens []token
+	// 位图需要的uint64个数
+	words int
+}
+
+// NFA的状态, 第i位是1表示已经匹配到模式的第i个位置
+type State []uint64
+
+func (s State) has(i int) bool {
+	return s[i/64]&(1<<(i%64)) != 0
+}
+
+func (s State) set(i int) {
+	s[i/64] |= 1 << (i % 64)
+}
+
+// 状态是否为空, 为空时后面不可能再匹配
+func (s State) Empty() bool {
+	for _, w := range s {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// 编译模式
+func Compile(pattern string) *Pattern {
+	p := &Pattern{}
+	for len(pattern) > 0 {
+		r, size := utf8.DecodeRuneInString(pattern)
+		switch r {
+		case '?':
+			p.tokens = append(p.tokens, token{kind: kindAny})
+		case '*':
+			// 连续的*和一个*一样
+			if n := len(p.tokens); n == 0 || p.tokens[n-1].kind != kindStar {
+				p.tokens = append(p.tokens, token{kind: kindStar})
+			}
+		case '[':
+			if t, n, ok := parseClass(pattern[size:]); ok {
+				p.tokens = append(p.tokens, t)
+				size += n
+				break
+			}
+			p.tokens = append(p.tokens, token{kind: kindLiteral, r: r})
+		case '\\':
+			if len(pattern) > size {
+				var n int
+				r, n = utf8.DecodeRuneInString(pattern[size:])
+				size += n
+			}
+			p.tokens = append(p.tokens, token{kind: kindLiteral, r: r})
+		default:
+			p.tokens = append(p.tokens, token{kind: kindLiteral, r: r})
+		}
+		pattern = pattern[size:]
+	}
+
+	p.words = (len(p.tokens) + 1 + 63) / 64
+	return p
+}
+
+// 解析[之后的内容, 返回token和使用的字节数, 没有]时返回false
+func parseClass(s string) (t token, n int, ok bool) {
+	t.kind = kindClass
+	if len(s) > 0 && (s[0] == '^' || s[0] == '!') {
+		t.negate = true
+		n++
+	}
+
+	first := true
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		n += size
+		if r == ']' && !first {
+			return t, n, true
+		}
+		first = false
+
+		if r == '\\' && n < len(s) {
+			r, size = utf8.DecodeRuneInString(s[n:])
+			n += size
+		}
+
+		lo, hi := r, r
+		// a-z, -在最后的时候是普通字符
+		if n+1 < len(s) && s[n] == '-' && s[n+1] != ']' {
+			n++
+			hi, size = utf8.DecodeRuneInString(s[n:])
+			n += size
+			if hi == '\\' && n < len(s) {
+				hi, size = utf8.DecodeRuneInString(s[n:])
+				n += size
+			}
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+		}
+		t.ranges = append(t.ranges, runeRange{lo: lo, hi: hi})
+	}
+	return t, 0, false
+}
+
+// 新建一个空的状态, 可以给Step当dst
+func (p *Pattern) NewState() State {
+	return make(State, p.words)
+}
+
+// 初始状态
+func (p *Pattern) Start() State {
+	s := p.NewState()
+	p.add(s, 0)
+	return s
+}
+
+// 加入位置i, *可以匹配0个字符, 所以后面的位置也要加入
+func (p *Pattern) add(s State, i int) {
+	for {
+		s.set(i)
+		if i == len(p.tokens) || p.tokens[i].kind != kindStar {
+			return
+		}
+		i++
+	}
+}
+
+// 从状态s读入r, 新的状态写入dst并返回
+func (p *Pattern) Step(s State, r rune, dst State) State {
+	clear(dst)
+	for i := range p.tokens {
+		if !s.has(i) {
+			continue
+		}
+
+		t := &p.tokens[i]
+		if t.kind == kindStar {
+			p.add(dst, i)
+			continue
+		}
+		if t.match(r) {
+			p.add(dst, i+1)
+		}
+	}
+	return dst
+}
+
+// 按label读入str, 状态为空时提前返回
+// 不能用range str, 它会把不合法的utf8字节都变成U+FFFD, 和trie, radix的label不一样
+func (p *Pattern) StepString(s State, str string, dst State) State {
+	tmp := p.NewState()
+	copy(tmp, s)
+	for i := 0; i < len(str); {
+		r, size := label.Decode(str[i:])
+		i += size
+		dst = p.Step(tmp, r, dst)
+		if dst.Empty() {
+			return dst
+		}
+		copy(tmp, dst)
+	}
+	copy(dst, tmp)
+	return dst
+}
+
+// 状态s是否匹配了整个模式
+func (p *Pattern) Accept(s State) bool {
+	return s.has(len(p.tokens))
+}
+
+// 整个字符串是否匹配模式
+func (p *Pattern) Match(str string) bool {
+	s := p.StepString(p.Start(), str, p.NewState())
+	return p.Accept(s)
+}
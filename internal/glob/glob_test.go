@@ -0,0 +1,71 @@
+package glob
+
+// apache 2.0 antlabs
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Match(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		str     string
+		need    bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"*", "user:1:session", true},
+		{"user:*:session", "user:1:session", true},
+		{"user:*:session", "user:1:2:session", true},
+		{"user:*:session", "user:1:sessions", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[!e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{"h[a-]llo", "h-llo", true},
+		{"h[]]llo", "h]llo", true},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"h[llo", "h[llo", true},
+		{"中?", "中文", true},
+		{"[一-龥]*", "中文", true},
+		{"**a**", "bab", true},
+		// 不合法的utf8字节各自是一个字符, 不等于U+FFFD
+		{"a?", "a\xff", true},
+		{"a??", "a\xff\xfe", true},
+		{"a\ufffd", "a\xff", false},
+		{"a[^\ufffd]", "a\xff", true},
+	} {
+		assert.Equal(t, Compile(tc.pattern).Match(tc.str), tc.need, tc.pattern+" "+tc.str)
+	}
+}
+
+// 没有/的时候和path.Match的结果一样
+func Test_Match_Path(t *testing.T) {
+	strs := []string{"", "a", "ab", "abc", "bac", "cab", "aaa", "abab", "x-y"}
+	patterns := []string{"a*", "*b", "*a*", "?b*", "[ab]*", "[^a]*", "a?c", "*[a-c]", "a*b*"}
+	for _, p := range patterns {
+		g := Compile(p)
+		for _, s := range strs {
+			need, err := path.Match(p, s)
+			assert.NoError(t, err)
+			assert.Equal(t, g.Match(s), need, p+" "+s)
+		}
+	}
+}
+
+// 状态为空时可以提前停止
+func Test_State_Empty(t *testing.T) {
+	g := Compile("ab*")
+	s := g.Step(g.Start(), 'a', g.NewState())
+	assert.False(t, s.Empty())
+	assert.True(t, g.Step(s, 'c', g.NewState()).Empty())
+	assert.True(t, g.StepString(g.Start(), "ba", g.NewState()).Empty())
+}
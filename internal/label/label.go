@@ -0,0 +1,25 @@
+// apache 2.0 antlabs
+
+// trie和radix的边上保存的label, 也是glob模式匹配时每一步读入的字符
+// 合法的utf8字符就是它的rune, 不是合法utf8的字节不能都用utf8.RuneError表示
+// (会和其它非法字节以及U+FFFD冲突), 映射到utf8.MaxRune后面, 每个字节一个label
+package label
+
+import "unicode/utf8"
+
+// 返回k的第一个label和它的字节数
+func Decode(k string) (r rune, size int) {
+	r, size = utf8.DecodeRuneInString(k)
+	if r == utf8.RuneError && size == 1 {
+		return utf8.MaxRune + 1 + rune(k[0]), 1
+	}
+	return r, size
+}
+
+// 把label还原成字节追加到dst, 是Decode的逆操作
+func Append(dst []byte, r rune) []byte {
+	if r > utf8.MaxRune {
+		return append(dst, byte(r-utf8.MaxRune-1))
+	}
+	return utf8.AppendRune(dst, r)
+}
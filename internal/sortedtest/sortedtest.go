@@ -0,0 +1,571 @@
+// apache 2.0 antlabs
+
+// 有序容器(rbtree, avltree, btree, skiplist)共用的测试
+// 每个容器的测试文件只需要传入自己的构造函数, 各自特有的边界情况(旋转, 分裂合并, 重复score)
+// 还是放在各自的测试文件里面
+package sortedtest
+
+import (
+	"fmt"
+	"iter"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/antlabs/gstl/api"
+	"github.com/antlabs/gstl/cmp"
+	"github.com/antlabs/gstl/vec"
+	"github.com/stretchr/testify/assert"
+)
+
+// 有序容器需要实现的接口, I是容器自己的迭代器类型
+type Map[K any, V any, I api.Iterator[K, V]] interface {
+	api.SortedMap[K, V]
+	Iterator() I
+	All() iter.Seq2[K, V]
+	Keys() iter.Seq[K]
+	Values() iter.Seq[V]
+	Backward() iter.Seq2[K, V]
+}
+
+// 插入0到max-1的偶数, value是key/2
+func evens[M api.SortedMap[int, int]](newMap func() M, max int) M {
+	b := newMap()
+	for i := 0; i < max; i++ {
+		b.Set(i*2, i)
+	}
+	return b
+}
+
+// 测试Floor, Ceiling, Lower, Higher
+func FloorCeiling[M api.SortedMap[int, int]](t *testing.T, newMap func() M) {
+	max := 500
+	// 只插入偶数
+	b := evens(newMap, max)
+
+	check := func(name string, k int, key int, val int, ok bool, needKey int, needOk bool) {
+		msg := fmt.Sprintf("%s(%d)", name, k)
+		assert.Equal(t, ok, needOk, msg)
+		if needOk {
+			assert.Equal(t, key, needKey, msg)
+			assert.Equal(t, val, needKey/2, msg)
+		}
+	}
+
+	last := (max - 1) * 2
+	for k := -3; k <= last+3; k++ {
+		// 小于等于k的最大偶数, 大于等于k的最小偶数
+		floor, ceiling := k, k
+		if k%2 != 0 {
+			floor, ceiling = k-1, k+1
+		}
+		lower, higher := floor, ceiling
+		if k%2 == 0 {
+			lower, higher = k-2, k+2
+		}
+
+		floor, lower = cmp.Min(floor, last), cmp.Min(lower, last)
+		ceiling, higher = cmp.Max(ceiling, 0), cmp.Max(higher, 0)
+
+		key, val, ok := b.Floor(k)
+		check("Floor", k, key, val, ok, floor, floor >= 0)
+
+		key, val, ok = b.Ceiling(k)
+		check("Ceiling", k, key, val, ok, ceiling, ceiling <= last)
+
+		key, val, ok = b.Lower(k)
+		check("Lower", k, key, val, ok, lower, lower >= 0)
+
+		key, val, ok = b.Higher(k)
+		check("Higher", k, key, val, ok, higher, higher <= last)
+	}
+
+	// 空的容器
+	empty := newMap()
+	_, _, ok := empty.Floor(1)
+	assert.False(t, ok)
+	_, _, ok = empty.Ceiling(1)
+	assert.False(t, ok)
+	_, _, ok = empty.Lower(1)
+	assert.False(t, ok)
+	_, _, ok = empty.Higher(1)
+	assert.False(t, ok)
+}
+
+// 测试RangeBetween和RangePrevBetween
+func RangeBetween[M api.SortedMap[int, int]](t *testing.T, newMap func() M) {
+	max := 200
+	// 只插入偶数
+	b := evens(newMap, max)
+
+	for _, tc := range [][2]int{{-10, 1000}, {10, 20}, {11, 19}, {-5, 3}, {395, 500}, {6, 6}, {7, 7}, {20, 10}} {
+		for _, inc := range [][2]bool{{true, true}, {true, false}, {false, true}, {false, false}} {
+			lo, hi := tc[0], tc[1]
+			var need []int
+			for i := 0; i < max; i++ {
+				k := i * 2
+				if k < lo || !inc[0] && k == lo || k > hi || !inc[1] && k == hi {
+					continue
+				}
+				need = append(need, k)
+			}
+
+			msg := fmt.Sprintf("lo:%d, hi:%d, inclusive:%v", lo, hi, inc)
+			var got []int
+			b.RangeBetween(lo, hi, inc[0], inc[1], func(k, v int) bool {
+				assert.Equal(t, k/2, v, msg)
+				got = append(got, k)
+				return true
+			})
+			assert.Equal(t, got, need, msg)
+
+			got = nil
+			b.RangePrevBetween(lo, hi, inc[0], inc[1], func(k, v int) bool {
+				assert.Equal(t, k/2, v, msg)
+				got = append(got, k)
+				return true
+			})
+			assert.Equal(t, got, vec.New(need...).Rev().ToSlice(), msg)
+		}
+	}
+
+	// callback返回false, 提前退出
+	var got []int
+	b.RangeBetween(10, 100, true, true, func(k, v int) bool {
+		got = append(got, k)
+		return len(got) < 3
+	})
+	assert.Equal(t, got, []int{10, 12, 14})
+
+	got = nil
+	b.RangePrevBetween(10, 100, true, true, func(k, v int) bool {
+		got = append(got, k)
+		return len(got) < 3
+	})
+	assert.Equal(t, got, []int{100, 98, 96})
+}
+
+// 测试迭代器
+func Iterator[M Map[int, int, I], I api.Iterator[int, int]](t *testing.T, newMap func() M) {
+	b := newMap()
+	it := b.Iterator()
+	assert.False(t, it.Valid())
+	assert.False(t, it.First())
+	assert.False(t, it.Last())
+	assert.False(t, it.Next())
+
+	max := 500
+	// 只插入偶数
+	for i := 0; i < max; i++ {
+		b.Set(i*2, i)
+	}
+
+	// 正向
+	var got []int
+	for ok := it.First(); ok; ok = it.Next() {
+		assert.Equal(t, it.Key()/2, it.Value())
+		got = append(got, it.Key())
+	}
+	assert.Equal(t, len(got), max)
+	for i, k := range got {
+		assert.Equal(t, k, i*2)
+	}
+	assert.False(t, it.Valid())
+
+	// 反向
+	got = got[:0]
+	for ok := it.Last(); ok; ok = it.Prev() {
+		got = append(got, it.Key())
+	}
+	assert.Equal(t, len(got), max)
+	for i, k := range got {
+		assert.Equal(t, k, (max-1-i)*2)
+	}
+
+	// Seek
+	for k := -1; k <= max*2; k++ {
+		ok := it.Seek(k)
+		need := k + k%2
+		if k < 0 {
+			need = 0
+		}
+		assert.Equal(t, ok, need < max*2, fmt.Sprintf("seek:%d", k))
+		if ok {
+			assert.Equal(t, it.Key(), need, fmt.Sprintf("seek:%d", k))
+		}
+	}
+
+	// Seek之后来回移动
+	assert.True(t, it.Seek(101))
+	assert.True(t, it.Prev())
+	assert.Equal(t, it.Key(), 100)
+	assert.True(t, it.Next())
+	assert.True(t, it.Next())
+	assert.Equal(t, it.Key(), 104)
+}
+
+// 迭代的过程中修改容器
+func IteratorMutation[M Map[int, int, I], I api.Iterator[int, int]](t *testing.T, newMap func() M) {
+	max := 500
+	b := evens(newMap, max)
+
+	// 边遍历边删除当前元素, 并插入奇数
+	it := b.Iterator()
+	var got []int
+	for ok := it.First(); ok; ok = it.Next() {
+		k := it.Key()
+		got = append(got, k)
+		if k%2 == 0 {
+			b.Delete(k)
+			b.Set(k+1, k+1)
+		}
+	}
+
+	// 新插入的奇数在当前元素后面, 也会被访问到
+	assert.Equal(t, len(got), max*2)
+	for i, k := range got {
+		assert.Equal(t, k, i)
+	}
+	assert.Equal(t, b.Len(), max)
+
+	// 反向遍历, 删除前一个元素
+	assert.True(t, it.Last())
+	assert.Equal(t, it.Key(), max*2-1)
+	b.Delete(max*2 - 3)
+	assert.True(t, it.Prev())
+	assert.Equal(t, it.Key(), max*2-5)
+
+	// Value返回的是现在的值, 新加元素之后也一样
+	assert.True(t, it.Seek(11))
+	b.Set(11, 110)
+	assert.Equal(t, it.Value(), 110)
+	b.Set(max*4, 0)
+	b.Set(11, 111)
+	assert.Equal(t, it.Value(), 111)
+
+	// 删除之后返回删除前的值
+	b.Delete(11)
+	assert.Equal(t, it.Key(), 11)
+	assert.Equal(t, it.Value(), 111)
+	assert.True(t, it.Next())
+	assert.Equal(t, it.Key(), 13)
+}
+
+// 使用两个迭代器合并两个有序容器
+func IteratorMerge[M Map[int, int, I], I api.Iterator[int, int]](t *testing.T, newMap func() M) {
+	a, b := newMap(), newMap()
+	for i := 0; i < 100; i++ {
+		if i%3 == 0 {
+			a.Set(i, i)
+		} else {
+			b.Set(i, i)
+		}
+	}
+
+	var got []int
+	ia, ib := a.Iterator(), b.Iterator()
+	okA, okB := ia.First(), ib.First()
+	for okA || okB {
+		if !okB || okA && ia.Key() < ib.Key() {
+			got = append(got, ia.Key())
+			okA = ia.Next()
+			continue
+		}
+
+		got = append(got, ib.Key())
+		okB = ib.Next()
+	}
+
+	assert.Equal(t, len(got), 100)
+	for i, k := range got {
+		assert.Equal(t, k, i)
+	}
+}
+
+// 测试range over func
+func Iter[M Map[int, int, I], I api.Iterator[int, int]](t *testing.T, newMap func() M) {
+	b := newMap()
+	max := 100
+	for i := max - 1; i >= 0; i-- {
+		b.Set(i, i*10)
+	}
+
+	var keys, vals []int
+	for k, v := range b.All() {
+		keys = append(keys, k)
+		vals = append(vals, v)
+	}
+	assert.Equal(t, len(keys), max)
+	for i := range keys {
+		assert.Equal(t, keys[i], i)
+		assert.Equal(t, vals[i], i*10)
+	}
+
+	keys = keys[:0]
+	for k := range b.Keys() {
+		if k == 3 {
+			break
+		}
+		keys = append(keys, k)
+	}
+	assert.Equal(t, keys, []int{0, 1, 2})
+
+	vals = vals[:0]
+	for v := range b.Values() {
+		if v == 30 {
+			break
+		}
+		vals = append(vals, v)
+	}
+	assert.Equal(t, vals, []int{0, 10, 20})
+
+	keys = keys[:0]
+	for k := range b.Backward() {
+		if k == max-4 {
+			break
+		}
+		keys = append(keys, k)
+	}
+	assert.Equal(t, keys, []int{max - 1, max - 2, max - 3})
+}
+
+// 不是Ordered类型的key, 先按X再按Y比较
+type Point struct {
+	X, Y int
+}
+
+func ComparePoint(a, b Point) int {
+	if c := cmp.Compare(a.X, b.X); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Y, b.Y)
+}
+
+// key不是Ordered类型, 使用自定义的比较函数
+func NewWithCompare[M Map[Point, int, I], I api.Iterator[Point, int]](t *testing.T, newMap func(compare func(a, b Point) int) M) {
+	b := newMap(ComparePoint)
+	for x := 9; x >= 0; x-- {
+		for y := 0; y < 10; y++ {
+			b.Set(Point{x, y}, x*10+y)
+		}
+	}
+	assert.Equal(t, b.Len(), 100)
+
+	need := 0
+	for k, v := range b.All() {
+		assert.Equal(t, k, Point{need / 10, need % 10})
+		assert.Equal(t, v, need)
+		need++
+	}
+	assert.Equal(t, need, 100)
+
+	v, ok := b.GetWithBool(Point{3, 4})
+	assert.True(t, ok)
+	assert.Equal(t, v, 34)
+	_, ok = b.GetWithBool(Point{3, 10})
+	assert.False(t, ok)
+
+	k, v, ok := b.Ceiling(Point{3, 10})
+	assert.True(t, ok)
+	assert.Equal(t, k, Point{4, 0})
+	assert.Equal(t, v, 40)
+
+	b.Delete(Point{4, 0})
+	k, _, ok = b.Higher(Point{3, 9})
+	assert.True(t, ok)
+	assert.Equal(t, k, Point{4, 1})
+}
+
+// 反过来的比较函数, 和Ordered版本的结果顺序相反
+func NewWithCompareReverse[M Map[int, int, I], I api.Iterator[int, int]](t *testing.T, newMap func(compare func(a, b int) int) M) {
+	b := newMap(func(a, b int) int { return cmp.Compare(b, a) })
+	for i := 0; i < 1000; i++ {
+		b.Set(i, i)
+	}
+
+	need := 999
+	for k := range b.Keys() {
+		assert.Equal(t, k, need)
+		need--
+	}
+	assert.Equal(t, need, -1)
+
+	var got []int
+	b.RangeBetween(10, 5, true, false, func(k int, _ int) bool {
+		got = append(got, k)
+		return true
+	})
+	assert.Equal(t, got, []int{10, 9, 8, 7, 6})
+}
+
+// 排好序的参考结果
+type reference struct {
+	keys []int
+	vals map[int]int
+}
+
+func (r *reference) set(k, v int) {
+	if _, ok := r.vals[k]; !ok {
+		i := sort.SearchInts(r.keys, k)
+		r.keys = append(r.keys, 0)
+		copy(r.keys[i+1:], r.keys[i:])
+		r.keys[i] = k
+	}
+	r.vals[k] = v
+}
+
+func (r *reference) delete(k int) {
+	if _, ok := r.vals[k]; !ok {
+		return
+	}
+	i := sort.SearchInts(r.keys, k)
+	r.keys = append(r.keys[:i], r.keys[i+1:]...)
+	delete(r.vals, k)
+}
+
+// 返回keys[i], i越界时ok为false
+func (r *reference) at(i int) (k int, ok bool) {
+	if i < 0 || i >= len(r.keys) {
+		return
+	}
+	return r.keys[i], true
+}
+
+// 和参考结果对比所有的查询
+func (r *reference) check(t *testing.T, b api.SortedMap[int, int], lo, hi int, msg string) {
+	assert.Equal(t, b.Len(), len(r.keys), msg)
+	for k := lo; k <= hi; k++ {
+		// 第一个大于等于k的位置, 第一个大于k的位置
+		ge := sort.SearchInts(r.keys, k)
+		gt := sort.SearchInts(r.keys, k+1)
+
+		queries := []struct {
+			name string
+			f    func(k int) (int, int, bool)
+			i    int
+		}{
+			{"Floor", b.Floor, gt - 1},
+			{"Ceiling", b.Ceiling, ge},
+			{"Lower", b.Lower, ge - 1},
+			{"Higher", b.Higher, gt},
+		}
+		for _, q := range queries {
+			key, val, ok := q.f(k)
+			needKey, needOk := r.at(q.i)
+			m := fmt.Sprintf("%s %s(%d)", msg, q.name, k)
+			if !assert.Equal(t, ok, needOk, m) {
+				return
+			}
+			if ok {
+				assert.Equal(t, key, needKey, m)
+				assert.Equal(t, val, r.vals[needKey], m)
+			}
+		}
+	}
+
+	// 区间的两端落在已有的key上, 也落在已经删除的key上
+	for _, tc := range [][2]int{{lo, hi}, {lo + 7, hi - 7}, {hi / 3, hi / 2}, {hi / 2, hi / 2}} {
+		var need []int
+		for _, k := range r.keys {
+			if k > tc[0] && k < tc[1] {
+				need = append(need, k)
+			}
+		}
+
+		var got []int
+		b.RangeBetween(tc[0], tc[1], false, false, func(k, v int) bool {
+			assert.Equal(t, v, r.vals[k], msg)
+			got = append(got, k)
+			return true
+		})
+		assert.Equal(t, got, need, fmt.Sprintf("%s RangeBetween(%d, %d)", msg, tc[0], tc[1]))
+
+		got = nil
+		b.RangePrevBetween(tc[0], tc[1], false, false, func(k, v int) bool {
+			got = append(got, k)
+			return true
+		})
+		assert.Equal(t, got, vec.New(need...).Rev().ToSlice(), fmt.Sprintf("%s RangePrevBetween(%d, %d)", msg, tc[0], tc[1]))
+	}
+}
+
+// 用迭代器正向和反向遍历, 和参考结果对比
+// 容器是空的时候, 两边都是nil
+func (r *reference) checkIter(t *testing.T, it api.Iterator[int, int], msg string) bool {
+	var keys, vals, needVals, needKeys []int
+	for ok := it.First(); ok; ok = it.Next() {
+		keys = append(keys, it.Key())
+		vals = append(vals, it.Value())
+	}
+	for _, k := range r.keys {
+		needKeys = append(needKeys, k)
+		needVals = append(needVals, r.vals[k])
+	}
+	if !assert.Equal(t, keys, needKeys, msg) || !assert.Equal(t, vals, needVals, msg) {
+		return false
+	}
+
+	keys = nil
+	for ok := it.Last(); ok; ok = it.Prev() {
+		keys = append(keys, it.Key())
+	}
+	return assert.Equal(t, keys, vec.New(needKeys...).Rev().ToSlice(), msg)
+}
+
+// 随机混合Set和Delete, 和排好序的参考结果对比
+// 每一步都用迭代器完整遍历一次, 另外有一个一直保留的迭代器在每次修改之后移动一步, 检查修改之后能不能找到后继/前驱
+// 每100步对比一次查询结果(Floor, Ceiling, Lower, Higher, RangeBetween)
+// 删除会触发各个容器自己的调整(旋转, 合并, 借元素), check在每一步之后检查容器自己的结构, 可以为nil
+func SetDeleteRandom[M Map[int, int, I], I api.Iterator[int, int]](t *testing.T, newMap func() M, check func(b M)) {
+	const (
+		steps  = 2000
+		maxKey = 300
+	)
+	b := newMap()
+	ref := &reference{vals: map[int]int{}}
+	r := rand.New(rand.NewSource(1))
+	walk, it := b.Iterator(), b.Iterator()
+	for step := 0; step < steps; step++ {
+		k := r.Intn(maxKey)
+		if r.Intn(2) == 0 {
+			b.Set(k, step)
+			ref.set(k, step)
+		} else {
+			b.Delete(k)
+			ref.delete(k)
+		}
+
+		if check != nil {
+			check(b)
+		}
+
+		msg := fmt.Sprintf("step:%d key:%d", step, k)
+		if !ref.checkIter(t, walk, msg) {
+			return
+		}
+
+		// 迭代器指向的元素可能已经被删除, 移动之后要落在参考结果里面当前key的后继(前驱)上
+		var ok bool
+		var i int
+		switch {
+		case !it.Valid():
+			ok, i = it.Seek(k), sort.SearchInts(ref.keys, k)
+		case step%2 == 0:
+			cur := it.Key()
+			ok, i = it.Next(), sort.SearchInts(ref.keys, cur+1)
+		default:
+			cur := it.Key()
+			ok, i = it.Prev(), sort.SearchInts(ref.keys, cur)-1
+		}
+		need, needOk := ref.at(i)
+		assert.Equal(t, ok, needOk, msg)
+		if ok && needOk {
+			assert.Equal(t, it.Key(), need, msg)
+			assert.Equal(t, it.Value(), ref.vals[need], msg)
+		}
+
+		if step%100 == 0 || step == steps-1 {
+			ref.check(t, b, -1, maxKey, fmt.Sprintf("step:%d", step))
+		}
+	}
+}
@@ -20,6 +20,7 @@ package linkedlist
 
 import (
 	"errors"
+	"iter"
 
 	"github.com/antlabs/gstl/cmp"
 )
@@ -604,3 +605,40 @@ func (l *LinkedList[T]) Trim(start, end int) *LinkedList[T] {
 	})
 	return l
 }
+
+// 返回索引和元素的迭代器, 可以配合for range使用
+// 遍历的过程中可以删除当前元素
+func (l *LinkedList[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		l.lazyInit()
+		i := 0
+		l.RangeSafe(func(n *Node[T]) bool {
+			exit := !yield(i, n.Element)
+			i++
+			return exit
+		})
+	}
+}
+
+// 返回元素的迭代器
+func (l *LinkedList[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		l.lazyInit()
+		l.RangeSafe(func(n *Node[T]) bool {
+			return !yield(n.Element)
+		})
+	}
+}
+
+// 从后向前返回索引和元素的迭代器
+func (l *LinkedList[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		l.lazyInit()
+		i := l.length - 1
+		l.RangePrevSafe(func(n *Node[T]) bool {
+			exit := !yield(i, n.Element)
+			i--
+			return exit
+		})
+	}
+}
@@ -301,3 +301,39 @@ func Test_OtherMoveToFrontList(t *testing.T) {
 	assert.Equal(t, l.ToSlice(), []int{1, 2, 3, 4, 5, 6})
 	assert.Equal(t, other.ToSlice(), []int(nil))
 }
+
+// 测试range over func
+func Test_LinkedList_Iter(t *testing.T) {
+	l := New[int]().PushBack(1, 2, 3, 4, 5)
+
+	var idx, got []int
+	for i, e := range l.All() {
+		idx = append(idx, i)
+		got = append(got, e)
+	}
+	assert.Equal(t, idx, []int{0, 1, 2, 3, 4})
+	assert.Equal(t, got, []int{1, 2, 3, 4, 5})
+
+	got = got[:0]
+	for e := range l.Values() {
+		if e == 4 {
+			break
+		}
+		got = append(got, e)
+	}
+	assert.Equal(t, got, []int{1, 2, 3})
+
+	idx, got = idx[:0], got[:0]
+	for i, e := range l.Backward() {
+		idx = append(idx, i)
+		got = append(got, e)
+	}
+	assert.Equal(t, idx, []int{4, 3, 2, 1, 0})
+	assert.Equal(t, got, []int{5, 4, 3, 2, 1})
+
+	// 零值也可以遍历
+	var empty LinkedList[int]
+	for range empty.All() {
+		t.Fatal("should be empty")
+	}
+}
@@ -1,12 +1,16 @@
 package radix
 
 // apache 2.0 antlabs
+// 参考资料
+// https://en.wikipedia.org/wiki/Radix_tree
 import (
+	"iter"
 	"strings"
 	"unicode/utf8"
 
 	"github.com/antlabs/gstl/api"
-	"github.com/antlabs/gstl/cmp"
+	"github.com/antlabs/gstl/internal/glob"
+	"github.com/antlabs/gstl/internal/label"
 	"github.com/antlabs/gstl/vec"
 )
 
@@ -29,7 +33,8 @@ type edge[V any] struct {
 type node[V any] struct {
 	pair[V]
 	prefix string
-	edges  vec.Vec[edge[V]]
+	// 按label从小到大排序
+	edges vec.Vec[edge[V]]
 }
 
 // 头节点
@@ -38,24 +43,43 @@ type Radix[V any] struct {
 	length int
 }
 
+// 初始化
+func New[V any]() *Radix[V] {
+	return &Radix[V]{root: &node[V]{}}
+}
+
+func (r *Radix[V]) lazyinit() {
+	if r.root == nil {
+		r.root = &node[V]{}
+	}
+}
+
 // 获取
 func (r *Radix[V]) Get(k string) (v V) {
 	v, _ = r.GetWithBool(k)
 	return
 }
 
-// 返回共同的前缀
+// 返回共同的前缀长度, 按utf8字符比较, 不会把一个字符从中间切开
+// 不是合法utf8的字节各自算一个字符, 两个key的第一个字符相同时返回值一定大于0
 func commonPrefix(k1, k2 string) (i int) {
-	min := cmp.Min(len(k1), len(k2))
-
-	for i = 0; i < min; i++ {
-		if k1[i] != k2[i] {
-			return i
+	for i < len(k1) && i < len(k2) {
+		r1, size1 := utf8.DecodeRuneInString(k1[i:])
+		r2, size2 := utf8.DecodeRuneInString(k2[i:])
+		if r1 != r2 || size1 != size2 || k1[i:i+size1] != k2[i:i+size2] {
+			break
 		}
+		i += size1
 	}
 	return
 }
 
+// 返回k的第一个字符, 也就是边的label, 和trie一样不是合法utf8的字节各自是一个label
+func firstRune(k string) rune {
+	r, _ := label.Decode(k)
+	return r
+}
+
 func (r *Radix[V]) newEdge(label rune, p pair[V], prefix string) edge[V] {
 	return edge[V]{
 		label: label,
@@ -67,38 +91,39 @@ func (r *Radix[V]) newEdge(label rune, p pair[V], prefix string) edge[V] {
 }
 
 // 设置
+func (r *Radix[V]) Set(k string, v V) {
+	_, _ = r.SetWithPrev(k, v)
+}
+
+// 设置, 如果k已经存在, 返回以前的值
 func (r *Radix[V]) SetWithPrev(k string, v V) (prev V, replaced bool) {
+	r.lazyinit()
 
 	var parent *node[V]
 	var found bool
 	n := r.root
 	remaining := k
+	kv := pair[V]{key: k, val: v, isSet: true}
 
 	for {
 
-		if len(k) == 0 {
+		if len(remaining) == 0 {
 			if n.isSet {
 				prev = n.val
 				n.val = v
 				return prev, true
 			}
 
-			n.key, n.val = k, v
-			n.isSet = true
+			n.pair = kv
 			r.length++
-			replaced = true
 			return
 		}
 
-		rune, _ := utf8.DecodeLastRuneInString(remaining)
+		label := firstRune(remaining)
 		parent = n
-		n, found = n.children(rune)
+		n, found = n.children(label)
 		if !found {
-			parent.edges.Push(r.newEdge(rune, pair[V]{
-				key:   k,
-				val:   v,
-				isSet: true,
-			}, remaining))
+			parent.insertChildren(label, r.newEdge(label, kv, remaining))
 			r.length++
 			return
 		}
@@ -110,9 +135,8 @@ func (r *Radix[V]) SetWithPrev(k string, v V) (prev V, replaced bool) {
 			continue
 		}
 
-		subRune, _ := utf8.DecodeLastRuneInString(remaining[commonPrefixLen:])
 		// 这里遇到分叉
-		// 这里的节点只加上，比如原来节点是/helloaxx, 现在要插入/hellobxx
+		// 比如原来节点是/helloaxx, 现在要插入/hellobxx
 		// /hello 会变成child
 		r.length++
 		child := &node[V]{
@@ -120,43 +144,63 @@ func (r *Radix[V]) SetWithPrev(k string, v V) (prev V, replaced bool) {
 			prefix: remaining[:commonPrefixLen],
 		}
 
-		// axx 变成child的儿子1
-		child.edges.Push(edge[V]{
-			label: subRune,
-			node:  n,
-		})
-		// 把/helloaxx 变成axx
-		n.prefix = n.prefix[commonPrefixLen:]
-
 		// 如果以前 parent指向/helloaxx，那么现在parent就指向/hello(即child节点)
-		// axx 和bxx都将成为/hello的两个子节点
-		parent.setChildren(rune, child)
+		parent.setChildren(label, child)
 
-		remaining = remaining[commonPrefixLen:]
-		pairKV := pair[V]{
-			key:   k,
-			val:   v,
-			isSet: true,
-		}
+		// 把/helloaxx 变成axx, axx 变成child的儿子1
+		n.prefix = n.prefix[commonPrefixLen:]
+		child.insertChildren(firstRune(n.prefix), edge[V]{
+			label: firstRune(n.prefix),
+			node:  n,
+		})
 
-		// 如果新插入路径只是原路径的子集，就走起
+		// 如果新插入路径只是原路径的子集
 		// 比如原路径是/helloaxx, 本次插入/hello
+		remaining = remaining[commonPrefixLen:]
 		if len(remaining) == 0 {
-			child.pair = pairKV
+			child.pair = kv
 			return
 		}
 
-		subRune, _ = utf8.DecodeLastRuneInString(remaining)
-
-		// 把bxx的路径是在newEdge函数里面设置的
-		child.insertChildren(subRune, r.newEdge(subRune, pairKV, remaining))
+		// bxx 变成child的儿子2
+		label = firstRune(remaining)
+		child.insertChildren(label, r.newEdge(label, kv, remaining))
+		return
 	}
 
 }
 
-// 是否有这个前缀串
+// 是否有以k为前缀的key
 func (r *Radix[V]) HasPrefix(k string) (ok bool) {
-	return
+	return r.prefixNode(k) != nil
+}
+
+// 返回包含所有以k为前缀的key的子树, 没有返回nil
+func (r *Radix[V]) prefixNode(k string) *node[V] {
+	if r.length == 0 {
+		return nil
+	}
+
+	n := r.root
+	for len(k) > 0 {
+		var found bool
+		n, found = n.children(firstRune(k))
+		if !found {
+			return nil
+		}
+
+		// k在这个节点的中间结束, 比如k是/he, 节点是/hello
+		if strings.HasPrefix(n.prefix, k) {
+			return n
+		}
+
+		if !strings.HasPrefix(k, n.prefix) {
+			return nil
+		}
+		k = k[len(n.prefix):]
+	}
+
+	return n
 }
 
 func (n *node[V]) insertChildren(r rune, new edge[V]) {
@@ -178,6 +222,15 @@ func (n *node[V]) setChildren(r rune, new *node[V]) {
 	n.edges.GetPtr(index).node = new
 }
 
+func (n *node[V]) deleteChildren(r rune) {
+	index, found := n.find(r)
+	if !found {
+		return
+	}
+
+	n.edges.Remove(index)
+}
+
 func (n *node[V]) children(r rune) (v *node[V], found bool) {
 	index, found := n.find(r)
 	if !found {
@@ -196,8 +249,21 @@ func (n *node[V]) find(r rune) (index int, found bool) {
 	return index, false
 }
 
+// 节点只有一个孩子并且自己没有值时, 把孩子合并到自己身上
+// 比如/hello -> axx 合并成/helloaxx
+func (n *node[V]) mergeChild() {
+	child := n.edges.Get(0).node
+	n.prefix += child.prefix
+	n.pair = child.pair
+	n.edges = child.edges
+}
+
 // 获取返回bool
 func (r *Radix[V]) GetWithBool(k string) (v V, found bool) {
+	if r.root == nil {
+		return
+	}
+
 	n := r.root
 
 	for {
@@ -207,11 +273,11 @@ func (r *Radix[V]) GetWithBool(k string) (v V, found bool) {
 			if n.isSet {
 				return n.val, true
 			}
-			return
+			// 分裂出来的中间节点没有值
+			return v, false
 		}
 
-		rune, _ := utf8.DecodeLastRuneInString(k)
-		n, found = n.children(rune)
+		n, found = n.children(firstRune(k))
 		if !found {
 			return
 		}
@@ -220,17 +286,214 @@ func (r *Radix[V]) GetWithBool(k string) (v V, found bool) {
 			k = k[len(n.prefix):]
 			continue
 		}
-		return
+		return v, false
 	}
 
 }
 
-// 删除
+// 删除, 删除之后会把只有一个孩子的节点和孩子合并
 func (r *Radix[V]) Delete(k string) {
+	if r.root == nil {
+		return
+	}
 
+	var parent *node[V]
+	var label rune
+	var found bool
+	n := r.root
+	for len(k) > 0 {
+		label = firstRune(k)
+		parent = n
+		n, found = n.children(label)
+		if !found || !strings.HasPrefix(k, n.prefix) {
+			return
+		}
+		k = k[len(n.prefix):]
+	}
+
+	if !n.isSet {
+		return
+	}
+
+	n.pair = pair[V]{}
+	r.length--
+
+	// 根节点不合并, 它的prefix必须是空串
+	if parent == nil {
+		return
+	}
+
+	// 叶子节点直接从父节点删除
+	if n.edges.Len() == 0 {
+		parent.deleteChildren(label)
+	}
+
+	if n.edges.Len() == 1 {
+		n.mergeChild()
+	}
+
+	// 父节点删除一个孩子之后, 可能也只剩一个孩子了
+	if parent != r.root && !parent.isSet && parent.edges.Len() == 1 {
+		parent.mergeChild()
+	}
 }
 
 // 返回长度
 func (r *Radix[V]) Len() int {
 	return r.length
 }
+
+// 遍历, 按key从小到大的顺序, callback 返回false就停止遍历
+// key里面不是合法utf8的字节排在所有合法字符的后面
+func (r *Radix[V]) Range(callback func(k string, v V) bool) {
+	if r.root == nil {
+		return
+	}
+
+	r.root.rangeInner(callback)
+}
+
+// 按key从大到小遍历, callback 返回false就停止遍历
+func (r *Radix[V]) RangePrev(callback func(k string, v V) bool) {
+	if r.root == nil {
+		return
+	}
+
+	r.root.rangePrevInner(callback)
+}
+
+// 遍历所有以prefix为前缀的key, 按key从小到大的顺序, callback 返回false就停止遍历
+func (r *Radix[V]) WalkPrefix(prefix string, callback func(k string, v V) bool) {
+	n := r.prefixNode(prefix)
+	if n == nil {
+		return
+	}
+
+	n.rangeInner(callback)
+}
+
+// 返回k的最长前缀, 这个前缀是树里面的一个key
+// 比如树里面有/a, /a/b, LongestPrefix("/a/b/c")返回/a/b
+func (r *Radix[V]) LongestPrefix(k string) (key string, v V, ok bool) {
+	r.PrefixesOf(k, func(k string, val V) bool {
+		key, v, ok = k, val, true
+		return true
+	})
+	return
+}
+
+// 和LongestPrefix一样, 实现api.Trie
+func (r *Radix[V]) LongestPrefixOf(s string) (key string, v V, ok bool) {
+	return r.LongestPrefix(s)
+}
+
+// 从短到长遍历所有是s前缀的key, callback返回false就停止遍历
+func (r *Radix[V]) PrefixesOf(s string, callback func(k string, v V) bool) {
+	if r.root == nil {
+		return
+	}
+
+	var found bool
+	n := r.root
+	for {
+		if n.isSet && !callback(n.key, n.val) {
+			return
+		}
+
+		if len(s) == 0 {
+			return
+		}
+
+		n, found = n.children(firstRune(s))
+		if !found || !strings.HasPrefix(s, n.prefix) {
+			return
+		}
+		s = s[len(n.prefix):]
+	}
+}
+
+// 按key从小到大返回所有匹配pattern的key, 和redis的KEYS命令一样支持?, *, [abc], [a-z], [^a-z]和\转义
+// 从根节点往下走的时候同时匹配模式, 不可能匹配的子树直接跳过, callback 返回false就停止遍历
+func (r *Radix[V]) Match(pattern string, callback func(k string, v V) bool) {
+	if r.root == nil {
+		return
+	}
+
+	p := glob.Compile(pattern)
+	r.root.match(p, p.Start(), callback)
+}
+
+func (n *node[V]) match(p *glob.Pattern, s glob.State, callback func(k string, v V) bool) bool {
+	if n.isSet && p.Accept(s) && !callback(n.key, n.val) {
+		return false
+	}
+
+	for i, l := 0, n.edges.Len(); i < l; i++ {
+		child := n.edges.Get(i).node
+		next := p.StepString(s, child.prefix, p.NewState())
+		if next.Empty() {
+			continue
+		}
+
+		if !child.match(p, next, callback) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *node[V]) rangeInner(callback func(k string, v V) bool) bool {
+	if n.isSet && !callback(n.key, n.val) {
+		return false
+	}
+
+	for i, l := 0, n.edges.Len(); i < l; i++ {
+		if !n.edges.Get(i).node.rangeInner(callback) {
+			return false
+		}
+	}
+	return true
+}
+
+// 先遍历子节点(从大到小), 再回调自己, 因为自己的key是所有子节点key的前缀
+func (n *node[V]) rangePrevInner(callback func(k string, v V) bool) bool {
+	for i := n.edges.Len() - 1; i >= 0; i-- {
+		if !n.edges.Get(i).node.rangePrevInner(callback) {
+			return false
+		}
+	}
+
+	return !n.isSet || callback(n.key, n.val)
+}
+
+// 返回key和value的迭代器, 可以配合for range使用
+func (r *Radix[V]) All() iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		r.Range(yield)
+	}
+}
+
+// 返回key的迭代器
+func (r *Radix[V]) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		r.Range(func(k string, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+// 返回value的迭代器
+func (r *Radix[V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		r.Range(func(_ string, v V) bool {
+			return yield(v)
+		})
+	}
+}
+
+// 从大到小返回key和value的迭代器
+func (r *Radix[V]) Backward() iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		r.RangePrev(yield)
+	}
+}
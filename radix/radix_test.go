@@ -0,0 +1,592 @@
+package radix
+
+// apache 2.0 antlabs
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/antlabs/gstl/api"
+	"github.com/antlabs/gstl/internal/glob"
+	"github.com/antlabs/gstl/trie"
+	"github.com/stretchr/testify/assert"
+)
+
+// 检查radix的结构
+// 1. 除了根节点, 没有值的节点至少有两个孩子(否则应该被合并)
+// 2. 边的label是孩子prefix的第一个字符, 并且从小到大排序
+func checkRadix[V any](t *testing.T, r *Radix[V]) {
+	var check func(n *node[V], isRoot bool) int
+	check = func(n *node[V], isRoot bool) (count int) {
+		if !isRoot && !n.isSet {
+			assert.GreaterOrEqual(t, n.edges.Len(), 2, "prefix:%s", n.prefix)
+		}
+
+		if n.isSet {
+			count++
+		}
+
+		for i, l := 0, n.edges.Len(); i < l; i++ {
+			e := n.edges.Get(i)
+			assert.Equal(t, e.label, firstRune(e.node.prefix))
+			if i > 0 {
+				assert.Less(t, n.edges.Get(i-1).label, e.label)
+			}
+			count += check(e.node, false)
+		}
+		return
+	}
+
+	if r.root == nil {
+		assert.Equal(t, r.Len(), 0)
+		return
+	}
+	assert.Equal(t, check(r.root, true), r.Len())
+}
+
+// set get 预期是设置进去， 也能读出来
+func Test_Radix_SetGet(t *testing.T) {
+	r := New[string]()
+	max := 1000
+
+	for i := 0; i < max; i++ {
+		key := fmt.Sprint(i)
+		r.Set(key, key)
+		assert.Equal(t, r.Get(key), key)
+	}
+
+	for i := 0; i < max; i++ {
+		key := fmt.Sprint(i)
+		v, ok := r.GetWithBool(key)
+		assert.True(t, ok)
+		assert.Equal(t, v, key)
+	}
+
+	assert.Equal(t, r.Len(), max)
+	checkRadix(t, r)
+}
+
+func Test_Radix_SetWithPrev(t *testing.T) {
+	r := New[int]()
+	_, replaced := r.SetWithPrev("/hello", 1)
+	assert.False(t, replaced)
+	_, replaced = r.SetWithPrev("", 2)
+	assert.False(t, replaced)
+
+	prev, replaced := r.SetWithPrev("/hello", 3)
+	assert.True(t, replaced)
+	assert.Equal(t, prev, 1)
+
+	prev, replaced = r.SetWithPrev("", 4)
+	assert.True(t, replaced)
+	assert.Equal(t, prev, 2)
+
+	assert.Equal(t, r.Len(), 2)
+	assert.Equal(t, r.Get(""), 4)
+	assert.Equal(t, r.Get("/hello"), 3)
+}
+
+// 节点分裂
+func Test_Radix_Split(t *testing.T) {
+	r := New[string]()
+	keys := []string{"/helloaxx", "/hellobxx", "/hello", "/he", "/h", "/hellob"}
+	for _, k := range keys {
+		r.Set(k, k)
+		checkRadix(t, r)
+	}
+
+	for _, k := range keys {
+		assert.Equal(t, r.Get(k), k)
+	}
+
+	for _, k := range []string{"/hel", "/hellob/", "/helloa", "/", "/hx"} {
+		_, ok := r.GetWithBool(k)
+		assert.False(t, ok, k)
+	}
+}
+
+// 多字节字符不会被从中间切开
+func Test_Radix_Unicode(t *testing.T) {
+	r := New[int]()
+	keys := []string{"中国", "中文", "中", "日本", "日文", "a中", "a丰"}
+	for i, k := range keys {
+		r.Set(k, i)
+	}
+	checkRadix(t, r)
+
+	for i, k := range keys {
+		assert.Equal(t, r.Get(k), i)
+	}
+
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	var got []string
+	for k := range r.Keys() {
+		got = append(got, k)
+	}
+	assert.Equal(t, got, sorted)
+}
+
+// key不是合法的utf8, 每个非法字节各自作为一个字符, 不会panic
+func Test_Radix_InvalidUTF8(t *testing.T) {
+	r := New[int]()
+	keys := []string{"\xff", "\xfe", "\xffa", "\xff\xfe", "\xe4\xb8", "\xe4\xb8a", "中", "中\xff",
+		"\ufffd", "a\x80\x81", "a\x80\x82", "a", "\x80"}
+	for i, k := range keys {
+		r.Set(k, i)
+	}
+	checkRadix(t, r)
+	assert.Equal(t, r.Len(), len(keys))
+
+	for i, k := range keys {
+		assert.Equal(t, r.Get(k), i, "key:%q", k)
+		assert.True(t, r.HasPrefix(k), "key:%q", k)
+	}
+	_, ok := r.GetWithBool("\xe4")
+	assert.False(t, ok)
+
+	got := make(map[string]int)
+	r.Range(func(k string, v int) bool {
+		got[k] = v
+		return true
+	})
+	assert.Equal(t, len(got), len(keys))
+
+	for i, k := range keys {
+		r.Delete(k)
+		checkRadix(t, r)
+		assert.Equal(t, r.Len(), len(keys)-i-1)
+		for _, k2 := range keys[i+1:] {
+			_, ok := r.GetWithBool(k2)
+			assert.True(t, ok, "key:%q", k2)
+		}
+	}
+}
+
+// 随机的字节串, 和map的结果一样
+func Test_Radix_InvalidUTF8Random(t *testing.T) {
+	r := New[int]()
+	m := make(map[string]int)
+	alphabet := []string{"\xe4", "\xb8", "\xad", "\xff", "\x80", "a", "中"}
+
+	for i := 0; i < 5000; i++ {
+		k := ""
+		for j, n := 0, rand.Intn(5); j < n; j++ {
+			k += alphabet[rand.Intn(len(alphabet))]
+		}
+		if rand.Intn(3) == 0 {
+			r.Delete(k)
+			delete(m, k)
+		} else {
+			r.Set(k, i)
+			m[k] = i
+		}
+	}
+	checkRadix(t, r)
+	assert.Equal(t, r.Len(), len(m))
+
+	for k, v := range m {
+		assert.Equal(t, r.Get(k), v, "key:%q", k)
+	}
+}
+
+// HasPrefix 找到
+func Test_Radix_HasPrefix(t *testing.T) {
+	r := New[string]()
+	key := "/hello/world"
+	r.Set("/hello", "1")
+	r.Set("/hello/world", "1")
+	for i := 0; i <= len(key); i++ {
+		assert.True(t, r.HasPrefix(key[:i]), key[:i])
+	}
+}
+
+// HasPrefix 找不到
+func Test_Radix_HasPrefix_notFound(t *testing.T) {
+	r := New[string]()
+	assert.False(t, r.HasPrefix(""))
+
+	r.Set("/hello", "1")
+	r.Set("/hello/world", "1")
+	assert.False(t, r.HasPrefix("/ha"))
+	assert.False(t, r.HasPrefix("/hello/world/"))
+	assert.False(t, r.HasPrefix("/hello/x"))
+}
+
+func Test_Radix_GetWithBool_notFound(t *testing.T) {
+	r := New[string]()
+	r.Set("/hello", "1")
+	r.Set("/hello/world", "1")
+	_, ok := r.GetWithBool("/ha")
+	assert.False(t, ok)
+	_, ok = r.GetWithBool("/he")
+	assert.False(t, ok)
+	_, ok = r.GetWithBool("")
+	assert.False(t, ok)
+}
+
+// 分裂出来的中间节点没有值, 不能找到
+func Test_Radix_GetWithBool_splitNode(t *testing.T) {
+	r := New[string]()
+	r.Set("/user/list", "1")
+	r.Set("/user/new", "2")
+	_, ok := r.GetWithBool("/user/")
+	assert.False(t, ok)
+}
+
+func Test_Radix_Delete(t *testing.T) {
+	r := New[string]()
+	r.Set("/hello", "1")
+	r.Set("/hello/world", "2")
+	r.Set("/hello/word", "3")
+
+	r.Delete("/hello/wor")
+	r.Delete("/hello/world/")
+	r.Delete("/he")
+	assert.Equal(t, r.Len(), 3)
+
+	r.Delete("/hello/world")
+	checkRadix(t, r)
+	assert.Equal(t, r.Len(), 2)
+	_, ok := r.GetWithBool("/hello/world")
+	assert.False(t, ok)
+	assert.Equal(t, r.Get("/hello/word"), "3")
+
+	r.Delete("/hello")
+	checkRadix(t, r)
+	assert.Equal(t, r.Len(), 1)
+	assert.False(t, r.HasPrefix("/hello/worl"))
+	assert.Equal(t, r.Get("/hello/word"), "3")
+
+	r.Delete("/hello/word")
+	checkRadix(t, r)
+	assert.Equal(t, r.Len(), 0)
+	assert.False(t, r.HasPrefix("/"))
+}
+
+// 删除之后, 只有一个孩子的节点会和孩子合并
+func Test_Radix_DeleteMerge(t *testing.T) {
+	r := New[int]()
+	r.Set("/helloaxx", 1)
+	r.Set("/hellobxx", 2)
+	r.Delete("/hellobxx")
+
+	assert.Equal(t, r.root.edges.Len(), 1)
+	n := r.root.edges.Get(0).node
+	assert.Equal(t, n.prefix, "/helloaxx")
+	assert.Equal(t, n.edges.Len(), 0)
+
+	r.Set("/hello", 3)
+	r.Set("/hellob", 4)
+	r.Delete("/hello")
+	checkRadix(t, r)
+	assert.Equal(t, r.Get("/helloaxx"), 1)
+	assert.Equal(t, r.Get("/hellob"), 4)
+}
+
+// 和map对比, 随机插入删除
+func Test_Radix_DeleteRandom(t *testing.T) {
+	r := New[int]()
+	m := make(map[string]int)
+	words := []string{"a", "ab", "abc", "b", "ba", "中", "中文", "中国", "/user", "/user/name", "/usr"}
+
+	for i := 0; i < 5000; i++ {
+		k := words[rand.Intn(len(words))] + words[rand.Intn(len(words))]
+		if rand.Intn(3) == 0 {
+			r.Delete(k)
+			delete(m, k)
+		} else {
+			r.Set(k, i)
+			m[k] = i
+		}
+	}
+	checkRadix(t, r)
+	assert.Equal(t, r.Len(), len(m))
+
+	for k, v := range m {
+		assert.Equal(t, r.Get(k), v)
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var got []string
+	r.Range(func(k string, v int) bool {
+		assert.Equal(t, v, m[k])
+		got = append(got, k)
+		return true
+	})
+	assert.Equal(t, got, keys)
+
+	for _, k := range keys {
+		r.Delete(k)
+	}
+	checkRadix(t, r)
+	assert.Equal(t, r.Len(), 0)
+}
+
+func Test_Radix_WalkPrefix(t *testing.T) {
+	r := New[int]()
+	for i, k := range []string{"/user", "/user/name", "/user/age", "/usr", "/a", "/user/name/first"} {
+		r.Set(k, i)
+	}
+
+	for _, tc := range []struct {
+		prefix string
+		need   []string
+	}{
+		{"/user", []string{"/user", "/user/age", "/user/name", "/user/name/first"}},
+		{"/user/", []string{"/user/age", "/user/name", "/user/name/first"}},
+		{"/us", []string{"/user", "/user/age", "/user/name", "/user/name/first", "/usr"}},
+		{"/user/name/", []string{"/user/name/first"}},
+		{"/b", nil},
+		{"/user/namex", nil},
+		{"", []string{"/a", "/user", "/user/age", "/user/name", "/user/name/first", "/usr"}},
+	} {
+		var got []string
+		r.WalkPrefix(tc.prefix, func(k string, v int) bool {
+			got = append(got, k)
+			return true
+		})
+		assert.Equal(t, got, tc.need, tc.prefix)
+	}
+
+	// callback 返回false就停止遍历
+	var got []string
+	r.WalkPrefix("/user", func(k string, v int) bool {
+		got = append(got, k)
+		return len(got) < 2
+	})
+	assert.Equal(t, got, []string{"/user", "/user/age"})
+}
+
+func Test_Radix_LongestPrefix(t *testing.T) {
+	r := New[int]()
+	_, _, ok := r.LongestPrefix("/a")
+	assert.False(t, ok)
+
+	r.Set("/a", 1)
+	r.Set("/a/b", 2)
+	r.Set("/a/b/cd", 3)
+
+	for _, tc := range []struct {
+		k    string
+		need string
+		ok   bool
+	}{
+		{"/a/b/c", "/a/b", true},
+		{"/a/b/cd/e", "/a/b/cd", true},
+		{"/a/b/cd", "/a/b/cd", true},
+		{"/a/", "/a", true},
+		{"/ab", "/a", true},
+		{"/", "", false},
+		{"", "", false},
+	} {
+		k, _, ok := r.LongestPrefix(tc.k)
+		assert.Equal(t, ok, tc.ok, tc.k)
+		assert.Equal(t, k, tc.need, tc.k)
+	}
+
+	r.Set("", 0)
+	k, v, ok := r.LongestPrefix("/b")
+	assert.True(t, ok)
+	assert.Equal(t, k, "")
+	assert.Equal(t, v, 0)
+}
+
+// 零值也可以直接使用
+func Test_Radix_ZeroValue(t *testing.T) {
+	var r Radix[int]
+	_, ok := r.GetWithBool("a")
+	assert.False(t, ok)
+	assert.False(t, r.HasPrefix("a"))
+	r.Delete("a")
+
+	r.Set("a", 1)
+	assert.Equal(t, r.Get("a"), 1)
+	assert.Equal(t, r.Len(), 1)
+}
+
+func Test_Radix_Iter(t *testing.T) {
+	r := New[int]()
+	keys := []string{"a", "ab", "b", "ba"}
+	for i, k := range keys {
+		r.Set(k, i)
+	}
+
+	var gotKeys []string
+	var gotValues []int
+	for k, v := range r.All() {
+		gotKeys = append(gotKeys, k)
+		gotValues = append(gotValues, v)
+	}
+	assert.Equal(t, gotKeys, keys)
+	assert.Equal(t, gotValues, []int{0, 1, 2, 3})
+
+	gotValues = gotValues[:0]
+	for v := range r.Values() {
+		gotValues = append(gotValues, v)
+		if len(gotValues) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, gotValues, []int{0, 1})
+
+	gotKeys = gotKeys[:0]
+	for k := range r.Backward() {
+		gotKeys = append(gotKeys, k)
+	}
+	assert.Equal(t, gotKeys, []string{"ba", "b", "ab", "a"})
+
+	gotKeys = gotKeys[:0]
+	for k := range r.Backward() {
+		if k == "ab" {
+			break
+		}
+		gotKeys = append(gotKeys, k)
+	}
+	assert.Equal(t, gotKeys, []string{"ba", "b"})
+}
+
+// Backward的结果是All的倒序
+func Test_Radix_Backward(t *testing.T) {
+	r := New[int]()
+	for i := 0; i < 1000; i++ {
+		r.Set(fmt.Sprintf("/%d/%d", i%37, i), i)
+	}
+	r.Set("", -1)
+
+	var forward, backward []string
+	for k := range r.All() {
+		forward = append(forward, k)
+	}
+	for k, v := range r.Backward() {
+		assert.Equal(t, r.Get(k), v)
+		backward = append(backward, k)
+	}
+	assert.True(t, sort.StringsAreSorted(forward))
+	assert.Equal(t, len(backward), len(forward))
+	for i := range forward {
+		assert.Equal(t, backward[len(backward)-1-i], forward[i])
+	}
+
+	for range New[int]().Backward() {
+		t.Fatal("empty radix should not yield")
+	}
+}
+
+func Test_Radix_PrefixesOf(t *testing.T) {
+	var tr api.Trie[int] = New[int]()
+	testPrefixesOf(t, tr)
+}
+
+// 和对每个前缀调用GetWithBool的结果一样
+func testPrefixesOf(t *testing.T, tr api.Trie[int]) {
+	keys := []string{"", "/a", "/a/b", "/a/b/cd", "中", "中文", "中文字"}
+	for i, k := range keys {
+		tr.SetWithPrev(k, i)
+	}
+
+	for _, s := range []string{"", "/", "/a", "/a/b/c", "/a/b/cd/e", "/ab", "中文", "中国", "x"} {
+		var need []string
+		for i := 0; i <= len(s); i++ {
+			if _, ok := tr.GetWithBool(s[:i]); ok {
+				need = append(need, s[:i])
+			}
+		}
+
+		var got []string
+		tr.PrefixesOf(s, func(k string, v int) bool {
+			assert.Equal(t, tr.Get(k), v)
+			got = append(got, k)
+			return true
+		})
+		assert.Equal(t, got, need, s)
+
+		k, v, ok := tr.LongestPrefixOf(s)
+		assert.True(t, ok)
+		assert.Equal(t, k, need[len(need)-1], s)
+		assert.Equal(t, v, tr.Get(k), s)
+	}
+
+	// 提前停止
+	n := 0
+	tr.PrefixesOf("/a/b/cd", func(string, int) bool {
+		n++
+		return n < 2
+	})
+	assert.Equal(t, n, 2)
+
+	tr.Delete("")
+	_, _, ok := tr.LongestPrefixOf("x")
+	assert.False(t, ok)
+}
+
+func Test_Radix_Match(t *testing.T) {
+	r := New[int]()
+	testMatch(t, r, r.Match)
+}
+
+// key里面有不合法的utf8字节时, 同一个模式在radix和trie里面匹配到的key一样
+// 不合法的字节各自是一个字符, 不会和U+FFFD或者其它不合法的字节混在一起
+func Test_Radix_MatchInvalidUTF8(t *testing.T) {
+	keys := []string{"a", "ab", "a\xff", "a\xffb", "a\xfe", "a\ufffd", "\xff", "\xffa", "\xff\xfe"}
+	r, tr := New[int](), trie.New[int]()
+	for i, k := range keys {
+		r.Set(k, i)
+		tr.Set(k, i)
+	}
+
+	for _, pattern := range []string{"*", "a?", "a?b", "a*", "?", "??", "?a", "[^a]*", "a[^b]", "a[^\ufffd]", "a\ufffd"} {
+		var need, got []string
+		tr.Match(pattern, func(k string, _ int) bool {
+			need = append(need, k)
+			return true
+		})
+		r.Match(pattern, func(k string, _ int) bool {
+			got = append(got, k)
+			return true
+		})
+		assert.Equal(t, got, need, pattern)
+	}
+}
+
+// 和对每个key调用glob匹配的结果一样, 并且按字典序返回
+func testMatch(t *testing.T, tr api.Trie[int], match func(pattern string, callback func(k string, v int) bool)) {
+	keys := []string{"", "user:1:session", "user:2:session", "user:12:session", "user:1:profile",
+		"user:abc:session", "users", "hello", "hallo", "hxllo", "中文", "中国", "a*b"}
+	for i, k := range keys {
+		tr.SetWithPrev(k, i)
+	}
+
+	for _, pattern := range []string{"*", "", "user:*:session", "user:?:*", "user:[0-9]*:session",
+		"h[ae]llo", "h[^e]llo", "中?", "users*", "a\\*b", "x*", "*o"} {
+		var need []string
+		g := glob.Compile(pattern)
+		for _, k := range keys {
+			if g.Match(k) {
+				need = append(need, k)
+			}
+		}
+		sort.Strings(need)
+
+		var got []string
+		match(pattern, func(k string, v int) bool {
+			assert.Equal(t, tr.Get(k), v)
+			got = append(got, k)
+			return true
+		})
+		assert.Equal(t, got, need, pattern)
+	}
+
+	n := 0
+	match("user:*", func(string, int) bool {
+		n++
+		return false
+	})
+	assert.Equal(t, n, 1)
+}
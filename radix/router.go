@@ -0,0 +1,378 @@
+package radix
+
+// apache 2.0 antlabs
+// 基于radix tree的http路由
+// 支持三种节点
+// 1. 静态路径, 比如/user/list
+// 2. 参数, 比如/user/:id, 匹配一段不包含/的非空路径
+// 3. 通配, 比如/static/*filepath, 匹配剩下的所有路径, 只能放在最后
+// 匹配的优先级是 静态路径 > 参数 > 通配, 高优先级的分支匹配失败时会回退到低优先级的分支
+//
+// 路由按参数切成几段, 每一段的静态路径保存在Radix里面, 比如/repo/:owner/:name/issues
+// 根节点的Radix保存/repo/, 它的参数节点owner的Radix保存/, name的Radix保存/issues
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidPath   = errors.New("radix: invalid path")
+	ErrRouteConflict = errors.New("radix: route conflict")
+)
+
+// 路径里面的一个参数
+type Param struct {
+	Key   string
+	Value string
+}
+
+// 匹配到的参数, 按在路径里面出现的顺序保存
+type Params []Param
+
+// 根据参数名返回参数值
+func (ps Params) Get(name string) (value string, ok bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return
+}
+
+// 根据参数名返回参数值, 没有返回空串
+func (ps Params) ByName(name string) string {
+	v, _ := ps.Get(name)
+	return v
+}
+
+// 路由树的节点, 根节点, 参数节点, 通配节点, 或者静态路径结束的节点
+type pathNode[V any] struct {
+	// 从这个节点开始的静态路径 -> 静态路径结束的节点
+	static Radix[*pathNode[V]]
+	// 参数孩子节点, 最多一个
+	param *pathNode[V]
+	// 通配孩子节点, 最多一个
+	catchAll *pathNode[V]
+	// 参数或者通配的名字
+	name  string
+	val   V
+	isSet bool
+	// 注册时的路径, 报错的时候使用
+	path string
+}
+
+// 路由树, 保存path -> V
+type PathTree[V any] struct {
+	root      pathNode[V]
+	length    int
+	maxParams int
+}
+
+// 初始化路由树
+func NewPathTree[V any]() *PathTree[V] {
+	return &PathTree[V]{}
+}
+
+// 返回路由的个数
+func (t *PathTree[V]) Len() int {
+	return t.length
+}
+
+// 返回所有路由里面最多的参数个数, 调用Lookup前可以用它预先分配Params
+func (t *PathTree[V]) MaxParams() int {
+	return t.maxParams
+}
+
+// 路由里面的一段, 静态路径后面跟着一个参数或者通配
+type segment struct {
+	static string
+	// ':'是参数, '*'是通配, 0表示只有静态路径
+	kind byte
+	name string
+}
+
+// 把路由切成几段, 检查路由是否合法
+func parsePath(path string) (segs []segment, params int, err error) {
+	if len(path) == 0 || path[0] != '/' {
+		return nil, 0, fmt.Errorf("%w: %q must begin with '/'", ErrInvalidPath, path)
+	}
+
+	for s := path; len(s) > 0; {
+		i := strings.IndexAny(s, ":*")
+		if i < 0 {
+			segs = append(segs, segment{static: s})
+			break
+		}
+
+		if i == 0 || s[i-1] != '/' {
+			return nil, 0, fmt.Errorf("%w: %q, parameter must follow '/'", ErrInvalidPath, path)
+		}
+
+		seg := segment{static: s[:i], kind: s[i]}
+		s = s[i+1:]
+
+		end := strings.IndexByte(s, '/')
+		if end < 0 {
+			end = len(s)
+		}
+		seg.name = s[:end]
+		if len(seg.name) == 0 || strings.ContainsAny(seg.name, ":*") {
+			return nil, 0, fmt.Errorf("%w: %q, bad parameter name", ErrInvalidPath, path)
+		}
+		s = s[end:]
+
+		if seg.kind == '*' && len(s) > 0 {
+			return nil, 0, fmt.Errorf("%w: %q, catch-all must be at the end", ErrInvalidPath, path)
+		}
+		segs = append(segs, seg)
+		params++
+	}
+	return segs, params, nil
+}
+
+// 按segs找到路由结束的节点
+// create为false时只检查冲突, 不修改路由树, 遇到不存在的节点说明后面都是新建的, 不会冲突
+// create为true时创建不存在的节点, 需要先用create为false检查过
+func (t *PathTree[V]) walk(path string, segs []segment, create bool) (*pathNode[V], error) {
+	n := &t.root
+	for _, seg := range segs {
+		if len(seg.static) > 0 {
+			next, ok := n.static.GetWithBool(seg.static)
+			if !ok {
+				if !create {
+					return nil, nil
+				}
+				next = &pathNode[V]{}
+				n.static.Set(seg.static, next)
+			}
+			n = next
+		}
+
+		var child **pathNode[V]
+		switch seg.kind {
+		case ':':
+			child = &n.param
+		case '*':
+			child = &n.catchAll
+		default:
+			continue
+		}
+
+		if *child == nil {
+			if !create {
+				return nil, nil
+			}
+			*child = &pathNode[V]{name: seg.name, path: path}
+		} else if (*child).name != seg.name {
+			return nil, fmt.Errorf("%w: %q conflicts with %q", ErrRouteConflict, path, (*child).path)
+		}
+		n = *child
+	}
+
+	if n.isSet {
+		return nil, fmt.Errorf("%w: %q already registered", ErrRouteConflict, path)
+	}
+	return n, nil
+}
+
+// 添加路由, path必须以/开头
+// 参数和通配的名字冲突, 或者路由重复时返回ErrRouteConflict
+// 返回错误时路由树不会被修改
+func (t *PathTree[V]) Add(path string, v V) error {
+	segs, params, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err = t.walk(path, segs, false); err != nil {
+		return err
+	}
+
+	n, _ := t.walk(path, segs, true)
+	n.val, n.isSet, n.path = v, true, path
+	t.length++
+	if params > t.maxParams {
+		t.maxParams = params
+	}
+	return nil
+}
+
+// 查找path对应的值, 匹配到的参数追加到params里面
+// 参数值直接引用path的内容, params的容量足够(MaxParams)时查找过程不会分配内存
+func (t *PathTree[V]) Lookup(path string, params *Params) (v V, ok bool) {
+	n := t.root.lookup(path, params)
+	if n == nil {
+		return
+	}
+	return n.val, true
+}
+
+// 按 静态路径 > 参数 > 通配 的顺序匹配, 失败时回退
+func (n *pathNode[V]) lookup(path string, params *Params) *pathNode[V] {
+	if n.static.root != nil {
+		if found := lookupStatic(n.static.root, path, params); found != nil {
+			return found
+		}
+	}
+
+	if len(path) == 0 {
+		if n.isSet {
+			return n
+		}
+
+		// /static/*filepath 可以匹配 /static/
+		if n.catchAll != nil && n.catchAll.isSet {
+			n.catchAll.appendParam(path, params)
+			return n.catchAll
+		}
+		return nil
+	}
+
+	if n.param != nil {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+
+		if end > 0 {
+			n.param.appendParam(path[:end], params)
+			if found := n.param.lookup(path[end:], params); found != nil {
+				return found
+			}
+			// 回退, 删除刚才追加的参数
+			if params != nil {
+				*params = (*params)[:len(*params)-1]
+			}
+		}
+	}
+
+	if n.catchAll != nil {
+		n.catchAll.appendParam(path, params)
+		return n.catchAll
+	}
+	return nil
+}
+
+// 在静态路径的radix里面匹配, 先走最长的静态路径, 失败之后从长到短回退到前缀结束的节点
+func lookupStatic[V any](rn *node[*pathNode[V]], path string, params *Params) *pathNode[V] {
+	if len(path) > 0 {
+		if child, found := rn.children(firstRune(path)); found && strings.HasPrefix(path, child.prefix) {
+			if found := lookupStatic(child, path[len(child.prefix):], params); found != nil {
+				return found
+			}
+		}
+	}
+
+	if rn.isSet {
+		return rn.val.lookup(path, params)
+	}
+	return nil
+}
+
+func (n *pathNode[V]) appendParam(value string, params *Params) {
+	if params != nil {
+		*params = append(*params, Param{Key: n.name, Value: value})
+	}
+}
+
+// 路由的处理函数, ps是匹配到的参数, 只在处理函数里面有效
+type Handle func(w http.ResponseWriter, r *http.Request, ps Params)
+
+// http路由, 每个method一棵路由树
+type Router struct {
+	trees map[string]*PathTree[Handle]
+	// 没有匹配到路由时调用, 为nil时使用http.NotFound
+	NotFound http.Handler
+
+	maxParams int
+	pool      sync.Pool
+}
+
+// 初始化路由
+func NewRouter() *Router {
+	r := &Router{trees: make(map[string]*PathTree[Handle])}
+	r.pool.New = func() any {
+		ps := make(Params, 0, r.maxParams)
+		return &ps
+	}
+	return r
+}
+
+// 注册路由, path冲突或者不合法时panic, 和http.ServeMux一样
+func (r *Router) Handle(method, path string, handle Handle) {
+	t := r.trees[method]
+	if t == nil {
+		t = NewPathTree[Handle]()
+		r.trees[method] = t
+	}
+
+	if err := t.Add(path, handle); err != nil {
+		panic(err)
+	}
+
+	if t.MaxParams() > r.maxParams {
+		r.maxParams = t.MaxParams()
+	}
+}
+
+// 注册http.Handler, 匹配到的参数会被忽略
+func (r *Router) Handler(method, path string, handler http.Handler) {
+	r.Handle(method, path, func(w http.ResponseWriter, req *http.Request, _ Params) {
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// 注册GET请求
+func (r *Router) GET(path string, handle Handle) {
+	r.Handle(http.MethodGet, path, handle)
+}
+
+// 注册POST请求
+func (r *Router) POST(path string, handle Handle) {
+	r.Handle(http.MethodPost, path, handle)
+}
+
+// 注册PUT请求
+func (r *Router) PUT(path string, handle Handle) {
+	r.Handle(http.MethodPut, path, handle)
+}
+
+// 注册DELETE请求
+func (r *Router) DELETE(path string, handle Handle) {
+	r.Handle(http.MethodDelete, path, handle)
+}
+
+// 查找路由, 返回的params是新分配的, 可以在处理函数外面使用
+func (r *Router) Lookup(method, path string) (handle Handle, ps Params, ok bool) {
+	t := r.trees[method]
+	if t == nil {
+		return
+	}
+
+	handle, ok = t.Lookup(path, &ps)
+	return
+}
+
+// 实现http.Handler接口
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if t := r.trees[req.Method]; t != nil {
+		ps := r.pool.Get().(*Params)
+		if handle, ok := t.Lookup(req.URL.Path, ps); ok {
+			handle(w, req, *ps)
+			*ps = (*ps)[:0]
+			r.pool.Put(ps)
+			return
+		}
+		*ps = (*ps)[:0]
+		r.pool.Put(ps)
+	}
+
+	if r.NotFound != nil {
+		r.NotFound.ServeHTTP(w, req)
+		return
+	}
+	http.NotFound(w, req)
+}
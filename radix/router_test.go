@@ -0,0 +1,227 @@
+package radix
+
+// apache 2.0 antlabs
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PathTree_Lookup(t *testing.T) {
+	tree := NewPathTree[string]()
+	routes := []string{
+		"/",
+		"/user/list",
+		"/user/new",
+		"/user/:id",
+		"/user/:id/profile",
+		"/users",
+		"/static/*filepath",
+		"/static/index.html",
+		"/repo/:owner/:name/issues",
+		"/中文/:name",
+	}
+	for _, r := range routes {
+		assert.NoError(t, tree.Add(r, r))
+	}
+	assert.Equal(t, tree.Len(), len(routes))
+	assert.Equal(t, tree.MaxParams(), 2)
+
+	for _, tc := range []struct {
+		path   string
+		route  string
+		params Params
+	}{
+		{"/", "/", nil},
+		{"/user/list", "/user/list", nil},
+		{"/user/new", "/user/new", nil},
+		{"/user/123", "/user/:id", Params{{"id", "123"}}},
+		// 静态路径new匹配失败, 回退到参数
+		{"/user/newx", "/user/:id", Params{{"id", "newx"}}},
+		{"/user/new/profile", "/user/:id/profile", Params{{"id", "new"}}},
+		{"/users", "/users", nil},
+		{"/static/index.html", "/static/index.html", nil},
+		{"/static/css/main.css", "/static/*filepath", Params{{"filepath", "css/main.css"}}},
+		{"/static/", "/static/*filepath", Params{{"filepath", ""}}},
+		{"/repo/antlabs/gstl/issues", "/repo/:owner/:name/issues", Params{{"owner", "antlabs"}, {"name", "gstl"}}},
+		{"/中文/名字", "/中文/:name", Params{{"name", "名字"}}},
+	} {
+		var ps Params
+		v, ok := tree.Lookup(tc.path, &ps)
+		assert.True(t, ok, tc.path)
+		assert.Equal(t, v, tc.route, tc.path)
+		assert.Equal(t, ps, tc.params, tc.path)
+	}
+
+	for _, path := range []string{"", "/user", "/user/", "/user/1/profile/x", "/repo/a/b", "/static", "/x"} {
+		var ps Params
+		_, ok := tree.Lookup(path, &ps)
+		assert.False(t, ok, path)
+		assert.Equal(t, len(ps), 0, path)
+	}
+}
+
+func Test_PathTree_AddError(t *testing.T) {
+	tree := NewPathTree[int]()
+	assert.NoError(t, tree.Add("/user/:id", 1))
+	assert.NoError(t, tree.Add("/static/*filepath", 1))
+
+	for _, tc := range []struct {
+		path string
+		err  error
+	}{
+		{"user", ErrInvalidPath},
+		{"", ErrInvalidPath},
+		{"/user:id", ErrInvalidPath},
+		{"/user/:", ErrInvalidPath},
+		{"/file/*", ErrInvalidPath},
+		{"/file/*name/x", ErrInvalidPath},
+		{"/user/:id", ErrRouteConflict},
+		{"/user/:name", ErrRouteConflict},
+		{"/static/*name", ErrRouteConflict},
+	} {
+		err := tree.Add(tc.path, 2)
+		assert.True(t, errors.Is(err, tc.err), fmt.Sprintf("path:%q, err:%v", tc.path, err))
+	}
+	assert.Equal(t, tree.Len(), 2)
+}
+
+// Add返回错误时不会修改路由树
+func Test_PathTree_AddErrorNoChange(t *testing.T) {
+	tree := NewPathTree[int]()
+	assert.NoError(t, tree.Add("/user/:id", 1))
+	assert.NoError(t, tree.Add("/static/*filepath", 2))
+
+	for _, path := range []string{
+		"/user/:name/profile/list",
+		"/static/*name",
+		"/user/:id/files/*name/x",
+		"/blog/list/:id/:",
+		"/blog/list:id",
+		"/user/:id",
+	} {
+		assert.Error(t, tree.Add(path, 3), path)
+	}
+	assert.Equal(t, tree.Len(), 2)
+	assert.Equal(t, tree.MaxParams(), 1)
+
+	for _, tc := range []struct {
+		path   string
+		v      int
+		params Params
+	}{
+		{"/user/1", 1, Params{{"id", "1"}}},
+		{"/static/a", 2, Params{{"filepath", "a"}}},
+	} {
+		var ps Params
+		v, ok := tree.Lookup(tc.path, &ps)
+		assert.True(t, ok, tc.path)
+		assert.Equal(t, v, tc.v, tc.path)
+		assert.Equal(t, ps, tc.params, tc.path)
+	}
+
+	// 失败的Add没有留下静态节点
+	for _, path := range []string{"/user/1/profile/list", "/user/1/files/a", "/blog/list/1/2", "/blog/list"} {
+		_, ok := tree.Lookup(path, nil)
+		assert.False(t, ok, path)
+	}
+	assert.Equal(t, tree.root.static.Len(), 2)
+	assert.Nil(t, tree.root.param)
+	n, _ := tree.root.static.GetWithBool("/user/")
+	assert.Equal(t, n.param.static.Len(), 0)
+}
+
+// 查找的过程不分配内存
+func Test_PathTree_LookupZeroAlloc(t *testing.T) {
+	tree := NewPathTree[int]()
+	tree.Add("/user/:id", 1)
+	tree.Add("/user/new", 2)
+	tree.Add("/repo/:owner/:name/issues", 3)
+	tree.Add("/static/*filepath", 4)
+
+	ps := make(Params, 0, tree.MaxParams())
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, path := range []string{"/user/123", "/user/newx", "/repo/antlabs/gstl/issues", "/static/a/b", "/none"} {
+			ps = ps[:0]
+			tree.Lookup(path, &ps)
+		}
+	})
+	assert.Equal(t, allocs, 0.0)
+}
+
+func Test_Router_ServeHTTP(t *testing.T) {
+	r := NewRouter()
+	r.GET("/user/:id", func(w http.ResponseWriter, req *http.Request, ps Params) {
+		fmt.Fprintf(w, "get user %s", ps.ByName("id"))
+	})
+	r.GET("/user/new", func(w http.ResponseWriter, req *http.Request, ps Params) {
+		io.WriteString(w, "new user")
+	})
+	r.POST("/user/:id", func(w http.ResponseWriter, req *http.Request, ps Params) {
+		fmt.Fprintf(w, "post user %s", ps.ByName("id"))
+	})
+	r.Handler(http.MethodGet, "/health", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	r.GET("/static/*filepath", func(w http.ResponseWriter, req *http.Request, ps Params) {
+		io.WriteString(w, ps.ByName("filepath"))
+	})
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/user/1", http.StatusOK, "get user 1"},
+		{http.MethodGet, "/user/new", http.StatusOK, "new user"},
+		{http.MethodPost, "/user/2", http.StatusOK, "post user 2"},
+		{http.MethodGet, "/health", http.StatusOK, "ok"},
+		{http.MethodGet, "/static/js/app.js", http.StatusOK, "js/app.js"},
+		{http.MethodGet, "/user", http.StatusNotFound, "404 page not found\n"},
+		{http.MethodDelete, "/user/1", http.StatusNotFound, "404 page not found\n"},
+	} {
+		req, err := http.NewRequest(tc.method, srv.URL+tc.path, nil)
+		assert.NoError(t, err)
+		rsp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		body, err := io.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, rsp.StatusCode, tc.code, tc.path)
+		assert.Equal(t, string(body), tc.body, tc.path)
+	}
+}
+
+func Test_Router_NotFoundAndLookup(t *testing.T) {
+	r := NewRouter()
+	r.DELETE("/user/:id", func(w http.ResponseWriter, req *http.Request, ps Params) {})
+	r.NotFound = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/1", nil))
+	assert.Equal(t, w.Code, http.StatusTeapot)
+
+	handle, ps, ok := r.Lookup(http.MethodDelete, "/user/1")
+	assert.True(t, ok)
+	assert.NotNil(t, handle)
+	assert.Equal(t, ps, Params{{"id", "1"}})
+
+	_, _, ok = r.Lookup(http.MethodPut, "/user/1")
+	assert.False(t, ok)
+
+	assert.Panics(t, func() {
+		r.DELETE("/user/:name", func(w http.ResponseWriter, req *http.Request, ps Params) {})
+	})
+}
@@ -0,0 +1,81 @@
+package rbtree
+
+// apache 2.0 antlabs
+import (
+	"github.com/antlabs/gstl/api"
+	"github.com/antlabs/gstl/internal/cursor"
+)
+
+var _ api.Iterator[int, int] = (*Iterator[int, int])(nil)
+
+// RBTree的迭代器, 可以暂停, 恢复, 双向移动
+// 迭代器创建之后如果RBTree被修改(新加或者删除元素), 迭代器不会失效,
+// 下次调用Next/Prev时会根据当前的key重新定位到它的后继/前驱(O(log n)), 可以看到修改后的数据.
+// 如果当前元素已经被删除, Key/Value返回的还是删除前的数据
+type Iterator[K any, V any] struct {
+	cursor.Cursor[K, V, *node[K, V]]
+}
+
+// 创建一个迭代器, 使用之前需要先调用Seek, First或者Last定位
+func (r *RBTreeFunc[K, V]) Iterator() *Iterator[K, V] {
+	return &Iterator[K, V]{Cursor: cursor.New[K, V, *node[K, V]]((*source[K, V])(r))}
+}
+
+// 给cursor使用的RBTreeFunc, 导出的方法不会出现在RBTreeFunc上
+type source[K any, V any] RBTreeFunc[K, V]
+
+func (s *source[K, V]) tree() *RBTreeFunc[K, V] {
+	return (*RBTreeFunc[K, V])(s)
+}
+
+func (s *source[K, V]) Version() int {
+	return s.version
+}
+
+func (s *source[K, V]) FirstNode() *node[K, V] {
+	n := s.root.node
+	if n != nil {
+		for n.left != nil {
+			n = n.left
+		}
+	}
+	return n
+}
+
+func (s *source[K, V]) LastNode() *node[K, V] {
+	n := s.root.node
+	if n != nil {
+		for n.right != nil {
+			n = n.right
+		}
+	}
+	return n
+}
+
+func (s *source[K, V]) CeilingNode(k K) *node[K, V] {
+	return s.tree().ceilingNode(k)
+}
+
+func (s *source[K, V]) HigherNode(k K) *node[K, V] {
+	return s.tree().higherNode(k)
+}
+
+func (s *source[K, V]) LowerNode(k K) *node[K, V] {
+	return s.tree().lowerNode(k)
+}
+
+func (s *source[K, V]) NextNode(n *node[K, V]) *node[K, V] {
+	return n.next()
+}
+
+func (s *source[K, V]) PrevNode(n *node[K, V]) *node[K, V] {
+	return n.prev()
+}
+
+func (s *source[K, V]) NodeKey(n *node[K, V]) K {
+	return n.key
+}
+
+func (s *source[K, V]) NodeValue(n *node[K, V]) V {
+	return n.val
+}
@@ -5,8 +5,10 @@ package rbtree
 // https://github.com/torvalds/linux/blob/master/lib/rbtree.c
 import (
 	"errors"
+	"iter"
 
 	"github.com/antlabs/gstl/api"
+	"github.com/antlabs/gstl/cmp"
 	"golang.org/x/exp/constraints"
 )
 
@@ -17,7 +19,10 @@ import (
 // 4. 每个红色节点的两个子节点均为黑色(红父黑子)
 // 5. 从根到叶的每个路径包含相同数量的黑色节点(黑高相同)
 
-var _ api.SortedMap[int, int] = (*RBTree[int, int])(nil)
+var (
+	_ api.SortedMap[int, int]    = (*RBTree[int, int])(nil)
+	_ api.SortedMap[[]byte, int] = (*RBTreeFunc[[]byte, int])(nil)
+)
 
 var ErrNotFound = errors.New("rbtree: not found value")
 
@@ -29,23 +34,34 @@ const (
 )
 
 // 元素
-type pair[K constraints.Ordered, V any] struct {
+type pair[K any, V any] struct {
 	val V
 	key K
 }
 
-type parentColor[K constraints.Ordered, V any] struct {
+type parentColor[K any, V any] struct {
 	parent *node[K, V]
 	color  color
+	// 以当前节点为根的子树的节点个数, 用于Select/Rank
+	// 和color放在一起, 利用对齐留下的空间, 节点大小和没有size时一样
+	size int32
 }
 
-type node[K constraints.Ordered, V any] struct {
+type node[K any, V any] struct {
 	left  *node[K, V]
 	right *node[K, V]
 	pair[K, V]
 	parentColor[K, V]
 }
 
+// 返回子树的节点个数, 空节点返回0
+func (n *node[K, V]) count() int {
+	if n == nil {
+		return 0
+	}
+	return int(n.size)
+}
+
 func (n *node[K, V]) setParent(parent *node[K, V]) {
 	n.parent = parent
 }
@@ -53,10 +69,18 @@ func (n *node[K, V]) setParent(parent *node[K, V]) {
 func (n *node[K, V]) link(parent *node[K, V], link **node[K, V]) {
 	n.parent = parent
 	n.color = RED
+	n.size = 1
 	*link = n
 }
 
-type root[K constraints.Ordered, V any] struct {
+// n的所有祖先, 子树节点个数加上delta
+func (n *node[K, V]) addAncestorsSize(delta int32) {
+	for p := n.parent; p != nil; p = p.parent {
+		p.size += delta
+	}
+}
+
+type root[K any, V any] struct {
 	node *node[K, V]
 }
 
@@ -83,6 +107,9 @@ func (r *root[K, V]) rotateLeft(n *node[K, V]) {
 		r.node = right
 	}
 	n.parent = right
+
+	// 只用到旋转时本来就会访问的节点, 不去读n.left, 少一次cache miss
+	n.size, right.size = n.size-right.size+int32(n.right.count()), n.size
 }
 
 func (r *root[K, V]) rotateRight(n *node[K, V]) {
@@ -104,6 +131,8 @@ func (r *root[K, V]) rotateRight(n *node[K, V]) {
 		r.node = left
 	}
 	n.parent = left
+
+	n.size, left.size = n.size-left.size+int32(n.left.count()), n.size
 }
 
 func (r *root[K, V]) changeChild(old, new, parent *node[K, V]) {
@@ -167,19 +196,126 @@ func (r *root[K, V]) insert(n *node[K, V]) {
 	r.node.color = BLACK //黑根
 }
 
-// 红黑树
+// 红黑树, K必须是有序类型, 零值可以直接使用
+// 其他类型的key(比如[]byte, time.Time, 结构体)使用NewWithCompare创建RBTreeFunc
 type RBTree[K constraints.Ordered, V any] struct {
+	RBTreeFunc[K, V]
+}
+
+// 使用比较函数的红黑树, K可以是任意类型, 必须使用NewWithCompare创建, 零值不能使用
+type RBTreeFunc[K any, V any] struct {
 	length int
 	root   root[K, V]
+	// 每次新加或者删除节点都会加1, 迭代器用它判断树是否被修改过
+	version int
+	// key的比较函数
+	compare func(a, b K) int
 }
 
 // 初始化函数
 func New[K constraints.Ordered, V any]() *RBTree[K, V] {
-	return &RBTree[K, V]{}
+	return &RBTree[K, V]{RBTreeFunc: RBTreeFunc[K, V]{compare: cmp.Compare[K]}}
+}
+
+// 使用自定义的比较函数初始化, key可以是任意类型
+// compare(a, b) a < b 返回负数, a == b 返回0, a > b 返回正数
+func NewWithCompare[K any, V any](compare func(a, b K) int) *RBTreeFunc[K, V] {
+	return &RBTreeFunc[K, V]{compare: compare}
+}
+
+// 零值的RBTree没有比较函数, 使用cmp.Compare
+func (r *RBTree[K, V]) lazyinit() {
+	if r.compare == nil {
+		r.compare = cmp.Compare[K]
+	}
+}
+
+// Get, Set, Delete是最常用的操作, K是有序类型时直接用==和<比较, 不经过比较函数
+func (r *RBTree[K, V]) Get(k K) (v V) {
+	v, _ = r.GetWithBool(k)
+	return
+}
+
+// 从rbtree 找到需要的值
+func (r *RBTree[K, V]) GetWithBool(k K) (v V, ok bool) {
+	n := r.root.node
+	for n != nil {
+		if n.key == k {
+			return n.val, true
+		}
+
+		if k > n.key {
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+
+	return
+}
+
+func (r *RBTree[K, V]) Set(k K, v V) {
+	_, _ = r.SetWithPrev(k, v)
+}
+
+// 设置
+func (r *RBTree[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
+	r.lazyinit()
+	link := &r.root.node
+	var parent *node[K, V]
+
+	for *link != nil {
+		parent = *link
+		if parent.key == k {
+			parent.addAncestorsSize(-1)
+			prev = parent.val
+			parent.val = v
+			return prev, true
+		}
+
+		// 查找的同时把经过的节点size+1, 省掉插入之后沿parent往上的一遍, key已存在时再减回去
+		parent.size++
+		if parent.key < k {
+			link = &parent.right
+		} else {
+			link = &parent.left
+		}
+	}
+
+	r.insertNode(parent, link, k, v)
+	return
+}
+
+func (r *RBTree[K, V]) Delete(k K) {
+	var parent *node[K, V]
+	n := r.root.node
+	for n != nil {
+		if n.key == k {
+			r.deleteNode(n)
+			return
+		}
+
+		// 查找的同时把经过的节点size-1, key不存在时再加回去
+		n.size--
+		parent = n
+		if k > n.key {
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+
+	r.deleteMiss(parent)
+}
+
+// 返回lo <= key <= hi的元素个数, O(log n)
+func (r *RBTree[K, V]) CountRange(lo, hi K) int {
+	r.lazyinit()
+	return r.RBTreeFunc.CountRange(lo, hi)
 }
 
 // 第一个节点
-func (r *RBTree[K, V]) First() (v V, ok bool) {
+func (r *RBTreeFunc[K, V]) First() (v V, ok bool) {
 	n := r.root.node
 	if n == nil {
 		return
@@ -193,7 +329,7 @@ func (r *RBTree[K, V]) First() (v V, ok bool) {
 }
 
 // 最后一个节点
-func (r *RBTree[K, V]) Last() (v V, ok bool) {
+func (r *RBTreeFunc[K, V]) Last() (v V, ok bool) {
 	n := r.root.node
 	if n == nil {
 		return
@@ -206,53 +342,63 @@ func (r *RBTree[K, V]) Last() (v V, ok bool) {
 	return n.val, true
 }
 
-func (r *RBTree[K, V]) Set(k K, v V) {
+func (r *RBTreeFunc[K, V]) Set(k K, v V) {
 	_, _ = r.SetWithPrev(k, v)
 }
 
 // 设置
-func (r *RBTree[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
+func (r *RBTreeFunc[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
 	link := &r.root.node
 	var parent *node[K, V]
 
-	node := &node[K, V]{pair: pair[K, V]{key: k, val: v}}
-
 	for *link != nil {
 		parent = *link
-		if parent.key == k {
+		c := r.compare(parent.key, k)
+		if c == 0 {
+			parent.addAncestorsSize(-1)
 			prev = parent.val
 			parent.val = v
 			return prev, true
 		}
 
-		if parent.key < k {
+		// 查找的同时把经过的节点size+1, 省掉插入之后沿parent往上的一遍, key已存在时再减回去
+		parent.size++
+		if c < 0 {
 			link = &parent.right
 		} else {
 			link = &parent.left
 		}
 	}
 
+	r.insertNode(parent, link, k, v)
+	return
+}
+
+// 在link的位置插入新节点, parent是它的父节点, 从根到parent的size已经在查找时加过了
+func (r *RBTreeFunc[K, V]) insertNode(parent *node[K, V], link **node[K, V], k K, v V) {
+	node := &node[K, V]{pair: pair[K, V]{key: k, val: v}}
 	node.link(parent, link)
 	r.root.insert(node)
 	r.length++
-	return
+	r.version++
 }
 
 // Get
-func (r *RBTree[K, V]) Get(k K) (v V) {
+func (r *RBTreeFunc[K, V]) Get(k K) (v V) {
 	v, _ = r.GetWithBool(k)
 	return
 }
 
 // 从rbtree 找到需要的值
-func (r *RBTree[K, V]) GetWithBool(k K) (v V, ok bool) {
+func (r *RBTreeFunc[K, V]) GetWithBool(k K) (v V, ok bool) {
 	n := r.root.node
 	for n != nil {
-		if n.key == k {
+		c := r.compare(k, n.key)
+		if c == 0 {
 			return n.val, true
 		}
 
-		if k > n.key {
+		if c > 0 {
 			n = n.right
 		} else {
 			n = n.left
@@ -262,7 +408,7 @@ func (r *RBTree[K, V]) GetWithBool(k K) (v V, ok bool) {
 	return
 }
 
-// 删除
+// 删除, n的祖先的size由调用者更新
 func (r *root[K, V]) erase(n *node[K, V]) {
 
 	var child, parent *node[K, V]
@@ -272,11 +418,15 @@ func (r *root[K, V]) erase(n *node[K, V]) {
 	} else if n.right == nil {
 		child = n.left
 	} else {
+		// n(后继节点)会被摘下来放到old的位置, 从old到n的父节点, 子树节点个数-1
 		old := n
+		old.size--
 		n = n.right
-		left := n.left
-		for ; left != nil; left = n.left {
+		for left := n.left; left != nil; left = n.left {
+			n.size--
+			n = left
 		}
+
 		child = n.right
 		parent = n.parent
 		color = n.color
@@ -303,6 +453,7 @@ func (r *root[K, V]) erase(n *node[K, V]) {
 		n.color = old.color
 		n.right = old.right
 		n.left = old.left
+		n.size = old.size
 
 		if old.parent != nil {
 			if old.parent.left == old {
@@ -424,31 +575,110 @@ func (r *root[K, V]) eraseColor(n *node[K, V], parent *node[K, V]) {
 
 }
 
-func (r *RBTree[K, V]) Delete(k K) {
+func (r *RBTreeFunc[K, V]) Delete(k K) {
+	var parent *node[K, V]
 	n := r.root.node
 	for n != nil {
-		if n.key == k {
+		c := r.compare(k, n.key)
+		if c == 0 {
 			goto found
 		}
 
-		if k > n.key {
+		// 查找的同时把经过的节点size-1, key不存在时再加回去
+		n.size--
+		parent = n
+		if c > 0 {
 			n = n.right
 		} else {
 			n = n.left
 		}
 	}
+	r.deleteMiss(parent)
 	return
 
 found:
+	r.deleteNode(n)
+}
+
+// Delete没有找到key, 把查找时减掉的size加回去, last是最后经过的节点
+func (r *RBTreeFunc[K, V]) deleteMiss(last *node[K, V]) {
+	if last == nil {
+		return
+	}
+	last.size++
+	last.addAncestorsSize(1)
+}
+
+// 删除节点n, n的所有祖先的size已经在查找时减过了
+func (r *RBTreeFunc[K, V]) deleteNode(n *node[K, V]) {
 	r.root.erase(n)
-	return
+	r.length--
+	r.version++
 }
 
-func (r *RBTree[K, V]) Len() int {
+func (r *RBTreeFunc[K, V]) Len() int {
 	return r.length
 }
 
-func (r *RBTree[K, V]) TopMin(limit int, callback func(k K, v V) bool) {
+// 返回升序排第i个(从0开始)的元素, O(log n)
+func (r *RBTreeFunc[K, V]) Select(i int) (k K, v V, ok bool) {
+	if i < 0 || i >= r.length {
+		return
+	}
+
+	n := r.root.node
+	for n != nil {
+		leftSize := n.left.count()
+		if i == leftSize {
+			return n.key, n.val, true
+		}
+
+		if i < leftSize {
+			n = n.left
+		} else {
+			i -= leftSize + 1
+			n = n.right
+		}
+	}
+	return
+}
+
+// 返回小于k的元素个数, 也就是k在升序中的位置(从0开始)
+// ok表示k是否存在, O(log n)
+func (r *RBTreeFunc[K, V]) Rank(k K) (rank int, ok bool) {
+	n := r.root.node
+	for n != nil {
+		c := r.compare(k, n.key)
+		if c == 0 {
+			return rank + n.left.count(), true
+		}
+
+		if c > 0 {
+			rank += n.left.count() + 1
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+
+	return
+}
+
+// 返回lo <= key <= hi的元素个数, O(log n)
+func (r *RBTreeFunc[K, V]) CountRange(lo, hi K) int {
+	if r.compare(lo, hi) > 0 {
+		return 0
+	}
+
+	start, _ := r.Rank(lo)
+	end, found := r.Rank(hi)
+	if found {
+		end++
+	}
+	return end - start
+}
+
+func (r *RBTreeFunc[K, V]) TopMin(limit int, callback func(k K, v V) bool) {
 
 	r.Range(func(k K, v V) bool {
 
@@ -466,7 +696,7 @@ func (r *RBTree[K, V]) TopMin(limit int, callback func(k K, v V) bool) {
 }
 
 // 遍历rbtree
-func (r *RBTree[K, V]) RangePrev(callback func(k K, v V) bool) {
+func (r *RBTreeFunc[K, V]) RangePrev(callback func(k K, v V) bool) {
 	// 遍历
 	if r.root.node == nil {
 		return
@@ -476,7 +706,7 @@ func (r *RBTree[K, V]) RangePrev(callback func(k K, v V) bool) {
 	return
 }
 
-func (r *RBTree[K, V]) TopMax(limit int, callback func(k K, v V) bool) {
+func (r *RBTreeFunc[K, V]) TopMax(limit int, callback func(k K, v V) bool) {
 
 	r.RangePrev(func(k K, v V) bool {
 
@@ -543,7 +773,7 @@ func (n *node[K, V]) rangeInner(callback func(k K, v V) bool) bool {
 }
 
 // 遍历rbtree
-func (a *RBTree[K, V]) Range(callback func(k K, v V) bool) {
+func (a *RBTreeFunc[K, V]) Range(callback func(k K, v V) bool) {
 	// 遍历
 	if a.root.node == nil {
 		return
@@ -552,3 +782,203 @@ func (a *RBTree[K, V]) Range(callback func(k K, v V) bool) {
 	a.root.node.rangeInner(callback)
 	return
 }
+
+// 返回小于等于k的最大元素
+func (r *RBTreeFunc[K, V]) Floor(k K) (key K, val V, ok bool) {
+	return r.floorNode(k).result()
+}
+
+// 返回大于等于k的最小元素
+func (r *RBTreeFunc[K, V]) Ceiling(k K) (key K, val V, ok bool) {
+	return r.ceilingNode(k).result()
+}
+
+// 返回小于k的最大元素
+func (r *RBTreeFunc[K, V]) Lower(k K) (key K, val V, ok bool) {
+	return r.lowerNode(k).result()
+}
+
+// 返回大于k的最小元素
+func (r *RBTreeFunc[K, V]) Higher(k K) (key K, val V, ok bool) {
+	return r.higherNode(k).result()
+}
+
+func (r *RBTreeFunc[K, V]) floorNode(k K) (found *node[K, V]) {
+	n := r.root.node
+	for n != nil {
+		c := r.compare(n.key, k)
+		if c == 0 {
+			return n
+		}
+
+		if c < 0 {
+			found = n
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+
+	return
+}
+
+func (r *RBTreeFunc[K, V]) ceilingNode(k K) (found *node[K, V]) {
+	n := r.root.node
+	for n != nil {
+		c := r.compare(n.key, k)
+		if c == 0 {
+			return n
+		}
+
+		if c > 0 {
+			found = n
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+
+	return
+}
+
+func (r *RBTreeFunc[K, V]) lowerNode(k K) (found *node[K, V]) {
+	n := r.root.node
+	for n != nil {
+		if r.compare(n.key, k) < 0 {
+			found = n
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+
+	return
+}
+
+func (r *RBTreeFunc[K, V]) higherNode(k K) (found *node[K, V]) {
+	n := r.root.node
+	for n != nil {
+		if r.compare(n.key, k) > 0 {
+			found = n
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+
+	return
+}
+
+// 把节点转成返回值, 空节点ok为false
+func (n *node[K, V]) result() (key K, val V, ok bool) {
+	if n == nil {
+		return
+	}
+	return n.key, n.val, true
+}
+
+// 中序遍历的后继节点
+func (n *node[K, V]) next() *node[K, V] {
+	if n.right != nil {
+		n = n.right
+		for n.left != nil {
+			n = n.left
+		}
+		return n
+	}
+
+	for n.parent != nil && n == n.parent.right {
+		n = n.parent
+	}
+	return n.parent
+}
+
+// 中序遍历的前驱节点
+func (n *node[K, V]) prev() *node[K, V] {
+	if n.left != nil {
+		n = n.left
+		for n.right != nil {
+			n = n.right
+		}
+		return n
+	}
+
+	for n.parent != nil && n == n.parent.left {
+		n = n.parent
+	}
+	return n.parent
+}
+
+// 升序遍历lo到hi之间的元素, loInclusive和hiInclusive控制是否包含lo和hi
+// 定位lo是O(log n), callback 返回false就停止遍历
+func (r *RBTreeFunc[K, V]) RangeBetween(lo, hi K, loInclusive, hiInclusive bool, callback func(k K, v V) bool) {
+	var n *node[K, V]
+	if loInclusive {
+		n = r.ceilingNode(lo)
+	} else {
+		n = r.higherNode(lo)
+	}
+
+	for ; n != nil; n = n.next() {
+		if c := r.compare(n.key, hi); c > 0 || !hiInclusive && c == 0 {
+			return
+		}
+
+		if !callback(n.key, n.val) {
+			return
+		}
+	}
+}
+
+// 从hi到lo降序遍历, loInclusive和hiInclusive控制是否包含lo和hi
+// 定位hi是O(log n), callback 返回false就停止遍历
+func (r *RBTreeFunc[K, V]) RangePrevBetween(lo, hi K, loInclusive, hiInclusive bool, callback func(k K, v V) bool) {
+	var n *node[K, V]
+	if hiInclusive {
+		n = r.floorNode(hi)
+	} else {
+		n = r.lowerNode(hi)
+	}
+
+	for ; n != nil; n = n.prev() {
+		if c := r.compare(n.key, lo); c < 0 || !loInclusive && c == 0 {
+			return
+		}
+
+		if !callback(n.key, n.val) {
+			return
+		}
+	}
+}
+
+// 返回key和value的迭代器, 可以配合for range使用
+func (r *RBTreeFunc[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		r.Range(yield)
+	}
+}
+
+// 返回key的迭代器
+func (r *RBTreeFunc[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		r.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+// 返回value的迭代器
+func (r *RBTreeFunc[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		r.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
+
+// 从大到小返回key和value的迭代器
+func (r *RBTreeFunc[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		r.RangePrev(yield)
+	}
+}
@@ -26,7 +26,10 @@ package rbtree
 
 import (
 	"fmt"
+	"math/rand"
 	"testing"
+
+	"github.com/antlabs/gstl/cmp"
 )
 
 func BenchmarkSetAsc(b *testing.B) {
@@ -113,3 +116,123 @@ func BenchmarkSetStd(b *testing.B) {
 	}
 
 }
+
+// 随机顺序的key, 比顺序插入更接近真实的使用场景
+// 树的大小固定是benchSize, 每个操作的耗时主要是比较和旋转, 不是cache miss
+const benchSize = 1 << 16
+
+func randKeys() []float64 {
+	keys := make([]float64, benchSize)
+	for i, v := range rand.Perm(benchSize) {
+		keys[i] = float64(v)
+	}
+	return keys
+}
+
+type benchTree interface {
+	Set(k, v float64)
+	Get(k float64) float64
+	Delete(k float64)
+}
+
+func benchSet(b *testing.B, newTree func() benchTree) {
+	keys := randKeys()
+	var set benchTree
+	for i := 0; i < b.N; i++ {
+		if i%benchSize == 0 {
+			b.StopTimer()
+			set = newTree()
+			b.StartTimer()
+		}
+		k := keys[i%benchSize]
+		set.Set(k, k)
+	}
+}
+
+func benchGet(b *testing.B, newTree func() benchTree) {
+	keys := randKeys()
+	set := newTree()
+	for _, k := range keys {
+		set.Set(k, k)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		k := keys[(i*7)%benchSize]
+		if v := set.Get(k); v != k {
+			panic(fmt.Sprintf("need:%f, got:%f", k, v))
+		}
+	}
+}
+
+// key都已经存在, Set只是替换value
+func benchReplace(b *testing.B, newTree func() benchTree) {
+	keys := randKeys()
+	set := newTree()
+	for _, k := range keys {
+		set.Set(k, k)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		k := keys[(i*7)%benchSize]
+		set.Set(k, k)
+	}
+}
+
+func benchDelete(b *testing.B, newTree func() benchTree) {
+	keys := randKeys()
+	var set benchTree
+	for i := 0; i < b.N; i++ {
+		if i%benchSize == 0 {
+			b.StopTimer()
+			set = newTree()
+			for _, k := range keys {
+				set.Set(k, k)
+			}
+			b.StartTimer()
+		}
+		set.Delete(keys[(i*7)%benchSize])
+	}
+}
+
+func newBenchTree() benchTree {
+	return New[float64, float64]()
+}
+
+func BenchmarkSetRand(b *testing.B) {
+	benchSet(b, newBenchTree)
+}
+
+func BenchmarkGetRand(b *testing.B) {
+	benchGet(b, newBenchTree)
+}
+
+func BenchmarkReplaceRand(b *testing.B) {
+	benchReplace(b, newBenchTree)
+}
+
+func BenchmarkDeleteRand(b *testing.B) {
+	benchDelete(b, newBenchTree)
+}
+
+// 使用比较函数的版本, 和上面的Ordered版本对比
+func newBenchTreeFunc() benchTree {
+	return NewWithCompare[float64, float64](cmp.Compare[float64])
+}
+
+func BenchmarkSetRandFunc(b *testing.B) {
+	benchSet(b, newBenchTreeFunc)
+}
+
+func BenchmarkGetRandFunc(b *testing.B) {
+	benchGet(b, newBenchTreeFunc)
+}
+
+func BenchmarkReplaceRandFunc(b *testing.B) {
+	benchReplace(b, newBenchTreeFunc)
+}
+
+func BenchmarkDeleteRandFunc(b *testing.B) {
+	benchDelete(b, newBenchTreeFunc)
+}
@@ -1,10 +1,13 @@
 package rbtree
 
 import (
+	"bytes"
 	"fmt"
+	"math/rand"
 	"testing"
 
 	"github.com/antlabs/gstl/cmp"
+	"github.com/antlabs/gstl/internal/sortedtest"
 	"github.com/antlabs/gstl/vec"
 	"github.com/stretchr/testify/assert"
 )
@@ -218,3 +221,256 @@ func Test_RanePrev(t *testing.T) {
 	assert.Equal(t, gotKey, dataRev)
 	assert.Equal(t, gotVal, dataRev)
 }
+
+// 检查每个节点的size是否等于左右子树size+1
+func checkSize[K any, V any](t *testing.T, n *node[K, V]) int {
+	if n == nil {
+		return 0
+	}
+
+	size := checkSize(t, n.left) + checkSize(t, n.right) + 1
+	assert.Equal(t, n.count(), size, fmt.Sprintf("key:%v", n.key))
+	return size
+}
+
+// 检查红黑树的性质: 红色节点的孩子都是黑色, 每条路径上的黑色节点个数一样, 返回黑高
+func checkColor[K any, V any](t *testing.T, n *node[K, V]) int {
+	if n == nil {
+		return 1
+	}
+
+	if n.color == RED {
+		for _, c := range []*node[K, V]{n.left, n.right} {
+			if c != nil {
+				assert.Equal(t, c.color, BLACK, fmt.Sprintf("key:%v", n.key))
+			}
+		}
+	}
+
+	lh := checkColor(t, n.left)
+	rh := checkColor(t, n.right)
+	assert.Equal(t, lh, rh, fmt.Sprintf("key:%v", n.key))
+	if n.color == BLACK {
+		lh++
+	}
+	return lh
+}
+
+// 随机插入删除, 删除会走到有两个孩子节点的分支
+func Test_RBTree_DeleteRandom(t *testing.T) {
+	b := New[int, int]()
+	max := 1000
+	r := rand.New(rand.NewSource(1))
+	keys := r.Perm(max)
+	for _, k := range keys {
+		b.Set(k, k)
+	}
+
+	deleted := map[int]bool{}
+	for _, k := range keys[:max/2] {
+		b.Delete(k)
+		deleted[k] = true
+	}
+
+	// 删除不存在的key, 长度不变
+	b.Delete(max + 1)
+
+	assert.Equal(t, b.Len(), max-max/2)
+	checkSize(t, b.root.node)
+	for i := 0; i < max; i++ {
+		_, ok := b.GetWithBool(i)
+		assert.Equal(t, ok, !deleted[i], fmt.Sprintf("index:%d", i))
+	}
+}
+
+// 测试Select, Rank, CountRange
+func Test_RBTree_SelectRank(t *testing.T) {
+	b := New[int, int]()
+	max := 1000
+	r := rand.New(rand.NewSource(1))
+	for _, k := range r.Perm(max) {
+		b.Set(k*2, k)
+	}
+
+	// 删除掉一部分, 再检查size
+	for i := 0; i < max; i += 3 {
+		b.Delete(i * 2)
+	}
+	checkSize(t, b.root.node)
+
+	var keys []int
+	b.Range(func(k, v int) bool {
+		keys = append(keys, k)
+		return true
+	})
+
+	for i, k := range keys {
+		key, val, ok := b.Select(i)
+		assert.True(t, ok)
+		assert.Equal(t, key, k)
+		assert.Equal(t, val, k/2)
+
+		rank, ok := b.Rank(k)
+		assert.True(t, ok)
+		assert.Equal(t, rank, i)
+
+		// 不存在的key, 返回小于它的元素个数
+		rank, ok = b.Rank(k + 1)
+		assert.False(t, ok)
+		assert.Equal(t, rank, i+1)
+	}
+
+	_, _, ok := b.Select(len(keys))
+	assert.False(t, ok)
+	_, _, ok = b.Select(-1)
+	assert.False(t, ok)
+
+	for _, tc := range [][2]int{{0, 2 * max}, {10, 20}, {11, 19}, {-100, 5}, {3, 3}, {4, 4}, {20, 10}} {
+		need := 0
+		for _, k := range keys {
+			if k >= tc[0] && k <= tc[1] {
+				need++
+			}
+		}
+		assert.Equal(t, b.CountRange(tc[0], tc[1]), need, fmt.Sprintf("lo:%d, hi:%d", tc[0], tc[1]))
+	}
+}
+
+// 测试Floor, Ceiling, Lower, Higher
+func Test_RBTree_FloorCeiling(t *testing.T) {
+	sortedtest.FloorCeiling(t, New[int, int])
+}
+
+// 随机插入删除之后, 迭代器和查询结果都和参考结果一样, 并且结构没有被破坏
+func Test_RBTree_SetDeleteRandom(t *testing.T) {
+	sortedtest.SetDeleteRandom(t, New[int, int], func(b *RBTree[int, int]) {
+		checkSize(t, b.root.node)
+		checkColor(t, b.root.node)
+		if b.root.node != nil {
+			assert.Equal(t, b.root.node.color, BLACK)
+		}
+	})
+}
+
+// NewWithCompare创建的树, Set和Delete走的是另一份查找代码, 同样检查size和颜色
+func Test_RBTree_SetDeleteRandomFunc(t *testing.T) {
+	sortedtest.SetDeleteRandom(t, func() *RBTreeFunc[int, int] {
+		return NewWithCompare[int, int](cmp.Compare[int])
+	}, func(b *RBTreeFunc[int, int]) {
+		checkSize(t, b.root.node)
+		checkColor(t, b.root.node)
+	})
+}
+
+// 测试RangeBetween和RangePrevBetween
+func Test_RBTree_RangeBetween(t *testing.T) {
+	sortedtest.RangeBetween(t, New[int, int])
+}
+
+// 测试迭代器
+func Test_RBTree_Iterator(t *testing.T) {
+	sortedtest.Iterator(t, New[int, int])
+}
+
+// 迭代的过程中修改容器
+func Test_RBTree_IteratorMutation(t *testing.T) {
+	sortedtest.IteratorMutation(t, New[int, int])
+}
+
+// 使用两个迭代器合并两个有序容器
+func Test_RBTree_IteratorMerge(t *testing.T) {
+	sortedtest.IteratorMerge(t, New[int, int])
+}
+
+// 测试range over func
+func Test_RBTree_Iter(t *testing.T) {
+	sortedtest.Iter(t, New[int, int])
+}
+
+// key不是Ordered类型, 使用自定义的比较函数
+func Test_RBTree_NewWithCompare(t *testing.T) {
+	sortedtest.NewWithCompare(t, NewWithCompare[sortedtest.Point, int])
+}
+
+// 反过来的比较函数, 和Ordered版本的结果顺序相反
+func Test_RBTree_NewWithCompareReverse(t *testing.T) {
+	sortedtest.NewWithCompareReverse(t, NewWithCompare[int, int])
+}
+
+type myString string
+
+// 零值的RBTree也可以直接使用
+func Test_RBTree_ZeroValue(t *testing.T) {
+	var b RBTree[myString, int]
+	for i, s := range []myString{"c", "a", "b"} {
+		b.Set(s, i)
+	}
+
+	var got []myString
+	for k := range b.Keys() {
+		got = append(got, k)
+	}
+	assert.Equal(t, got, []myString{"a", "b", "c"})
+	assert.Equal(t, b.Get("c"), 0)
+}
+
+// 非有序类型的key需要使用NewWithCompare
+func Test_RBTree_NewWithCompareBytes(t *testing.T) {
+	b := NewWithCompare[[]byte, int](bytes.Compare)
+	b.Set([]byte("b"), 2)
+	b.Set([]byte("a"), 1)
+	assert.Equal(t, b.Get([]byte("a")), 1)
+	assert.Equal(t, b.CountRange([]byte("a"), []byte("b")), 2)
+}
+
+// 零值的RBTree没有调用过Set, 读操作也可以直接使用
+func Test_RBTree_ZeroValueRead(t *testing.T) {
+	var b RBTree[int, int]
+	assert.Equal(t, b.Len(), 0)
+	assert.Equal(t, b.Get(1), 0)
+	_, ok := b.GetWithBool(1)
+	assert.False(t, ok)
+	_, ok = b.First()
+	assert.False(t, ok)
+	_, ok = b.Last()
+	assert.False(t, ok)
+	_, _, ok = b.Select(0)
+	assert.False(t, ok)
+	_, ok = b.Rank(1)
+	assert.False(t, ok)
+	assert.Equal(t, b.CountRange(1, 2), 0)
+	_, _, ok = b.Floor(1)
+	assert.False(t, ok)
+	_, _, ok = b.Ceiling(1)
+	assert.False(t, ok)
+	_, _, ok = b.Lower(1)
+	assert.False(t, ok)
+	_, _, ok = b.Higher(1)
+	assert.False(t, ok)
+
+	count := 0
+	callback := func(int, int) bool {
+		count++
+		return true
+	}
+	b.Range(callback)
+	b.RangePrev(callback)
+	b.TopMin(1, callback)
+	b.TopMax(1, callback)
+	b.RangeBetween(1, 2, true, true, callback)
+	b.RangePrevBetween(1, 2, true, true, callback)
+	for range b.All() {
+		count++
+	}
+	for range b.Backward() {
+		count++
+	}
+	assert.Equal(t, count, 0)
+
+	it := b.Iterator()
+	assert.False(t, it.Seek(1))
+	assert.False(t, it.First())
+	assert.False(t, it.Last())
+	b.Delete(1)
+	assert.Equal(t, b.Len(), 0)
+}
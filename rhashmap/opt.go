@@ -1,6 +1,8 @@
 package rhashmap
 
 // apache 2.0 antlabs
+import "github.com/antlabs/gstl/hasher"
+
 type Option interface {
 	apply(*config)
 }
@@ -11,10 +13,25 @@ func (h hashFunc) apply(c *config) {
 	c.hashFunc = h
 }
 
+// 设置字符串的hash函数, string, 整数和浮点数类型的key会使用它
 func WithHashFunc(hfunc func(str string) uint64) Option {
 	return hashFunc(hfunc)
 }
 
+type withHasher struct {
+	keyHasher any
+}
+
+func (w withHasher) apply(c *config) {
+	c.keyHasher = w.keyHasher
+}
+
+// 设置key的Hasher, 所有类型的key都可以使用, 优先级比WithHashFunc高
+// K必须和HashMap的key类型一样
+func WithHasher[K comparable](h hasher.Hasher[K]) Option {
+	return withHasher{keyHasher: h}
+}
+
 type withCap int
 
 func (wc withCap) apply(c *config) {
@@ -24,3 +41,24 @@ func (wc withCap) apply(c *config) {
 func WithCap(cap int) Option {
 	return withCap(cap)
 }
+
+// 遍历的顺序
+type Order int8
+
+const (
+	// 按hash桶的顺序, 默认值
+	NoOrder Order = iota
+	// 按插入的顺序, 更新已经存在的key不改变顺序
+	InsertionOrder
+	// 按访问的顺序, Get和Set都会把元素移动到最后, 可以用来实现LRU
+	AccessOrder
+)
+
+func (o Order) apply(c *config) {
+	c.order = o
+}
+
+// 设置遍历的顺序, 有序模式下会额外维护一个双向链表, 只有HashMap支持, NewSwissMap会panic
+func WithOrder(order Order) Option {
+	return order
+}
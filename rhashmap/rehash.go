@@ -0,0 +1,104 @@
+package rhashmap
+
+// apache 2.0 antlabs
+// 参考资料
+// https://github.com/redis/redis/blob/unstable/src/dict.c dictRehashMilliseconds
+// 渐进式rehash只在Get, Set, Delete的时候推进, 没有访问的hash表会一直保留两个table
+// 这里提供按时间片rehash的接口, 可以在空闲的时候(或者定时)调用
+import (
+	"sync"
+	"time"
+)
+
+// rehash的进度
+type RehashProgress struct {
+	// 是否正在rehash
+	Rehashing bool
+	// 旧表的桶数
+	Buckets uint64
+	// 旧表已经迁移的桶数
+	MigratedBuckets uint64
+	// 旧表还没有迁移的元素个数
+	Remaining uint64
+	// 累计迁移的桶数
+	TotalBuckets uint64
+	// 累计迁移的元素个数
+	TotalEntries uint64
+	// 累计完成rehash的次数
+	Completed uint64
+}
+
+// 在ms毫秒内执行rehash, 每次最多迁移100个桶, 返回实际迁移的(非空)桶数
+// 没有初始化, 没有在rehash或者有安全迭代器打开时返回0
+func (h *HashMap[K, V]) RehashMilliseconds(ms int) int {
+	if !h.rehashing() || h.pauseRehash > 0 {
+		return 0
+	}
+
+	start := time.Now()
+	timeout := time.Duration(ms) * time.Millisecond
+	before := h.rehashedBuckets
+	for h.isRehashing() {
+		h.rehash(100)
+		if time.Since(start) > timeout {
+			break
+		}
+	}
+	return int(h.rehashedBuckets - before)
+}
+
+// 返回rehash的进度
+func (h *HashMap[K, V]) RehashProgress() (p RehashProgress) {
+	p.TotalBuckets = h.rehashedBuckets
+	p.TotalEntries = h.rehashedEntries
+	p.Completed = h.rehashCount
+	if !h.rehashing() {
+		return
+	}
+
+	p.Rehashing = true
+	p.Buckets = hashSize(h.sizeExp[0])
+	p.MigratedBuckets = uint64(h.rehashidx)
+	p.Remaining = h.used[0]
+	return
+}
+
+// 零值的HashMap rehashidx是0, 不能只看isRehashing
+func (h *HashMap[K, V]) rehashing() bool {
+	return h.init && h.table[0] != nil && h.isRehashing()
+}
+
+// 启动一个goroutine, 每隔interval调用一次RehashMilliseconds(ms), 返回的stop函数用于停止
+// HashMap不是线程安全的, locker必须和其它访问HashMap的代码共用同一把锁
+func (h *HashMap[K, V]) RehashEvery(interval time.Duration, ms int, locker sync.Locker) (stop func()) {
+	if locker == nil {
+		panic("rhashmap: RehashEvery needs a non-nil locker")
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				locker.Lock()
+				h.RehashMilliseconds(ms)
+				locker.Unlock()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+		})
+	}
+}
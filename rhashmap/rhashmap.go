@@ -5,12 +5,11 @@ package rhashmap
 // https://github.com/redis/redis/blob/unstable/src/dict.c
 import (
 	"errors"
+	"iter"
 	"math"
-	"reflect"
-	"unsafe"
 
 	"github.com/antlabs/gstl/api"
-	xxhash "github.com/cespare/xxhash/v2"
+	"github.com/antlabs/gstl/hasher"
 )
 
 var _ api.Map[int, int] = (*HashMap[int, int])(nil)
@@ -33,11 +32,19 @@ type entry[K comparable, V any] struct {
 	key  K
 	val  V
 	next *entry[K, V]
+	// 有序模式下, 所有元素组成一个双向链表
+	before *entry[K, V]
+	after  *entry[K, V]
+	// 已经被删除, Range保存的下一个元素可能就是它, 需要跳过
+	removed bool
 }
 
 type config struct {
 	hashFunc func(str string) uint64
-	cap      int
+	// WithHasher设置的hasher.Hasher[K]
+	keyHasher any
+	cap       int
+	order     Order
 }
 
 // hash 表头
@@ -51,10 +58,21 @@ type HashMap[K comparable, V any] struct {
 	sizeExp [2]int8           //记录exp
 
 	rehashidx int // rehashid目前的槽位
-	keySize   int //key的长度
+	// 大于0时暂停渐进式rehash, 安全迭代器和Scan使用
+	pauseRehash int
+	// 累计迁移的桶数, 元素个数和完成rehash的次数
+	rehashedBuckets uint64
+	rehashedEntries uint64
+	rehashCount     uint64
 	config
-	isKeyStr bool //是string类型的key, 或者不是
-	init     bool
+	hasher hasher.Hasher[K] // 计算key的hash值
+	init   bool
+
+	// 有序模式下双向链表的头和尾, head是最早插入(或者最久没有访问)的元素
+	head *entry[K, V]
+	tail *entry[K, V]
+	// 正在进行的有序Range的个数, 大于0时不调整链表的顺序
+	ranging int
 }
 
 // 初始化一个hashtable
@@ -67,12 +85,11 @@ func New[K comparable, V any]() *HashMap[K, V] {
 func (h *HashMap[K, V]) Init() {
 
 	h.rehashidx = -1
-	h.hashFunc = xxhash.Sum64String
 	h.init = true
 
 	h.reset(0)
 	h.reset(1)
-	h.keyTypeAndKeySize()
+	h.initHasher()
 }
 
 func (h *HashMap[K, V]) lazyinit() {
@@ -87,6 +104,7 @@ func NewWithOpt[K comparable, V any](opts ...Option) *HashMap[K, V] {
 	for _, o := range opts {
 		o.apply(&h.config)
 	}
+	h.initHasher()
 
 	if h.cap > 0 {
 		h.Resize(uint64(h.cap))
@@ -94,33 +112,24 @@ func NewWithOpt[K comparable, V any](opts ...Option) *HashMap[K, V] {
 	return h
 }
 
-// 保存key的类型和key的长度
-func (h *HashMap[K, V]) keyTypeAndKeySize() {
-	var k K
-	switch (interface{})(k).(type) {
-	case string:
-		h.isKeyStr = true
-	default:
-		h.keySize = int(unsafe.Sizeof(k))
+// 根据配置选择计算hash值的方式
+// 优先使用WithHasher, 其次是WithHashFunc, 都没有设置就使用key类型默认的hasher
+func (h *HashMap[K, V]) initHasher() {
+	if h.keyHasher != nil {
+		kh, ok := h.keyHasher.(hasher.Hasher[K])
+		if !ok {
+			panic("rhashmap: the key type of WithHasher does not match the map")
+		}
+		h.hasher = kh
+		return
 	}
+
+	h.hasher = hasher.New[K](h.hashFunc)
 }
 
 // 计算hash值
 func (h *HashMap[K, V]) calHash(k K) uint64 {
-	var key string
-
-	if h.isKeyStr {
-		// 直接赋值会报错, 使用unsafe绕过编译器检查
-		key = *(*string)(unsafe.Pointer(&k))
-	} else {
-		// 因为xxhash.Sum64String 接收string, 所以要把非string类型变量当成string类型来处理
-		key = *(*string)(unsafe.Pointer(&reflect.StringHeader{
-			Data: uintptr(unsafe.Pointer(&k)),
-			Len:  h.keySize,
-		}))
-	}
-
-	return xxhash.Sum64String(key)
+	return h.hasher.Hash(k)
 }
 
 func (h *HashMap[K, V]) isRehashing() bool {
@@ -140,8 +149,6 @@ func nextExp(size uint64) int8 {
 		}
 		e++
 	}
-
-	return e
 }
 
 func (h *HashMap[K, V]) expand() error {
@@ -248,6 +255,11 @@ func (h *HashMap[K, V]) rehash(n int) error {
 		return ErrHashing
 	}
 
+	// 有安全迭代器打开, 元素不能移动
+	if h.pauseRehash > 0 {
+		return nil
+	}
+
 	// n是控制桶数
 	for ; n > 0 && h.used[0] != 0; n-- {
 
@@ -268,11 +280,13 @@ func (h *HashMap[K, V]) rehash(n int) error {
 			h.table[1][newIdx] = head
 			h.used[0]--
 			h.used[1]++
+			h.rehashedEntries++
 			head = next
 		}
 
 		h.table[0][h.rehashidx] = nil
 		h.rehashidx++
+		h.rehashedBuckets++
 	}
 
 	if h.used[0] == 0 {
@@ -282,6 +296,7 @@ func (h *HashMap[K, V]) rehash(n int) error {
 		h.reset(1)
 		// 这里重装置为-1
 		h.rehashidx = -1
+		h.rehashCount++
 	}
 	return nil
 }
@@ -308,11 +323,25 @@ func sizeMask(exp int8) uint64 {
 }
 
 // 获取
+// 访问顺序模式下, 找到的元素会移动到链表尾部
 func (h *HashMap[K, V]) GetWithBool(key K) (v V, ok bool) {
-	if h.Len() == 0 {
+	e := h.findEntry(key)
+	if e == nil {
 		return
 	}
 
+	if h.order == AccessOrder {
+		h.moveToBack(e)
+	}
+	return e.val, true
+}
+
+// 查找key对应的元素, 找不到返回nil
+func (h *HashMap[K, V]) findEntry(key K) *entry[K, V] {
+	if h.Len() == 0 {
+		return nil
+	}
+
 	if h.isRehashing() {
 		h.rehash(1)
 	}
@@ -324,7 +353,7 @@ func (h *HashMap[K, V]) GetWithBool(key K) (v V, ok bool) {
 		head := h.table[table][idx]
 		for head != nil {
 			if key == head.key {
-				return head.val, true
+				return head
 			}
 
 			head = head.next
@@ -334,7 +363,7 @@ func (h *HashMap[K, V]) GetWithBool(key K) (v V, ok bool) {
 			break
 		}
 	}
-	return
+	return nil
 }
 
 // 获取
@@ -344,32 +373,39 @@ func (h *HashMap[K, V]) Get(key K) (v V) {
 }
 
 // 遍历
+// 有序模式下按链表的顺序遍历, 否则按hash桶的顺序遍历
+// 遍历的过程中可以删除任意元素, 新加的元素可能遍历到也可能遍历不到
+// 有序模式下遍历的过程中不会调整顺序, callback里面调用Get, Set, MoveToBack, MoveToFront都不会移动元素
 func (h *HashMap[K, V]) Range(pr func(key K, val V) bool) {
 	if h.Len() == 0 {
 		//err = ErrNotFound
 		return
 	}
 
-	if h.isRehashing() {
-		h.rehash(1)
-	}
-
-	length := h.Len()
-	for table := 0; table < 2 && length > 0; table++ {
+	if h.order != NoOrder {
+		h.ranging++
+		defer func() { h.ranging-- }()
 
-		for idx := 0; idx < len(h.table[table]); idx++ {
-			head := h.table[table][idx]
-			for head != nil {
-				if !pr(head.key, head.val) {
-					return
-				}
-				head = head.next
+		// 先保存下一个元素, callback里面可以删除当前元素
+		for e := h.head; e != nil; {
+			next := e.after
+			if !pr(e.key, e.val) {
+				return
 			}
-
-			length--
+			// callback里面删除了next, 沿着删除时保留的after找到还在链表里的元素
+			for next != nil && next.removed {
+				next = next.after
+			}
+			e = next
 		}
-		if !h.isRehashing() {
-			break
+		return
+	}
+
+	it := h.SafeIterator()
+	defer it.Close()
+	for it.Next() {
+		if !pr(it.Key(), it.Value()) {
+			return
 		}
 	}
 }
@@ -401,6 +437,9 @@ func (h *HashMap[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
 		//e.key = k
 		prev = e.val
 		e.val = v
+		if h.order == AccessOrder {
+			h.moveToBack(e)
+		}
 		return prev, true
 	}
 
@@ -408,6 +447,9 @@ func (h *HashMap[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
 	e.next = h.table[idx][index]
 	h.table[idx][index] = e
 	h.used[idx]++
+	if h.order != NoOrder {
+		h.linkBack(e)
+	}
 	return
 }
 
@@ -443,6 +485,10 @@ func (h *HashMap[K, V]) Remove(key K) (err error) {
 					h.table[table][idx] = head.next
 				}
 				h.used[table]--
+				if h.order != NoOrder {
+					h.unlink(head)
+					head.removed = true
+				}
 				return nil
 			}
 
@@ -461,3 +507,130 @@ func (h *HashMap[K, V]) Remove(key K) (err error) {
 func (h *HashMap[K, V]) Len() int {
 	return int(h.used[0] + h.used[1])
 }
+
+// 把e放到链表尾部
+func (h *HashMap[K, V]) linkBack(e *entry[K, V]) {
+	e.before = h.tail
+	e.after = nil
+	if h.tail != nil {
+		h.tail.after = e
+	} else {
+		h.head = e
+	}
+	h.tail = e
+}
+
+// 把e放到链表头部
+func (h *HashMap[K, V]) linkFront(e *entry[K, V]) {
+	e.before = nil
+	e.after = h.head
+	if h.head != nil {
+		h.head.before = e
+	} else {
+		h.tail = e
+	}
+	h.head = e
+}
+
+// 把e从链表中摘下来
+func (h *HashMap[K, V]) unlink(e *entry[K, V]) {
+	if e.before != nil {
+		e.before.after = e.after
+	} else {
+		h.head = e.after
+	}
+
+	if e.after != nil {
+		e.after.before = e.before
+	} else {
+		h.tail = e.before
+	}
+	// 保留e.after, Range保存的下一个元素可能是被删除的e, 需要通过它找到后面的元素
+	e.before = nil
+}
+
+// Range的过程中不移动, 否则当前元素移到尾部之后会被再次遍历到
+func (h *HashMap[K, V]) moveToBack(e *entry[K, V]) {
+	if h.tail == e || h.ranging > 0 {
+		return
+	}
+	h.unlink(e)
+	h.linkBack(e)
+}
+
+// 返回链表的第一个元素, 只在有序模式下有效
+// 插入顺序模式下是最早插入的元素, 访问顺序模式下是最久没有访问的元素
+func (h *HashMap[K, V]) First() (k K, v V, ok bool) {
+	if h.head == nil {
+		return
+	}
+	return h.head.key, h.head.val, true
+}
+
+// 返回链表的最后一个元素, 只在有序模式下有效
+func (h *HashMap[K, V]) Last() (k K, v V, ok bool) {
+	if h.tail == nil {
+		return
+	}
+	return h.tail.key, h.tail.val, true
+}
+
+// 把key移动到链表尾部, 只在有序模式下有效, key不存在返回false
+// Range的过程中不移动
+func (h *HashMap[K, V]) MoveToBack(key K) bool {
+	if h.order == NoOrder {
+		return false
+	}
+
+	e := h.findEntry(key)
+	if e == nil {
+		return false
+	}
+
+	h.moveToBack(e)
+	return true
+}
+
+// 把key移动到链表头部, 只在有序模式下有效, key不存在返回false
+// Range的过程中不移动
+func (h *HashMap[K, V]) MoveToFront(key K) bool {
+	if h.order == NoOrder {
+		return false
+	}
+
+	e := h.findEntry(key)
+	if e == nil {
+		return false
+	}
+
+	if h.head != e && h.ranging == 0 {
+		h.unlink(e)
+		h.linkFront(e)
+	}
+	return true
+}
+
+// 返回key和value的迭代器, 可以配合for range使用
+func (h *HashMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		h.Range(yield)
+	}
+}
+
+// 返回key的迭代器
+func (h *HashMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		h.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+// 返回value的迭代器
+func (h *HashMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		h.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
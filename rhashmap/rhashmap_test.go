@@ -2,9 +2,15 @@ package rhashmap
 
 // apache 2.0 antlabs
 import (
+	"fmt"
+	"math"
 	"sort"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/antlabs/gstl/hasher"
+	xxhash "github.com/cespare/xxhash/v2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -247,3 +253,514 @@ func Test_Range_ShrinkToFit(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, hm.Len(), 0)
 }
+
+// 测试range over func
+func Test_All(t *testing.T) {
+	hm := New[int, int]()
+	max := 100
+	for i := 0; i < max; i++ {
+		hm.Set(i, i*10)
+	}
+
+	all := map[int]int{}
+	for k, v := range hm.All() {
+		all[k] = v
+	}
+	assert.Equal(t, len(all), max)
+	for k, v := range all {
+		assert.Equal(t, v, k*10)
+	}
+
+	var keys, vals []int
+	for k := range hm.Keys() {
+		keys = append(keys, k)
+	}
+	for v := range hm.Values() {
+		vals = append(vals, v)
+	}
+	sort.Ints(keys)
+	sort.Ints(vals)
+	for i := 0; i < max; i++ {
+		assert.Equal(t, keys[i], i)
+		assert.Equal(t, vals[i], i*10)
+	}
+
+	n := 0
+	for range hm.All() {
+		n++
+		if n == 3 {
+			break
+		}
+	}
+	assert.Equal(t, n, 3)
+}
+
+type structKey struct {
+	name string
+	id   int
+}
+
+// struct类型的key, 里面的string按内容比较
+func Test_SetGet_StructKey(t *testing.T) {
+	hm := New[structKey, int]()
+	max := 1000
+	for i := 0; i < max; i++ {
+		hm.Set(structKey{name: fmt.Sprint("name", i), id: i}, i)
+	}
+
+	assert.Equal(t, hm.Len(), max)
+	for i := 0; i < max; i++ {
+		v, ok := hm.GetWithBool(structKey{name: fmt.Sprint("name", i), id: i})
+		assert.True(t, ok)
+		assert.Equal(t, v, i)
+	}
+
+	_, ok := hm.GetWithBool(structKey{name: "name1", id: 2})
+	assert.False(t, ok)
+}
+
+// +0和-0是同一个key
+func Test_SetGet_FloatZero(t *testing.T) {
+	hm := New[float64, string]()
+	hm.Set(0, "zero")
+	assert.Equal(t, hm.Get(math.Copysign(0, -1)), "zero")
+
+	hm.Set(math.NaN(), "nan")
+	hm.Set(math.NaN(), "nan")
+	assert.Equal(t, hm.Len(), 3)
+	_, ok := hm.GetWithBool(math.NaN())
+	assert.False(t, ok)
+}
+
+// WithHashFunc和WithHasher设置的hash函数会被使用
+func Test_WithHashFunc(t *testing.T) {
+	calls := 0
+	hm := NewWithOpt[int, int](WithHashFunc(func(str string) uint64 {
+		calls++
+		return xxhash.Sum64String(str)
+	}))
+	for i := 0; i < 100; i++ {
+		hm.Set(i, i)
+	}
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, hm.Get(i), i)
+	}
+	assert.NotEqual(t, calls, 0)
+
+	calls = 0
+	hm2 := NewWithOpt[structKey, int](WithHasher[structKey](hasher.Func[structKey](func(k structKey) uint64 {
+		calls++
+		return uint64(k.id)
+	})))
+	hm2.Set(structKey{name: "a", id: 1}, 1)
+	hm2.Set(structKey{name: "b", id: 1}, 2)
+	assert.Equal(t, hm2.Get(structKey{name: "a", id: 1}), 1)
+	assert.Equal(t, hm2.Get(structKey{name: "b", id: 1}), 2)
+	assert.NotEqual(t, calls, 0)
+
+	assert.Panics(t, func() {
+		NewWithOpt[int, int](WithHasher[string](hasher.New[string](nil)))
+	})
+}
+
+func collectKeys[K comparable, V any](h *HashMap[K, V]) (keys []K) {
+	h.Range(func(k K, _ V) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return
+}
+
+// 插入顺序, rehash之后顺序也不变
+func Test_InsertionOrder(t *testing.T) {
+	hm := NewWithOpt[string, int](WithOrder(InsertionOrder))
+	var need []string
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprint("key", (i*7919)%1000)
+		hm.Set(k, i)
+		need = append(need, k)
+		if i%100 == 0 {
+			assert.Equal(t, collectKeys(hm), need)
+		}
+	}
+	assert.Equal(t, collectKeys(hm), need)
+
+	// 更新不改变顺序
+	hm.Set(need[0], -1)
+	hm.Get(need[1])
+	assert.Equal(t, collectKeys(hm), need)
+
+	k, v, ok := hm.First()
+	assert.True(t, ok)
+	assert.Equal(t, k, need[0])
+	assert.Equal(t, v, -1)
+
+	k, _, ok = hm.Last()
+	assert.True(t, ok)
+	assert.Equal(t, k, need[len(need)-1])
+
+	// 删除
+	for i := 0; i < len(need); i += 2 {
+		hm.Delete(need[i])
+	}
+	var odd []string
+	for i := 1; i < len(need); i += 2 {
+		odd = append(odd, need[i])
+	}
+	assert.Equal(t, collectKeys(hm), odd)
+}
+
+// 访问顺序, 可以当作LRU使用
+func Test_AccessOrder(t *testing.T) {
+	hm := NewWithOpt[int, int](WithOrder(AccessOrder))
+	for i := 0; i < 5; i++ {
+		hm.Set(i, i)
+	}
+
+	hm.Get(1)
+	hm.Set(3, 30)
+	assert.Equal(t, collectKeys(hm), []int{0, 2, 4, 1, 3})
+
+	// 淘汰最久没有访问的元素
+	k, _, ok := hm.First()
+	assert.True(t, ok)
+	assert.Equal(t, k, 0)
+	hm.Delete(k)
+	assert.Equal(t, collectKeys(hm), []int{2, 4, 1, 3})
+}
+
+// 访问顺序模式下, Range的callback里面调用Get不会移动元素, 每个元素只遍历一次
+func Test_AccessOrder_GetInRange(t *testing.T) {
+	hm := NewWithOpt[int, int](WithOrder(AccessOrder))
+	for i := 0; i < 3; i++ {
+		hm.Set(i, i)
+	}
+
+	var got []int
+	hm.Range(func(k, _ int) bool {
+		got = append(got, k)
+		hm.Get(k)
+		hm.Set(k, k*10)
+		assert.True(t, hm.MoveToBack(k))
+		assert.True(t, hm.MoveToFront(k))
+		return len(got) < 100
+	})
+	assert.Equal(t, got, []int{0, 1, 2})
+	assert.Equal(t, hm.Get(1), 10)
+
+	// Range结束之后恢复按访问顺序移动, 上面的Get(1)已经把1移到了尾部
+	hm.Get(0)
+	assert.Equal(t, collectKeys(hm), []int{2, 1, 0})
+}
+
+// Range的callback里面删除后面的元素, 被删除的元素不会遍历到, 后面的元素也不会丢
+func Test_InsertionOrder_DeleteInRange(t *testing.T) {
+	hm := NewWithOpt[int, int](WithOrder(InsertionOrder))
+	for i := 0; i < 5; i++ {
+		hm.Set(i, i)
+	}
+
+	var got []int
+	hm.Range(func(k, _ int) bool {
+		got = append(got, k)
+		if k == 1 {
+			hm.Delete(2)
+		}
+		return true
+	})
+	assert.Equal(t, got, []int{0, 1, 3, 4})
+	assert.Equal(t, collectKeys(hm), []int{0, 1, 3, 4})
+
+	// 删除当前元素和后面连续的几个元素, 再把其中一个加回来(加到尾部)
+	got = got[:0]
+	hm.Range(func(k, _ int) bool {
+		got = append(got, k)
+		if k == 0 {
+			hm.Delete(0)
+			hm.Delete(1)
+			hm.Delete(3)
+			hm.Set(1, 1)
+		}
+		return true
+	})
+	assert.Equal(t, got, []int{0, 4, 1})
+	assert.Equal(t, collectKeys(hm), []int{4, 1})
+}
+
+func Test_MoveToFrontBack(t *testing.T) {
+	hm := NewWithOpt[int, int](WithOrder(InsertionOrder))
+	for i := 0; i < 5; i++ {
+		hm.Set(i, i)
+	}
+
+	assert.True(t, hm.MoveToBack(0))
+	assert.True(t, hm.MoveToFront(3))
+	assert.True(t, hm.MoveToFront(3))
+	assert.True(t, hm.MoveToBack(0))
+	assert.False(t, hm.MoveToBack(100))
+	assert.Equal(t, collectKeys(hm), []int{3, 1, 2, 4, 0})
+
+	// callback里面删除当前元素
+	hm.Range(func(k, _ int) bool {
+		hm.Delete(k)
+		return true
+	})
+	assert.Equal(t, hm.Len(), 0)
+	_, _, ok := hm.First()
+	assert.False(t, ok)
+	_, _, ok = hm.Last()
+	assert.False(t, ok)
+
+	// 默认模式不维护链表
+	hm2 := New[int, int]()
+	hm2.Set(1, 1)
+	assert.False(t, hm2.MoveToBack(1))
+	_, _, ok = hm2.First()
+	assert.False(t, ok)
+}
+
+// 用Scan遍历所有元素, 每次Scan之后调用between
+func scanAll[K comparable, V any](h *HashMap[K, V], between func()) map[K]int {
+	got := make(map[K]int)
+	cursor := uint64(0)
+	for {
+		cursor = h.Scan(cursor, func(k K, _ V) {
+			got[k]++
+		})
+		if cursor == 0 {
+			return got
+		}
+		if between != nil {
+			between()
+		}
+	}
+}
+
+func Test_Scan(t *testing.T) {
+	hm := New[int, int]()
+	assert.Equal(t, hm.Scan(0, func(k, v int) {}), uint64(0))
+
+	max := 1000
+	for i := 0; i < max; i++ {
+		hm.Set(i, i)
+	}
+
+	// 没有rehash的时候每个元素只返回一次
+	for hm.isRehashing() {
+		hm.rehash(100)
+	}
+	got := scanAll(hm, nil)
+	assert.Equal(t, len(got), max)
+	for k, n := range got {
+		assert.Equal(t, n, 1, k)
+	}
+}
+
+// 两次Scan之间hash表变大或者变小, 一直存在的元素至少返回一次
+func Test_Scan_Resize(t *testing.T) {
+	for _, grow := range []bool{true, false} {
+		hm := New[int, int]()
+		max := 1000
+		for i := 0; i < max; i++ {
+			hm.Set(i, i)
+		}
+		for hm.isRehashing() {
+			hm.rehash(100)
+		}
+
+		step := 0
+		got := scanAll(hm, func() {
+			step++
+			switch {
+			case step == 3 && grow:
+				assert.NoError(t, hm.Resize(hashSize(hm.sizeExp[0])*4))
+			case step == 3 && !grow:
+				// 删除一半的元素再收缩
+				for i := max / 2; i < max; i++ {
+					hm.Delete(i)
+				}
+				assert.NoError(t, hm.ShrinkToFit())
+			}
+			// Get会推动渐进式rehash
+			hm.Get(step)
+		})
+
+		keep := max
+		if !grow {
+			keep = max / 2
+		}
+		for i := 0; i < keep; i++ {
+			assert.GreaterOrEqual(t, got[i], 1, i)
+		}
+	}
+}
+
+func Test_SafeIterator(t *testing.T) {
+	hm := New[int, int]()
+	max := 1000
+	for i := 0; i < max; i++ {
+		hm.Set(i, i)
+	}
+	assert.NoError(t, hm.Resize(hashSize(hm.sizeExp[0])*2))
+	hm.Get(0)
+	assert.True(t, hm.isRehashing())
+	rehashidx := hm.rehashidx
+
+	it := hm.SafeIterator()
+	got := make(map[int]int)
+	for it.Next() {
+		got[it.Key()] = it.Value()
+		// 删除当前元素, 查找和插入不会移动元素
+		hm.Delete(it.Key())
+		hm.Get(it.Key() + 1)
+		assert.Equal(t, hm.rehashidx, rehashidx)
+	}
+	it.Close()
+	it.Close()
+	assert.False(t, it.Next())
+
+	assert.Equal(t, len(got), max)
+	for k, v := range got {
+		assert.Equal(t, k, v)
+	}
+	assert.Equal(t, hm.Len(), 0)
+	assert.Equal(t, hm.pauseRehash, 0)
+}
+
+// 没有访问的时候, 调用RehashMilliseconds完成rehash
+func Test_RehashMilliseconds(t *testing.T) {
+	hm := New[int, int]()
+	max := 10000
+	for i := 0; i < max; i++ {
+		hm.Set(i, i)
+	}
+	for hm.isRehashing() {
+		hm.rehash(100)
+	}
+	assert.Equal(t, hm.RehashMilliseconds(10), 0)
+
+	assert.NoError(t, hm.Resize(hashSize(hm.sizeExp[0])*4))
+	p := hm.RehashProgress()
+	assert.True(t, p.Rehashing)
+	assert.Equal(t, p.MigratedBuckets, uint64(0))
+	assert.Equal(t, p.Remaining, uint64(max))
+
+	// 安全迭代器打开时不迁移
+	it := hm.SafeIterator()
+	assert.Equal(t, hm.RehashMilliseconds(10), 0)
+	it.Close()
+
+	completed := p.Completed
+	entries := p.TotalEntries
+	buckets := p.TotalBuckets
+	n := hm.RehashMilliseconds(1000)
+	assert.Greater(t, n, 0)
+	p = hm.RehashProgress()
+	assert.Equal(t, p.TotalBuckets, buckets+uint64(n))
+	assert.False(t, p.Rehashing)
+	assert.Nil(t, hm.table[1])
+	assert.Equal(t, p.Completed, completed+1)
+	assert.Equal(t, p.TotalEntries, entries+uint64(max))
+
+	for i := 0; i < max; i++ {
+		assert.Equal(t, hm.Get(i), i)
+	}
+}
+
+// 零值的HashMap不能当成正在rehash
+func Test_RehashMilliseconds_ZeroValue(t *testing.T) {
+	var hm HashMap[int, int]
+	assert.Equal(t, hm.RehashMilliseconds(10), 0)
+	assert.Equal(t, hm.RehashProgress(), RehashProgress{})
+	assert.Equal(t, hm.rehashCount, uint64(0))
+
+	hm.Set(1, 1)
+	assert.Equal(t, hm.RehashProgress().Rehashing, false)
+	assert.Equal(t, hm.Get(1), 1)
+}
+
+func Test_RehashEvery(t *testing.T) {
+	hm := New[int, int]()
+	for i := 0; i < 1000; i++ {
+		hm.Set(i, i)
+	}
+
+	var mu sync.Mutex
+	mu.Lock()
+	assert.NoError(t, hm.Resize(hashSize(hm.sizeExp[0])*4))
+	mu.Unlock()
+
+	stop := hm.RehashEvery(time.Millisecond, 1, &mu)
+	defer stop()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return !hm.RehashProgress().Rehashing
+	}, time.Second, time.Millisecond)
+	stop()
+	stop()
+	assert.Panics(t, func() { hm.RehashEvery(time.Millisecond, 1, nil) })
+}
+
+func Test_Stats(t *testing.T) {
+	hm := New[int, int]()
+	s := hm.Stats()
+	assert.Equal(t, len(s.Tables), 0)
+	assert.Equal(t, s.RehashIdx, -1)
+
+	max := 1000
+	for i := 0; i < max; i++ {
+		hm.Set(i, i)
+	}
+	for hm.isRehashing() {
+		hm.rehash(100)
+	}
+
+	s = hm.Stats()
+	assert.False(t, s.Rehashing)
+	assert.Equal(t, len(s.Tables), 1)
+	t0 := s.Tables[0]
+	assert.Equal(t, t0.Used, uint64(max))
+	assert.Equal(t, t0.Size, hashSize(hm.sizeExp[0]))
+	assert.Equal(t, t0.LoadFactor, float64(max)/float64(t0.Size))
+	assert.Equal(t, s.MaxChainLen, t0.MaxChainLen)
+
+	// 分布加起来是桶数和元素个数
+	buckets, elems := uint64(0), uint64(0)
+	for l, n := range t0.ChainLenHist {
+		buckets += n
+		elems += uint64(l) * n
+	}
+	assert.Equal(t, buckets, t0.Size)
+	assert.Equal(t, elems, t0.Used)
+	assert.Equal(t, t0.Slots, t0.Size-t0.ChainLenHist[0])
+	assert.Contains(t, s.String(), "number of elements: 1000")
+
+	// rehash的时候有两个表
+	assert.NoError(t, hm.Resize(t0.Size*2))
+	hm.Get(0)
+	s = hm.Stats()
+	assert.True(t, s.Rehashing)
+	assert.Equal(t, s.RehashIdx, hm.rehashidx)
+	assert.Equal(t, len(s.Tables), 2)
+	assert.Equal(t, s.Tables[0].Used+s.Tables[1].Used, uint64(max))
+	assert.Contains(t, s.String(), "rehashing target")
+}
+
+// 所有key的hash值一样, 只有一个很长的链表
+func Test_Stats_BadHash(t *testing.T) {
+	hm := NewWithOpt[int, int](WithHasher[int](hasher.Func[int](func(int) uint64 { return 1 })))
+	max := 100
+	for i := 0; i < max; i++ {
+		hm.Set(i, i)
+	}
+	for hm.isRehashing() {
+		hm.rehash(100)
+	}
+
+	s := hm.Stats()
+	assert.Equal(t, s.MaxChainLen, uint64(max))
+	assert.Equal(t, s.Tables[0].Slots, uint64(1))
+	assert.Equal(t, s.Tables[0].ChainLenHist[statsVectLen-1], uint64(1))
+}
@@ -0,0 +1,129 @@
+package rhashmap
+
+// apache 2.0 antlabs
+// 参考资料
+// https://github.com/redis/redis/blob/unstable/src/dict.c dictScan和safe iterator
+import "math/bits"
+
+// 从cursor开始遍历一个hash桶(rehash的时候是小表的一个桶和大表里面对应的几个桶), 返回下一次的cursor
+// 第一次调用cursor传0, 返回0表示遍历结束
+// 在整个遍历过程中一直存在的元素至少会返回一次(可能返回多次), 中间调用Resize或者ShrinkToFit也一样
+// cursor的高位加1再反转(reverse binary), 这样hash表大小变化时, 已经访问过的桶不会再访问
+// callback里面可以删除当前元素和新加元素
+func (h *HashMap[K, V]) Scan(cursor uint64, callback func(k K, v V)) (next uint64) {
+	if h.Len() == 0 {
+		return 0
+	}
+
+	// callback里面的修改不能移动元素
+	h.pauseRehash++
+	defer func() { h.pauseRehash-- }()
+
+	v := cursor
+	if !h.isRehashing() {
+		m0 := sizeMask(h.sizeExp[0])
+		emitBucket(h.table[0][v&m0], callback)
+
+		// 高位加1
+		v |= ^m0
+		v = bits.Reverse64(bits.Reverse64(v) + 1)
+		return v
+	}
+
+	t0, t1 := 0, 1
+	// 保证t0是小表, t1是大表
+	if h.sizeExp[t0] > h.sizeExp[t1] {
+		t0, t1 = t1, t0
+	}
+
+	m0, m1 := sizeMask(h.sizeExp[t0]), sizeMask(h.sizeExp[t1])
+	emitBucket(h.table[t0][v&m0], callback)
+
+	// 小表的一个桶对应大表里面低位相同的所有桶
+	for {
+		emitBucket(h.table[t1][v&m1], callback)
+
+		v |= ^m1
+		v = bits.Reverse64(bits.Reverse64(v) + 1)
+		if v&(m0^m1) == 0 {
+			break
+		}
+	}
+	return v
+}
+
+// 遍历一个桶里面的所有元素, 先保存next, callback里面可以删除当前元素
+func emitBucket[K comparable, V any](e *entry[K, V], callback func(k K, v V)) {
+	for e != nil {
+		next := e.next
+		callback(e.key, e.val)
+		e = next
+	}
+}
+
+// 安全迭代器, 打开期间暂停渐进式rehash, 元素不会在两个hash表之间移动
+// 遍历的过程中可以删除当前元素, 新加的元素可能遍历到也可能遍历不到
+// 用完之后需要调用Close
+type SafeIterator[K comparable, V any] struct {
+	h      *HashMap[K, V]
+	table  int
+	index  int
+	next   *entry[K, V]
+	key    K
+	val    V
+	closed bool
+}
+
+// 创建一个安全迭代器, 调用Next之后才指向第一个元素
+func (h *HashMap[K, V]) SafeIterator() *SafeIterator[K, V] {
+	h.pauseRehash++
+	return &SafeIterator[K, V]{h: h}
+}
+
+// 移动到下一个元素, 没有下一个元素返回false
+func (it *SafeIterator[K, V]) Next() bool {
+	if it.closed {
+		return false
+	}
+
+	h := it.h
+	for {
+		if e := it.next; e != nil {
+			// 先保存next, 可以删除当前元素
+			it.next = e.next
+			it.key, it.val = e.key, e.val
+			return true
+		}
+
+		if it.index >= len(h.table[it.table]) {
+			if it.table == 1 || !h.isRehashing() {
+				return false
+			}
+			it.table, it.index = 1, 0
+			continue
+		}
+
+		it.next = h.table[it.table][it.index]
+		it.index++
+	}
+}
+
+// 返回当前元素的key
+func (it *SafeIterator[K, V]) Key() K {
+	return it.key
+}
+
+// 返回当前元素的value
+func (it *SafeIterator[K, V]) Value() V {
+	return it.val
+}
+
+// 关闭迭代器, 恢复渐进式rehash, 可以调用多次
+func (it *SafeIterator[K, V]) Close() {
+	if it.closed {
+		return
+	}
+
+	it.closed = true
+	it.h.pauseRehash--
+}
@@ -0,0 +1,138 @@
+package rhashmap
+
+// apache 2.0 antlabs
+// 参考资料
+// https://github.com/redis/redis/blob/unstable/src/dict.c dictGetStats
+import (
+	"fmt"
+	"strings"
+)
+
+// 链表长度分布的长度, 大于等于statsVectLen-1的链表都算在最后一个里面
+const statsVectLen = 50
+
+// 一个hash表的统计信息
+type TableStats struct {
+	// 桶数
+	Size uint64
+	// 元素个数
+	Used uint64
+	// 负载因子 Used/Size
+	LoadFactor float64
+	// 不为空的桶数
+	Slots uint64
+	// 最长的链表
+	MaxChainLen uint64
+	// 链表长度分布, ChainLenHist[i]表示长度为i的桶数
+	ChainLenHist []uint64
+}
+
+// 平均链表长度(只算不为空的桶)
+func (t TableStats) AvgChainLen() float64 {
+	if t.Slots == 0 {
+		return 0
+	}
+	return float64(t.Used) / float64(t.Slots)
+}
+
+// hash表的统计信息
+type Stats struct {
+	// 是否正在rehash
+	Rehashing bool
+	// rehash到的桶, 没有rehash时是-1
+	RehashIdx int
+	// 没有rehash时只有一个, rehash时Tables[1]是新表
+	Tables []TableStats
+	// 所有表里面最长的链表
+	MaxChainLen uint64
+}
+
+// 返回hash表的统计信息, 需要遍历所有的桶, 时间复杂度是O(n)
+func (h *HashMap[K, V]) Stats() (s Stats) {
+	s.RehashIdx = -1
+	if h.table[0] == nil {
+		return
+	}
+
+	s.Rehashing = h.isRehashing()
+	n := 1
+	if s.Rehashing {
+		s.RehashIdx = h.rehashidx
+		n = 2
+	}
+
+	for i := 0; i < n; i++ {
+		t := h.tableStats(i)
+		if t.MaxChainLen > s.MaxChainLen {
+			s.MaxChainLen = t.MaxChainLen
+		}
+		s.Tables = append(s.Tables, t)
+	}
+	return
+}
+
+func (h *HashMap[K, V]) tableStats(idx int) (t TableStats) {
+	t.Size = uint64(len(h.table[idx]))
+	t.Used = h.used[idx]
+	t.ChainLenHist = make([]uint64, statsVectLen)
+	if t.Size > 0 {
+		t.LoadFactor = float64(t.Used) / float64(t.Size)
+	}
+
+	for _, head := range h.table[idx] {
+		chainLen := uint64(0)
+		for ; head != nil; head = head.next {
+			chainLen++
+		}
+
+		if chainLen > 0 {
+			t.Slots++
+		}
+		if chainLen > t.MaxChainLen {
+			t.MaxChainLen = chainLen
+		}
+		t.ChainLenHist[min(chainLen, statsVectLen-1)]++
+	}
+	return
+}
+
+// 和redis的输出格式类似, 方便打印到日志里面
+func (s Stats) String() string {
+	var b strings.Builder
+	if len(s.Tables) == 0 {
+		b.WriteString("No stats available for empty dictionaries\n")
+		return b.String()
+	}
+
+	for i, t := range s.Tables {
+		name := "main hash table"
+		if i == 1 {
+			name = "rehashing target"
+		}
+		fmt.Fprintf(&b, "Hash table %d stats (%s):\n", i, name)
+		fmt.Fprintf(&b, " table size: %d\n", t.Size)
+		fmt.Fprintf(&b, " number of elements: %d\n", t.Used)
+		fmt.Fprintf(&b, " load factor: %.2f\n", t.LoadFactor)
+		fmt.Fprintf(&b, " different slots: %d\n", t.Slots)
+		fmt.Fprintf(&b, " max chain length: %d\n", t.MaxChainLen)
+		fmt.Fprintf(&b, " avg chain length (counted): %.02f\n", t.AvgChainLen())
+		b.WriteString(" Chain length distribution:\n")
+		for l, n := range t.ChainLenHist {
+			if n == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "   %s: %d (%.02f%%)\n", chainLenLabel(l), n, float64(n)*100/float64(t.Size))
+		}
+	}
+	if s.Rehashing {
+		fmt.Fprintf(&b, "rehash index: %d\n", s.RehashIdx)
+	}
+	return b.String()
+}
+
+func chainLenLabel(l int) string {
+	if l == statsVectLen-1 {
+		return fmt.Sprintf(">= %d", l)
+	}
+	return fmt.Sprintf("%d", l)
+}
@@ -0,0 +1,345 @@
+package rhashmap
+
+// apache 2.0 antlabs
+// 参考资料
+// https://abseil.io/about/design/swisstables
+// https://github.com/golang/go/blob/master/src/internal/runtime/maps/group.go
+// 开放寻址的hash表, 8个槽位组成一个group, 每个槽位有一个控制字节
+// 1. 0x80 表示空槽位
+// 2. 0xFE 表示已经删除的槽位(墓碑)
+// 3. 0x00-0x7F 表示有元素, 值是hash值的低7位(h2)
+// 查找时一次比较一个group的8个控制字节, 只有h2相等的槽位才需要比较key
+// 元素直接保存在数组里面, 插入不需要分配内存, 也没有给GC扫描的指针链
+import (
+	"iter"
+	"math/bits"
+
+	"github.com/antlabs/gstl/api"
+	"github.com/antlabs/gstl/hasher"
+)
+
+var _ api.Map[int, int] = (*SwissMap[int, int])(nil)
+
+const (
+	groupSize = 8
+
+	ctrlEmpty   uint8 = 0x80
+	ctrlDeleted uint8 = 0xFE
+
+	bitsetLSB     = 0x0101010101010101
+	bitsetMSB     = 0x8080808080808080
+	ctrlsEmpty    = bitsetMSB
+	loadFactorNum = 7
+	loadFactorDen = 8
+)
+
+// 8个控制字节
+type ctrlGroup uint64
+
+// 返回控制字节等于h2的槽位, 可能会有误报, 需要再比较key
+func (c ctrlGroup) matchH2(h2 uint8) bitset {
+	v := uint64(c) ^ (bitsetLSB * uint64(h2))
+	return bitset(((v - bitsetLSB) &^ v) & bitsetMSB)
+}
+
+// 返回空槽位
+func (c ctrlGroup) matchEmpty() bitset {
+	// 空槽位的第7位是1, 第1位是0, 墓碑的第1位是1
+	v := uint64(c)
+	return bitset((v &^ (v << 6)) & bitsetMSB)
+}
+
+// 返回空槽位和墓碑
+func (c ctrlGroup) matchEmptyOrDeleted() bitset {
+	return bitset(uint64(c) & bitsetMSB)
+}
+
+// 返回有元素的槽位
+func (c ctrlGroup) matchFull() bitset {
+	return bitset(^uint64(c) & bitsetMSB)
+}
+
+// 第i个槽位是否有元素
+func (c ctrlGroup) isFull(i int) bool {
+	return uint64(c)>>(uint(i)*8)&0x80 == 0
+}
+
+// 第i个槽位是否为空
+func (c ctrlGroup) isEmpty(i int) bool {
+	return uint8(uint64(c)>>(uint(i)*8)) == ctrlEmpty
+}
+
+func (c *ctrlGroup) set(i int, ctrl uint8) {
+	shift := uint(i) * 8
+	*c = ctrlGroup(uint64(*c)&^(0xff<<shift) | uint64(ctrl)<<shift)
+}
+
+// 每个槽位的最高位表示是否匹配
+type bitset uint64
+
+// 返回第一个匹配的槽位
+func (b bitset) first() int {
+	return bits.TrailingZeros64(uint64(b)) >> 3
+}
+
+// 去掉第一个匹配的槽位
+func (b bitset) removeFirst() bitset {
+	return b & (b - 1)
+}
+
+type slot[K comparable, V any] struct {
+	key K
+	val V
+}
+
+type group[K comparable, V any] struct {
+	ctrl  ctrlGroup
+	slots [groupSize]slot[K, V]
+}
+
+// 开放寻址的hash表(swiss table), 和HashMap一样实现了api.Map
+// 和HashMap不同的是, 扩容是一次完成的, 遍历的过程中不能插入新的key
+type SwissMap[K comparable, V any] struct {
+	groups []group[K, V]
+	// 元素个数
+	length int
+	// 墓碑个数
+	tombstones int
+	// 还可以插入多少个元素(包含墓碑)才需要扩容
+	growthLeft int
+	hasher     hasher.Hasher[K]
+	config
+}
+
+// 初始化一个swiss table, 支持WithCap, WithHashFunc, WithHasher选项
+// swiss table的遍历顺序只能是hash的顺序, 使用WithOrder设置有序模式会panic
+func NewSwissMap[K comparable, V any](opts ...Option) *SwissMap[K, V] {
+	s := &SwissMap[K, V]{}
+	for _, o := range opts {
+		o.apply(&s.config)
+	}
+
+	if s.order != NoOrder {
+		panic("rhashmap: SwissMap does not support WithOrder")
+	}
+
+	s.lazyinit()
+	if s.cap > 0 {
+		s.resize(groupsForCap(s.cap))
+	}
+	return s
+}
+
+func (s *SwissMap[K, V]) lazyinit() {
+	if s.hasher != nil {
+		return
+	}
+
+	if s.keyHasher != nil {
+		kh, ok := s.keyHasher.(hasher.Hasher[K])
+		if !ok {
+			panic("rhashmap: the key type of WithHasher does not match the map")
+		}
+		s.hasher = kh
+		return
+	}
+
+	s.hasher = hasher.New[K](s.hashFunc)
+}
+
+// 保存cap个元素需要的group个数, 是2的n次方
+func groupsForCap(cap int) int {
+	slots := (cap*loadFactorDen + loadFactorNum - 1) / loadFactorNum
+	n := 1
+	for n*groupSize < slots {
+		n <<= 1
+	}
+	return n
+}
+
+// 高57位选择group, 低7位保存在控制字节里面
+func splitHash(hash uint64) (h1 uint64, h2 uint8) {
+	return hash >> 7, uint8(hash & 0x7f)
+}
+
+// 查找key所在的group和槽位
+func (s *SwissMap[K, V]) find(key K) (g *group[K, V], i int, ok bool) {
+	if s.length == 0 {
+		return
+	}
+
+	h1, h2 := splitHash(s.hasher.Hash(key))
+	mask := uint64(len(s.groups) - 1)
+	// 二次探测, 每次多跳一个group
+	for idx, step := h1&mask, uint64(1); ; idx, step = (idx+step)&mask, step+1 {
+		g = &s.groups[idx]
+		for m := g.ctrl.matchH2(h2); m != 0; m = m.removeFirst() {
+			i = m.first()
+			if g.slots[i].key == key {
+				return g, i, true
+			}
+		}
+
+		// 有空槽位说明key不存在, 插入的时候也会先用这个空槽位
+		if g.ctrl.matchEmpty() != 0 {
+			return nil, 0, false
+		}
+	}
+}
+
+// 获取
+func (s *SwissMap[K, V]) Get(key K) (v V) {
+	v, _ = s.GetWithBool(key)
+	return
+}
+
+// 获取
+func (s *SwissMap[K, V]) GetWithBool(key K) (v V, ok bool) {
+	g, i, ok := s.find(key)
+	if !ok {
+		return
+	}
+	return g.slots[i].val, true
+}
+
+func (s *SwissMap[K, V]) Set(k K, v V) {
+	s.SetWithPrev(k, v)
+}
+
+// 设置, 如果key已经存在, 返回以前的值
+func (s *SwissMap[K, V]) SetWithPrev(k K, v V) (prev V, replaced bool) {
+	s.lazyinit()
+	if g, i, ok := s.find(k); ok {
+		prev = g.slots[i].val
+		g.slots[i].val = v
+		return prev, true
+	}
+
+	if s.growthLeft == 0 {
+		s.grow()
+	}
+
+	s.insert(k, v)
+	s.length++
+	return
+}
+
+// 插入一个不存在的key, 调用之前需要保证有空位置
+func (s *SwissMap[K, V]) insert(k K, v V) {
+	h1, h2 := splitHash(s.hasher.Hash(k))
+	mask := uint64(len(s.groups) - 1)
+	for idx, step := h1&mask, uint64(1); ; idx, step = (idx+step)&mask, step+1 {
+		g := &s.groups[idx]
+		m := g.ctrl.matchEmptyOrDeleted()
+		if m == 0 {
+			continue
+		}
+
+		i := m.first()
+		if g.ctrl.isEmpty(i) {
+			s.growthLeft--
+		} else {
+			// 复用墓碑, 不占用新的空间
+			s.tombstones--
+		}
+
+		g.ctrl.set(i, h2)
+		g.slots[i] = slot[K, V]{key: k, val: v}
+		return
+	}
+}
+
+// 空间不够时扩容, 如果大部分是墓碑, 大小不变只清理墓碑
+func (s *SwissMap[K, V]) grow() {
+	n := len(s.groups)
+	if n == 0 {
+		n = 1
+	} else if s.length*2 >= n*groupSize*loadFactorNum/loadFactorDen {
+		n *= 2
+	}
+	s.resize(n)
+}
+
+// 重新分配n个group, 把所有元素重新插入
+func (s *SwissMap[K, V]) resize(n int) {
+	old := s.groups
+	s.groups = make([]group[K, V], n)
+	for i := range s.groups {
+		s.groups[i].ctrl = ctrlsEmpty
+	}
+	s.growthLeft = n * groupSize * loadFactorNum / loadFactorDen
+	s.tombstones = 0
+
+	for gi := range old {
+		g := &old[gi]
+		for m := g.ctrl.matchFull(); m != 0; m = m.removeFirst() {
+			i := m.first()
+			s.insert(g.slots[i].key, g.slots[i].val)
+		}
+	}
+}
+
+// 删除
+func (s *SwissMap[K, V]) Delete(key K) {
+	g, i, ok := s.find(key)
+	if !ok {
+		return
+	}
+
+	// group里面有空槽位, 说明查找不会经过这个group去别的group, 可以直接标记成空
+	if g.ctrl.matchEmpty() != 0 {
+		g.ctrl.set(i, ctrlEmpty)
+		s.growthLeft++
+	} else {
+		g.ctrl.set(i, ctrlDeleted)
+		s.tombstones++
+	}
+
+	// 释放key和value引用的内存
+	g.slots[i] = slot[K, V]{}
+	s.length--
+}
+
+// 返回元素个数
+func (s *SwissMap[K, V]) Len() int {
+	return s.length
+}
+
+// 遍历, callback 返回false就停止遍历
+// callback里面可以删除元素, 但是不能插入新的key
+func (s *SwissMap[K, V]) Range(callback func(k K, v V) bool) {
+	for gi := range s.groups {
+		g := &s.groups[gi]
+		// 每次都重新读控制字节, callback可能删除了同一个group后面的元素
+		for i := 0; i < groupSize; i++ {
+			if g.ctrl.isFull(i) && !callback(g.slots[i].key, g.slots[i].val) {
+				return
+			}
+		}
+	}
+}
+
+// 返回key和value的迭代器, 可以配合for range使用
+func (s *SwissMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		s.Range(yield)
+	}
+}
+
+// 返回key的迭代器
+func (s *SwissMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		s.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+// 返回value的迭代器
+func (s *SwissMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		s.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package rhashmap
+
+// apache 2.0 antlabs
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SwissMap_SetGet(t *testing.T) {
+	m := NewSwissMap[string, int]()
+	max := 10000
+	for i := 0; i < max; i++ {
+		_, replaced := m.SetWithPrev(fmt.Sprint(i), i)
+		assert.False(t, replaced)
+	}
+	assert.Equal(t, m.Len(), max)
+
+	for i := 0; i < max; i++ {
+		v, ok := m.GetWithBool(fmt.Sprint(i))
+		assert.True(t, ok)
+		assert.Equal(t, v, i)
+	}
+
+	prev, replaced := m.SetWithPrev("1", 100)
+	assert.True(t, replaced)
+	assert.Equal(t, prev, 1)
+	assert.Equal(t, m.Get("1"), 100)
+	assert.Equal(t, m.Len(), max)
+
+	_, ok := m.GetWithBool("not found")
+	assert.False(t, ok)
+}
+
+// 零值也可以使用
+func Test_SwissMap_Zero(t *testing.T) {
+	var m SwissMap[int, int]
+	_, ok := m.GetWithBool(1)
+	assert.False(t, ok)
+	m.Delete(1)
+
+	m.Set(1, 1)
+	assert.Equal(t, m.Get(1), 1)
+	assert.Equal(t, m.Len(), 1)
+}
+
+// 和map对比, 随机插入删除, 会产生墓碑
+func Test_SwissMap_Random(t *testing.T) {
+	m := NewSwissMap[int, int](WithCap(16))
+	std := make(map[int]int)
+	for i := 0; i < 100000; i++ {
+		k := rand.Intn(2000)
+		switch rand.Intn(3) {
+		case 0:
+			m.Delete(k)
+			delete(std, k)
+		default:
+			m.Set(k, i)
+			std[k] = i
+		}
+	}
+
+	assert.Equal(t, m.Len(), len(std))
+	for k, v := range std {
+		got, ok := m.GetWithBool(k)
+		assert.True(t, ok)
+		assert.Equal(t, got, v)
+	}
+
+	got := make(map[int]int)
+	m.Range(func(k, v int) bool {
+		got[k] = v
+		return true
+	})
+	assert.Equal(t, got, std)
+}
+
+func Test_SwissMap_Range(t *testing.T) {
+	m := NewSwissMap[int, int]()
+	for i := 0; i < 100; i++ {
+		m.Set(i, i)
+	}
+
+	var keys []int
+	for k := range m.Keys() {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	assert.Equal(t, len(keys), 100)
+	assert.Equal(t, keys[0], 0)
+	assert.Equal(t, keys[99], 99)
+
+	// callback 返回false就停止遍历
+	n := 0
+	m.Range(func(k, v int) bool {
+		n++
+		return n < 10
+	})
+	assert.Equal(t, n, 10)
+
+	// 遍历的时候删除
+	m.Range(func(k, v int) bool {
+		m.Delete(k)
+		m.Delete(k + 1)
+		return true
+	})
+	assert.Equal(t, m.Len(), 0)
+}
+
+// 删除之后插入会复用墓碑, 长度不变的情况下不会一直扩容
+func Test_SwissMap_Tombstone(t *testing.T) {
+	m := NewSwissMap[int, int](WithCap(64))
+	groups := len(m.groups)
+	for i := 0; i < 100000; i++ {
+		m.Set(i, i)
+		if i >= 32 {
+			m.Delete(i - 32)
+		}
+	}
+	assert.Equal(t, m.Len(), 32)
+	assert.Equal(t, len(m.groups), groups)
+}
+
+func Test_SwissMap_StructKey(t *testing.T) {
+	m := NewSwissMap[structKey, int]()
+	for i := 0; i < 1000; i++ {
+		m.Set(structKey{name: fmt.Sprint("name", i), id: i}, i)
+	}
+	for i := 0; i < 1000; i++ {
+		assert.Equal(t, m.Get(structKey{name: fmt.Sprint("name", i), id: i}), i)
+	}
+}
+
+// swiss table不支持有序模式, 不能让调用者以为拿到了插入顺序
+func Test_SwissMap_WithOrder(t *testing.T) {
+	assert.PanicsWithValue(t, "rhashmap: SwissMap does not support WithOrder", func() {
+		NewSwissMap[int, int](WithOrder(InsertionOrder))
+	})
+	assert.PanicsWithValue(t, "rhashmap: SwissMap does not support WithOrder", func() {
+		NewSwissMap[int, int](WithOrder(AccessOrder))
+	})
+	assert.NotPanics(t, func() { NewSwissMap[int, int](WithOrder(NoOrder)) })
+}
@@ -3,6 +3,8 @@
 package rwmap
 
 import (
+	"context"
+	"iter"
 	"sync"
 
 	"github.com/antlabs/gstl/api"
@@ -16,7 +18,7 @@ type Pair[K comparable, V any] struct {
 	Val V
 }
 
-var _ api.CMaper[int, int] = (*RWMap[int, int])(nil)
+var _ api.AtomicCMaper[int, int] = (*RWMap[int, int])(nil)
 
 type RWMap[K comparable, V any] struct {
 	rw sync.RWMutex
@@ -89,20 +91,59 @@ func (r *RWMap[K, V]) Range(f func(key K, value V) bool) {
 	r.rw.RUnlock()
 }
 
+// 返回所有元素的channel, 基于Snapshot实现, channel的容量和副本的长度一样, 返回之前已经写完并且关闭
+// 不会启动goroutine, 提前退出循环不会泄漏, 元素很多的时候使用IterContext或者SnapshotIter
 func (r *RWMap[K, V]) Iter() <-chan Pair[K, V] {
+	snap := r.Snapshot()
+	p := make(chan Pair[K, V], len(snap))
+	for _, pair := range snap {
+		p <- pair
+	}
+	close(p)
+	return p
+}
+
+// 返回所有元素的channel, 先复制一份再发送, 发送的时候不持有锁
+// 提前退出循环时调用cancel, 后台的goroutine会退出并且关闭channel
+// 提前退出循环又没有调用cancel的话, 后台的goroutine会一直阻塞在发送上, 造成泄漏
+func (r *RWMap[K, V]) IterContext(ctx context.Context) <-chan Pair[K, V] {
 	p := make(chan Pair[K, V])
 	go func() {
-		r.rw.RLock()
-		for k, v := range r.m {
-			p <- Pair[K, V]{Key: k, Val: v}
+		defer close(p)
+		for _, pair := range r.Snapshot() {
+			select {
+			case p <- pair:
+			case <-ctx.Done():
+				return
+			}
 		}
-		close(p)
-		r.rw.RUnlock()
-
 	}()
 	return p
 }
 
+// 返回所有元素的副本
+func (r *RWMap[K, V]) Snapshot() []Pair[K, V] {
+	r.rw.RLock()
+	all := make([]Pair[K, V], 0, len(r.m))
+	for k, v := range r.m {
+		all = append(all, Pair[K, V]{Key: k, Val: v})
+	}
+	r.rw.RUnlock()
+	return all
+}
+
+// 返回key和value的迭代器, 先复制一份, yield的时候不持有锁
+// 和All不同的是循环体里面可以修改map
+func (r *RWMap[K, V]) SnapshotIter() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, p := range r.Snapshot() {
+			if !yield(p.Key, p.Val) {
+				return
+			}
+		}
+	}
+}
+
 // 保存值
 func (r *RWMap[K, V]) Store(key K, value V) {
 	r.rw.Lock()
@@ -146,3 +187,130 @@ func (r *RWMap[K, V]) Len() (l int) {
 	r.rw.RUnlock()
 	return
 }
+
+// 返回key和value的迭代器, 可以配合for range使用
+// 和Range一样, 遍历的过程中持有读锁, 循环体里面不能修改map
+func (r *RWMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		r.Range(yield)
+	}
+}
+
+// 返回key的迭代器, 和All一样遍历的过程中持有读锁
+// Keys已经返回[]K, 为了不破坏已有的调用, 迭代器版本叫KeysSeq
+func (r *RWMap[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		r.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+// 返回value的迭代器, 和All一样遍历的过程中持有读锁
+// Values已经返回[]V, 为了不破坏已有的调用, 迭代器版本叫ValuesSeq
+func (r *RWMap[K, V]) ValuesSeq() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		r.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
+
+// 在写锁里面调用fn, 用fn的返回值更新key, delete为true时删除key
+// 返回保存的值和key是否存在, fn里面不能再访问RWMap
+func (r *RWMap[K, V]) Compute(key K, fn func(old V, loaded bool) (newValue V, delete bool)) (actual V, ok bool) {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+
+	old, loaded := r.m[key]
+	newValue, del := fn(old, loaded)
+	if del {
+		delete(r.m, key)
+		return
+	}
+
+	if r.m == nil {
+		r.m = make(map[K]V)
+	}
+	r.m[key] = newValue
+	return newValue, true
+}
+
+// key存在时在写锁里面调用fn, 用fn的返回值更新key
+func (r *RWMap[K, V]) Update(key K, fn func(old V) V) (newValue V, ok bool) {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+
+	old, ok := r.m[key]
+	if !ok {
+		return
+	}
+
+	newValue = fn(old)
+	r.m[key] = newValue
+	return newValue, true
+}
+
+// 保存新值, 返回以前的值
+func (r *RWMap[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	r.rw.Lock()
+	if r.m == nil {
+		r.m = make(map[K]V)
+	}
+	previous, loaded = r.m[key]
+	r.m[key] = value
+	r.rw.Unlock()
+	return
+}
+
+// 当前值等于old时替换成new, 和sync.Map一样, V的动态类型必须是可比较的, 否则会panic
+func (r *RWMap[K, V]) CompareAndSwap(key K, old, new V) (swapped bool) {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+
+	cur, ok := r.m[key]
+	if !ok || any(cur) != any(old) {
+		return false
+	}
+
+	r.m[key] = new
+	return true
+}
+
+// 当前值等于old时删除, 和sync.Map一样, V的动态类型必须是可比较的, 否则会panic
+func (r *RWMap[K, V]) CompareAndDelete(key K, old V) (deleted bool) {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+
+	cur, ok := r.m[key]
+	if !ok || any(cur) != any(old) {
+		return false
+	}
+
+	delete(r.m, key)
+	return true
+}
+
+// key存在返回现有的值, 不存在时在写锁里面调用fn, 保存fn的返回值
+// 适合创建成本比较高的值, 并发调用时fn只会执行一次
+func (r *RWMap[K, V]) LoadOrCompute(key K, fn func() V) (actual V, loaded bool) {
+	r.rw.RLock()
+	actual, loaded = r.m[key]
+	r.rw.RUnlock()
+	if loaded {
+		return
+	}
+
+	r.rw.Lock()
+	defer r.rw.Unlock()
+	if actual, loaded = r.m[key]; loaded {
+		return
+	}
+
+	if r.m == nil {
+		r.m = make(map[K]V)
+	}
+	actual = fn()
+	r.m[key] = actual
+	return actual, false
+}
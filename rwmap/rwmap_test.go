@@ -1,7 +1,9 @@
 package rwmap
 
 import (
+	"context"
 	"fmt"
+	"runtime"
 	"sort"
 	"sync"
 	"testing"
@@ -182,3 +184,237 @@ func Test_Values(t *testing.T) {
 	var m2 RWMap[string, string]
 	assert.Equal(t, len(m2.Keys()), 0)
 }
+
+// 测试range over func
+func Test_All(t *testing.T) {
+	m := New[int, int](0)
+	max := 100
+	for i := 0; i < max; i++ {
+		m.Store(i, i*10)
+	}
+
+	all := map[int]int{}
+	for k, v := range m.All() {
+		all[k] = v
+	}
+	assert.Equal(t, len(all), max)
+	for k, v := range all {
+		assert.Equal(t, v, k*10)
+	}
+
+	n := 0
+	for range m.All() {
+		n++
+		if n == 3 {
+			break
+		}
+	}
+	assert.Equal(t, n, 3)
+}
+
+// 测试KeysSeq和ValuesSeq
+func Test_KeysSeq_ValuesSeq(t *testing.T) {
+	m := New[int, int](0)
+	max := 100
+	for i := 0; i < max; i++ {
+		m.Store(i, i*10)
+	}
+
+	var keys, values []int
+	for k := range m.KeysSeq() {
+		keys = append(keys, k)
+	}
+	for v := range m.ValuesSeq() {
+		values = append(values, v)
+	}
+	sort.Ints(keys)
+	sort.Ints(values)
+	for i := 0; i < max; i++ {
+		assert.Equal(t, keys[i], i)
+		assert.Equal(t, values[i], i*10)
+	}
+
+	n := 0
+	for range m.KeysSeq() {
+		n++
+		if n == 3 {
+			break
+		}
+	}
+	assert.Equal(t, n, 3)
+}
+
+func Test_Compute(t *testing.T) {
+	m := New[string, int](0)
+	// 不存在的时候新建
+	v, ok := m.Compute("a", func(old int, loaded bool) (int, bool) {
+		assert.False(t, loaded)
+		return old + 1, false
+	})
+	assert.True(t, ok)
+	assert.Equal(t, v, 1)
+
+	v, ok = m.Compute("a", func(old int, loaded bool) (int, bool) {
+		assert.True(t, loaded)
+		return old + 1, false
+	})
+	assert.True(t, ok)
+	assert.Equal(t, v, 2)
+
+	// 删除
+	_, ok = m.Compute("a", func(old int, loaded bool) (int, bool) {
+		return 0, true
+	})
+	assert.False(t, ok)
+	_, ok = m.Load("a")
+	assert.False(t, ok)
+
+	_, ok = m.Update("a", func(old int) int { return old + 1 })
+	assert.False(t, ok)
+	m.Store("a", 1)
+	v, ok = m.Update("a", func(old int) int { return old + 1 })
+	assert.True(t, ok)
+	assert.Equal(t, v, 2)
+}
+
+func Test_SwapAndCompare(t *testing.T) {
+	m := New[string, int](0)
+	prev, loaded := m.Swap("a", 1)
+	assert.False(t, loaded)
+	assert.Equal(t, prev, 0)
+
+	prev, loaded = m.Swap("a", 2)
+	assert.True(t, loaded)
+	assert.Equal(t, prev, 1)
+
+	assert.False(t, m.CompareAndSwap("a", 1, 3))
+	assert.False(t, m.CompareAndSwap("b", 0, 3))
+	assert.True(t, m.CompareAndSwap("a", 2, 3))
+	v, _ := m.Load("a")
+	assert.Equal(t, v, 3)
+
+	assert.False(t, m.CompareAndDelete("a", 2))
+	assert.True(t, m.CompareAndDelete("a", 3))
+	_, ok := m.Load("a")
+	assert.False(t, ok)
+}
+
+// 并发调用时fn只执行一次, 计数不会丢失
+func Test_LoadOrCompute_Concurrent(t *testing.T) {
+	m := New[int, int](0)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	calls := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.LoadOrCompute(j, func() int {
+					mu.Lock()
+					calls++
+					mu.Unlock()
+					return j
+				})
+				m.Compute(-1, func(old int, _ bool) (int, bool) {
+					return old + 1, false
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, calls, 1000)
+	v, _ := m.Load(-1)
+	assert.Equal(t, v, 8000)
+	v, loaded := m.LoadOrCompute(10, func() int { return -10 })
+	assert.True(t, loaded)
+	assert.Equal(t, v, 10)
+}
+
+// 提前退出循环, 调用cancel之后后台goroutine退出, 写操作不会被阻塞
+func Test_IterContext(t *testing.T) {
+	m := New[int, int](0)
+	max := 100
+	for i := 0; i < max; i++ {
+		m.Store(i, i)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := m.IterContext(ctx)
+	<-ch
+	cancel()
+
+	// 没有读完的时候也可以写
+	m.Store(max, max)
+	for range ch {
+	}
+
+	// Iter提前退出也不会阻塞写
+	for range m.Iter() {
+		break
+	}
+	m.Store(max+1, max+1)
+	assert.Equal(t, m.Len(), max+2)
+
+	got := map[int]int{}
+	for p := range m.IterContext(context.Background()) {
+		got[p.Key] = p.Val
+	}
+	assert.Equal(t, len(got), max+2)
+}
+
+// Iter提前退出循环, 不会留下goroutine
+func Test_IterEarlyBreak(t *testing.T) {
+	m := New[int, int](0)
+	for i := 0; i < 100; i++ {
+		m.Store(i, i)
+	}
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		for range m.Iter() {
+			break
+		}
+	}
+	assert.Equal(t, runtime.NumGoroutine(), before)
+
+	n := 0
+	for range m.Iter() {
+		n++
+	}
+	assert.Equal(t, n, 100)
+}
+
+func Test_Snapshot(t *testing.T) {
+	m := New[int, int](0)
+	max := 100
+	for i := 0; i < max; i++ {
+		m.Store(i, i)
+	}
+
+	pairs := m.Snapshot()
+	assert.Equal(t, len(pairs), max)
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	for i, p := range pairs {
+		assert.Equal(t, p, Pair[int, int]{Key: i, Val: i})
+	}
+
+	// 循环体里面可以修改map
+	n := 0
+	for k, v := range m.SnapshotIter() {
+		assert.Equal(t, k, v)
+		m.Delete(k)
+		m.Store(k+max, k)
+		n++
+	}
+	assert.Equal(t, n, max)
+	assert.Equal(t, m.Len(), max)
+
+	n = 0
+	for range m.SnapshotIter() {
+		n++
+		break
+	}
+	assert.Equal(t, n, 1)
+}
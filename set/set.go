@@ -2,42 +2,65 @@ package set
 
 // apache 2.0 antlabs
 import (
+	"iter"
+
 	"github.com/antlabs/gstl/api"
+	"github.com/antlabs/gstl/cmp"
 	"github.com/antlabs/gstl/rbtree"
 	"golang.org/x/exp/constraints"
 )
 
+// 有序集合, K必须是有序类型, 必须使用New或者From创建, 零值不能使用
+// 其他类型的元素(比如[]byte, time.Time, 结构体)使用NewWithCompare创建SetFunc
 type Set[K constraints.Ordered] struct {
+	SetFunc[K]
+}
+
+// 使用比较函数的有序集合, K可以是任意类型, 必须使用NewWithCompare创建, 零值不能使用
+type SetFunc[K any] struct {
 	api.SortedMap[K, struct{}]
+	// 元素的比较函数, 创建新集合时使用
+	compare func(a, b K) int
 }
 
 // 创建一个空的slice
 func New[K constraints.Ordered]() *Set[K] {
+	return &Set[K]{SetFunc: *NewWithCompare(cmp.Compare[K])}
+}
+
+// 使用自定义的比较函数创建一个空的集合, 元素可以是任意类型
+// compare(a, b) a < b 返回负数, a == b 返回0, a > b 返回正数
+func NewWithCompare[K any](compare func(a, b K) int) *SetFunc[K] {
 	// 随手使用rbtree，后面压测再决定使用
-	return &Set[K]{SortedMap: rbtree.New[K, struct{}]()}
+	return &SetFunc[K]{SortedMap: rbtree.NewWithCompare[K, struct{}](compare), compare: compare}
+}
+
+// 创建一个和s使用相同比较函数的空集合
+func (s *SetFunc[K]) newSet() *SetFunc[K] {
+	return NewWithCompare(s.compare)
 }
 
 // 从slice创建set
 func From[K constraints.Ordered](s ...K) *Set[K] {
-	var b rbtree.RBTree[K, struct{}]
+	b := New[K]()
 	for _, v := range s {
-		b.Set(v, struct{}{})
+		b.Set(v)
 	}
 
-	return &Set[K]{SortedMap: &b}
+	return b
 }
 
 // 给集合添加元素
-func (s *Set[K]) Set(k K) {
+func (s *SetFunc[K]) Set(k K) {
 	s.SortedMap.Set(k, struct{}{})
 }
 
 // 返回集合中元素的个数
-func (s *Set[K]) Len() int {
+func (s *SetFunc[K]) Len() int {
 	return s.SortedMap.Len()
 }
 
-func (s *Set[K]) ToSlice() (new []K) {
+func (s *SetFunc[K]) ToSlice() (new []K) {
 	new = make([]K, 0, s.Len())
 	s.Range(func(k K) bool {
 		new = append(new, k)
@@ -47,8 +70,8 @@ func (s *Set[K]) ToSlice() (new []K) {
 }
 
 // 深度复制一个集合
-func (s *Set[K]) Clone() (new *Set[K]) {
-	new = New[K]()
+func (s *SetFunc[K]) Clone() (new *SetFunc[K]) {
+	new = s.newSet()
 	s.Range(func(k K) bool {
 		new.Set(k)
 		return true
@@ -57,15 +80,15 @@ func (s *Set[K]) Clone() (new *Set[K]) {
 }
 
 // 测试k是否在集合中
-func (s *Set[K]) IsMember(k K) (b bool) {
+func (s *SetFunc[K]) IsMember(k K) (b bool) {
 	_, b = s.GetWithBool(k)
 	return
 }
 
 // 返回的是s1没有的元素, s - s1
-func (s *Set[K]) Diff(s1 *Set[K]) (new *Set[K]) {
+func (s *SetFunc[K]) Diff(s1 *SetFunc[K]) (new *SetFunc[K]) {
 
-	new = New[K]()
+	new = s.newSet()
 	s.Range(func(k K) bool {
 		if !s1.IsMember(k) {
 			new.Set(k)
@@ -76,9 +99,9 @@ func (s *Set[K]) Diff(s1 *Set[K]) (new *Set[K]) {
 }
 
 // 返回两个集合的所有元素
-func (s *Set[K]) Union(sets ...*Set[K]) (new *Set[K]) {
+func (s *SetFunc[K]) Union(sets ...*SetFunc[K]) (new *SetFunc[K]) {
 
-	new = New[K]()
+	new = s.newSet()
 	s.Range(func(k K) bool {
 		new.Set(k)
 		return true
@@ -95,12 +118,12 @@ func (s *Set[K]) Union(sets ...*Set[K]) (new *Set[K]) {
 }
 
 // 返回两个集合的公共集合
-func (s *Set[K]) Intersection(s1 *Set[K]) (new *Set[K]) {
+func (s *SetFunc[K]) Intersection(s1 *SetFunc[K]) (new *SetFunc[K]) {
 	if s.Len() >= s1.Len() {
 		s, s1 = s1, s
 	}
 
-	new = New[K]()
+	new = s.newSet()
 	s.Range(func(k K) bool {
 		if s1.IsMember(k) {
 			new.Set(k)
@@ -111,7 +134,7 @@ func (s *Set[K]) Intersection(s1 *Set[K]) (new *Set[K]) {
 }
 
 // 测试集合s每个元素是否在s1里面, s <= s1
-func (s *Set[K]) IsSubset(s1 *Set[K]) (b bool) {
+func (s *SetFunc[K]) IsSubset(s1 *SetFunc[K]) (b bool) {
 	if s.Len() > s1.Len() {
 		return false
 	}
@@ -128,19 +151,19 @@ func (s *Set[K]) IsSubset(s1 *Set[K]) (b bool) {
 }
 
 // 测试集合s1每个元素是否在s里面 s1 <= s
-func (s *Set[K]) IsSuperset(s1 *Set[K]) (b bool) {
+func (s *SetFunc[K]) IsSuperset(s1 *SetFunc[K]) (b bool) {
 	return s1.IsSubset(s)
 }
 
 // 遍历
-func (s *Set[K]) Range(cb func(k K) bool) {
+func (s *SetFunc[K]) Range(cb func(k K) bool) {
 	s.SortedMap.Range(func(k K, _ struct{}) bool {
 		return cb(k)
 	})
 }
 
 // 两个集合是否相等
-func (s *Set[K]) Equal(s1 *Set[K]) (b bool) {
+func (s *SetFunc[K]) Equal(s1 *SetFunc[K]) (b bool) {
 	if s.Len() != s1.Len() {
 		return false
 	}
@@ -153,3 +176,58 @@ func (s *Set[K]) Equal(s1 *Set[K]) (b bool) {
 
 	return
 }
+
+// 返回集合元素的迭代器, 从小到大, 可以配合for range使用
+func (s *SetFunc[K]) All() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		s.Range(yield)
+	}
+}
+
+// 返回集合元素的迭代器, 从大到小
+func (s *SetFunc[K]) Backward() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		s.SortedMap.TopMax(s.Len(), func(k K, _ struct{}) bool {
+			return yield(k)
+		})
+	}
+}
+
+// 深度复制一个集合
+func (s *Set[K]) Clone() *Set[K] {
+	return &Set[K]{SetFunc: *s.SetFunc.Clone()}
+}
+
+// 返回的是s1没有的元素, s - s1
+func (s *Set[K]) Diff(s1 *Set[K]) *Set[K] {
+	return &Set[K]{SetFunc: *s.SetFunc.Diff(&s1.SetFunc)}
+}
+
+// 返回两个集合的所有元素
+func (s *Set[K]) Union(sets ...*Set[K]) *Set[K] {
+	funcSets := make([]*SetFunc[K], len(sets))
+	for i, s1 := range sets {
+		funcSets[i] = &s1.SetFunc
+	}
+	return &Set[K]{SetFunc: *s.SetFunc.Union(funcSets...)}
+}
+
+// 返回两个集合的公共集合
+func (s *Set[K]) Intersection(s1 *Set[K]) *Set[K] {
+	return &Set[K]{SetFunc: *s.SetFunc.Intersection(&s1.SetFunc)}
+}
+
+// 测试集合s每个元素是否在s1里面, s <= s1
+func (s *Set[K]) IsSubset(s1 *Set[K]) bool {
+	return s.SetFunc.IsSubset(&s1.SetFunc)
+}
+
+// 测试集合s1每个元素是否在s里面 s1 <= s
+func (s *Set[K]) IsSuperset(s1 *Set[K]) bool {
+	return s.SetFunc.IsSuperset(&s1.SetFunc)
+}
+
+// 两个集合是否相等
+func (s *Set[K]) Equal(s1 *Set[K]) bool {
+	return s.SetFunc.Equal(&s1.SetFunc)
+}
@@ -2,6 +2,7 @@ package set
 
 // apache 2.0 antlabs
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -177,3 +178,42 @@ func Test_IsSuperset_Not2(t *testing.T) {
 
 	assert.False(t, s.IsSuperset(s2))
 }
+
+// 测试range over func
+func Test_Set_Iter(t *testing.T) {
+	s := From(3, 1, 2)
+
+	var got []int
+	for k := range s.All() {
+		got = append(got, k)
+	}
+	assert.Equal(t, got, []int{1, 2, 3})
+
+	got = got[:0]
+	for k := range s.Backward() {
+		if k == 1 {
+			break
+		}
+		got = append(got, k)
+	}
+	assert.Equal(t, got, []int{3, 2})
+}
+
+// 元素是[]byte, 使用自定义的比较函数
+func Test_Set_NewWithCompare(t *testing.T) {
+	s := NewWithCompare(bytes.Compare)
+	s.Set([]byte("b"))
+	s.Set([]byte("a"))
+	s.Set([]byte("c"))
+	s.Set([]byte("a"))
+	assert.Equal(t, s.Len(), 3)
+	assert.True(t, s.IsMember([]byte("b")))
+
+	s1 := NewWithCompare(bytes.Compare)
+	s1.Set([]byte("b"))
+	s1.Set([]byte("d"))
+
+	assert.Equal(t, s.Union(s1).ToSlice(), [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")})
+	assert.Equal(t, s.Intersection(s1).ToSlice(), [][]byte{[]byte("b")})
+	assert.Equal(t, s.Diff(s1).ToSlice(), [][]byte{[]byte("a"), []byte("c")})
+}
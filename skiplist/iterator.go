@@ -0,0 +1,84 @@
+package skiplist
+
+// apache 2.0 antlabs
+import (
+	"github.com/antlabs/gstl/api"
+)
+
+var _ api.Iterator[int, int] = (*Iterator[int, int])(nil)
+
+// SkipList的迭代器, 可以暂停, 恢复, 双向移动
+// 迭代器创建之后如果SkipList被修改(新加或者删除元素), 迭代器不会失效,
+// 下次调用Next/Prev时会根据当前的score重新定位到它的后继/前驱(O(log n)), 可以看到修改后的数据.
+// zset模式下score可以重复, 这时按(score, elem)重新定位, 不会跳过score相同的元素.
+// 如果当前元素已经被删除, Key/Value返回的还是删除前的数据
+type Iterator[K any, T any] struct {
+	s       *SkipListFunc[K, T]
+	node    *Node[K, T]
+	version int
+}
+
+// 创建一个迭代器, 使用之前需要先调用Seek, First或者Last定位
+func (s *SkipListFunc[K, T]) Iterator() *Iterator[K, T] {
+	return &Iterator[K, T]{s: s}
+}
+
+func (it *Iterator[K, T]) setNode(x *Node[K, T]) bool {
+	it.node = x
+	it.version = it.s.version
+	return x != nil
+}
+
+// 定位到第一个大于等于score的元素
+func (it *Iterator[K, T]) Seek(score K) bool {
+	return it.setNode(it.s.firstInRange(score))
+}
+
+// 定位到第一个元素
+func (it *Iterator[K, T]) First() bool {
+	return it.setNode(it.s.head.NodeLevel[0].forward)
+}
+
+// 定位到最后一个元素
+func (it *Iterator[K, T]) Last() bool {
+	return it.setNode(it.s.tail)
+}
+
+// 移动到下一个元素, 没有下一个元素返回false
+func (it *Iterator[K, T]) Next() bool {
+	if it.node == nil {
+		return false
+	}
+
+	if it.version != it.s.version {
+		return it.setNode(it.s.afterNode(it.node.score, it.node.elem))
+	}
+	return it.setNode(it.node.NodeLevel[0].forward)
+}
+
+// 移动到上一个元素, 没有上一个元素返回false
+func (it *Iterator[K, T]) Prev() bool {
+	if it.node == nil {
+		return false
+	}
+
+	if it.version != it.s.version {
+		return it.setNode(it.s.beforeNode(it.node.score, it.node.elem))
+	}
+	return it.setNode(it.node.backward)
+}
+
+// 是否指向一个元素
+func (it *Iterator[K, T]) Valid() bool {
+	return it.node != nil
+}
+
+// 返回当前元素的score, 调用之前需要保证Valid()为true
+func (it *Iterator[K, T]) Key() K {
+	return it.node.score
+}
+
+// 返回当前元素的value, 调用之前需要保证Valid()为true
+func (it *Iterator[K, T]) Value() T {
+	return it.node.elem
+}
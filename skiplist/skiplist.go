@@ -4,47 +4,23 @@ package skiplist
 
 // 参考文档如下
 // https://github.com/redis/redis/blob/unstable/src/t_zset.c
-// https://redis.io/commands/zcount/
-// ZADD
-// ZCARD
-// ZCOUNT
-// ZDIFFSTORE
-// ZINCRBY
-// ZINTER
-// ZINTERCARD
-// ZINTERSTORE
-// ZLEXCOUNT
-// ZMPOP
-// ZMSCORE
-// ZPOPMAX
-// ZPOPMIN
-// ZRANDMEMBER
-// ZRANGE
-// ZRANGEBYLEX
-// ZRANGEBYSCORE
-// ZRANGESTORE
-// ZRANK
-// ZREMRANGEBYLEX
-// ZREMRANGEBYRANK
-// ZREMRANGEBYSCORE
-// ZREVRANGE
-// ZREVRANGEBYLEX
-// ZREVRANGEBYSCORE
-// ZREVRANK
-// ZSCAN
-// ZUNION
-// ZUNIONSTORE
+// redis的zset命令由zset.go里面的ZSet实现, SkipList只提供按score排序和按排名查找
 import (
 	"errors"
 	"fmt"
+	"iter"
 	"math/rand"
 	"time"
 
 	"github.com/antlabs/gstl/api"
+	"github.com/antlabs/gstl/cmp"
 	"golang.org/x/exp/constraints"
 )
 
-var _ api.SortedMap[float64, float64] = (*SkipList[float64, float64])(nil)
+var (
+	_ api.SortedMap[float64, float64] = (*SkipList[float64, float64])(nil)
+	_ api.SortedMap[[]byte, float64]  = (*SkipListFunc[[]byte, float64])(nil)
+)
 
 const (
 	SKIPLIST_MAXLEVEL = 32
@@ -54,7 +30,7 @@ var (
 	ErrNotFound = errors.New("not found element")
 )
 
-type Node[K constraints.Ordered, T any] struct {
+type Node[K any, T any] struct {
 	score K
 	elem  T
 	// 后退指针
@@ -66,37 +42,55 @@ type Node[K constraints.Ordered, T any] struct {
 	}
 }
 
+// 跳表, 按score(K)从小到大排序, K必须是有序类型, 必须使用New创建, 零值不能使用
+// 其他类型的score(比如[]byte, time.Time, 结构体)使用NewWithCompare创建SkipListFunc
 type SkipList[K constraints.Ordered, T any] struct {
+	SkipListFunc[K, T]
+}
+
+// 使用比较函数的跳表, K可以是任意类型, 必须使用NewWithCompare创建, 零值不能使用
+type SkipListFunc[K any, T any] struct {
 	head *Node[K, T]
 	tail *Node[K, T]
 
 	r      *rand.Rand
 	length int
 	level  int
+	// 每次新加或者删除节点都会加1, 迭代器用它判断skiplist是否被修改过
+	version int
 
-	//compare func(T, T) int
+	// score的比较函数
+	compareKey func(a, b K) int
+	// 不为nil时, score相同的元素按elem排序, 可以保存重复的score(zset使用)
+	compareElem func(T, T) int
 }
 
 // 初始化skiplist
 // func New[T any](compare func(T, T) int) *SkipList[T] {
 func New[K constraints.Ordered, T any]() *SkipList[K, T] {
-	s := &SkipList[K, T]{
-		level: 1,
+	return &SkipList[K, T]{SkipListFunc: *NewWithCompare[K, T](cmp.Compare[K])}
+}
+
+// 使用自定义的比较函数初始化, score可以是任意类型
+// compare(a, b) a < b 返回负数, a == b 返回0, a > b 返回正数
+func NewWithCompare[K any, T any](compare func(a, b K) int) *SkipListFunc[K, T] {
+	s := &SkipListFunc[K, T]{
+		level:      1,
+		compareKey: compare,
 	}
 
-	//s.compare = compare
 	var score K
 	s.resetRand()
 	s.head = newNode(SKIPLIST_MAXLEVEL, score, *new(T))
 	return s
 }
 
-func (s *SkipList[K, T]) resetRand() {
+func (s *SkipListFunc[K, T]) resetRand() {
 
 	s.r = rand.New(rand.NewSource(time.Now().UnixNano()))
 }
 
-func (s *SkipList[K, T]) rand() int {
+func (s *SkipListFunc[K, T]) rand() int {
 	level := 1
 	for {
 
@@ -113,7 +107,7 @@ func (s *SkipList[K, T]) rand() int {
 	return SKIPLIST_MAXLEVEL
 }
 
-func newNode[K constraints.Ordered, T any](level int, score K, elem T) *Node[K, T] {
+func newNode[K any, T any](level int, score K, elem T) *Node[K, T] {
 	return &Node[K, T]{
 		score: score,
 		elem:  elem,
@@ -125,21 +119,21 @@ func newNode[K constraints.Ordered, T any](level int, score K, elem T) *Node[K,
 }
 
 // 设置值, 和Insert是同义词
-func (s *SkipList[K, T]) Set(score K, elem T) {
+func (s *SkipListFunc[K, T]) Set(score K, elem T) {
 	s.InsertInner(score, elem, s.rand())
 }
 
-func (s *SkipList[K, T]) SetWithPrev(score K, elem T) (prev T, replaced bool) {
+func (s *SkipListFunc[K, T]) SetWithPrev(score K, elem T) (prev T, replaced bool) {
 	return s.InsertInner(score, elem, s.rand())
 }
 
 // 设置值
-func (s *SkipList[K, T]) Insert(score K, elem T) {
+func (s *SkipListFunc[K, T]) Insert(score K, elem T) {
 	s.InsertInner(score, elem, s.rand())
 }
 
 // 方便给作者调试用的函数
-func (s *SkipList[K, T]) InsertInner(score K, elem T, level int) (prev T, replaced bool) {
+func (s *SkipListFunc[K, T]) InsertInner(score K, elem T, level int) (prev T, replaced bool) {
 	var (
 		update [SKIPLIST_MAXLEVEL]*Node[K, T]
 		rank   [SKIPLIST_MAXLEVEL]int
@@ -155,12 +149,9 @@ func (s *SkipList[K, T]) InsertInner(score K, elem T, level int) (prev T, replac
 		}
 
 		for x.NodeLevel[i].forward != nil &&
-			(x.NodeLevel[i].forward.score < score) {
-			// 暂时不支持重复的key, 后面再说 TODO
-			//|| x.NodeLevel[i].forward.score == score &&
-			//s.compare(elem, x.NodeLevel[i].forward.elem) < 0) {
+			s.cmpNode(x.NodeLevel[i].forward, score, elem) < 0 {
 
-			//TODO span的含义是?
+			// span是当前节点到forward节点跨过的节点数
 			rank[i] += x.NodeLevel[i].span
 			x = x.NodeLevel[i].forward
 		}
@@ -171,12 +162,18 @@ func (s *SkipList[K, T]) InsertInner(score K, elem T, level int) (prev T, replac
 
 	// 这个score已经存在直接返回
 	x2 = x.NodeLevel[0].forward
-	if x2 != nil && score == x2.score {
+	if x2 != nil && s.cmpNode(x2, score, elem) == 0 {
 		prev = x2.elem
 		x2.elem = elem
 		return prev, true
 	}
 
+	s.insertNode(&update, &rank, level, score, elem)
+	return
+}
+
+// 在update[i]后面插入新节点, rank[i]是update[i]的排名
+func (s *SkipListFunc[K, T]) insertNode(update *[SKIPLIST_MAXLEVEL]*Node[K, T], rank *[SKIPLIST_MAXLEVEL]int, level int, score K, elem T) {
 	if level > s.level {
 		// 这次新的level与老的level的差值, 给填充head指针
 		for i := s.level; i < level; i++ {
@@ -189,7 +186,7 @@ func (s *SkipList[K, T]) InsertInner(score K, elem T, level int) (prev T, replac
 	}
 
 	// 创建新节点
-	x = newNode(level, score, elem)
+	x := newNode(level, score, elem)
 	for i := 0; i < level; i++ {
 		// x.NodeLevel[i]的节点假设等于a, 需要插入的节点x在a之后,
 		// a, x, a.forward三者的关系就是[a, x, a.forward]
@@ -218,15 +215,112 @@ func (s *SkipList[K, T]) InsertInner(score K, elem T, level int) (prev T, replac
 	}
 
 	s.length++
+	s.version++
+}
+
+// Get, Set, Delete是最常用的操作, K是有序类型时直接用==和<比较, 不经过比较函数
+// zset会设置compareElem, 插入时还要比较elem, 这时候走SkipListFunc的版本
+
+// 设置值, 和Insert是同义词
+func (s *SkipList[K, T]) Set(score K, elem T) {
+	s.InsertInner(score, elem, s.rand())
+}
+
+func (s *SkipList[K, T]) SetWithPrev(score K, elem T) (prev T, replaced bool) {
+	return s.InsertInner(score, elem, s.rand())
+}
+
+// 设置值
+func (s *SkipList[K, T]) Insert(score K, elem T) {
+	s.InsertInner(score, elem, s.rand())
+}
+
+// 方便给作者调试用的函数
+func (s *SkipList[K, T]) InsertInner(score K, elem T, level int) (prev T, replaced bool) {
+	if s.compareElem != nil {
+		return s.SkipListFunc.InsertInner(score, elem, level)
+	}
+
+	var (
+		update [SKIPLIST_MAXLEVEL]*Node[K, T]
+		rank   [SKIPLIST_MAXLEVEL]int
+	)
+
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		if i == s.level-1 {
+			rank[i] = 0
+		} else {
+			rank[i] = rank[i+1]
+		}
+
+		for x.NodeLevel[i].forward != nil && x.NodeLevel[i].forward.score < score {
+			rank[i] += x.NodeLevel[i].span
+			x = x.NodeLevel[i].forward
+		}
+		update[i] = x
+	}
+
+	// 这个score已经存在直接返回
+	x = x.NodeLevel[0].forward
+	if x != nil && x.score == score {
+		prev = x.elem
+		x.elem = elem
+		return prev, true
+	}
+
+	s.insertNode(&update, &rank, level, score, elem)
 	return
 }
 
+// 根据score获取value值
+func (s *SkipList[K, T]) Get(score K) (elem T) {
+	elem, _ = s.GetWithBool(score)
+	return elem
+}
+
 // 获取
 func (s *SkipList[K, T]) GetWithBool(score K) (elem T, ok bool) {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.NodeLevel[i].forward != nil && x.NodeLevel[i].forward.score < score {
+			x = x.NodeLevel[i].forward
+		}
+	}
+
+	x = x.NodeLevel[0].forward
+	if x != nil && x.score == score {
+		return x.elem, true
+	}
+
+	return
+}
+
+// 根据score删除
+func (s *SkipList[K, T]) Delete(score K) {
+	s.Remove(score)
+}
+
+// 比较节点x和(score, elem)的大小
+// 没有设置compare时只比较score
+func (s *SkipListFunc[K, T]) cmpNode(x *Node[K, T], score K, elem T) int {
+	if c := s.compareKey(x.score, score); c != 0 {
+		return c
+	}
+
+	if s.compareElem == nil {
+		return 0
+	}
+
+	return s.compareElem(x.elem, elem)
+}
+
+// 获取
+func (s *SkipListFunc[K, T]) GetWithBool(score K) (elem T, ok bool) {
 
 	x := s.head
 	for i := s.level - 1; i >= 0; i-- {
-		for x.NodeLevel[i].forward != nil && (x.NodeLevel[i].forward.score < score) {
+		for x.NodeLevel[i].forward != nil && (s.compareKey(x.NodeLevel[i].forward.score, score) < 0) {
 			x = x.NodeLevel[i].forward
 		}
 
@@ -248,7 +342,7 @@ func (s *SkipList[K, T]) GetWithBool(score K) (elem T, ok bool) {
 	}
 
 	x = x.NodeLevel[0].forward
-	if x != nil && score == x.score {
+	if x != nil && s.compareKey(score, x.score) == 0 {
 		return x.elem, true
 	}
 
@@ -256,7 +350,7 @@ func (s *SkipList[K, T]) GetWithBool(score K) (elem T, ok bool) {
 }
 
 // debug 使用
-type Number[K constraints.Ordered] struct {
+type Number[K any] struct {
 	Total    int
 	Keys     []K
 	Level    []int
@@ -264,7 +358,7 @@ type Number[K constraints.Ordered] struct {
 }
 
 // debug使用, 返回查找某个key 比较的次数+经过的节点数
-func (s *SkipList[K, T]) GetWithMeta(score K) (elem T, number Number[K], ok bool) {
+func (s *SkipListFunc[K, T]) GetWithMeta(score K) (elem T, number Number[K], ok bool) {
 
 	x := s.head
 	fmt.Println()
@@ -272,7 +366,7 @@ func (s *SkipList[K, T]) GetWithMeta(score K) (elem T, number Number[K], ok bool
 		if x.NodeLevel[i].forward != nil {
 			fmt.Printf("x.NodeLevel[%d].score:%v, score:%v\n", i, x.NodeLevel[i].forward.score, score)
 		}
-		for x.NodeLevel[i].forward != nil && (x.NodeLevel[i].forward.score < score) {
+		for x.NodeLevel[i].forward != nil && (s.compareKey(x.NodeLevel[i].forward.score, score) < 0) {
 			number.Total++
 			number.Keys = append(number.Keys, x.score)
 			number.Level = append(number.Level, i)
@@ -280,7 +374,7 @@ func (s *SkipList[K, T]) GetWithMeta(score K) (elem T, number Number[K], ok bool
 			x = x.NodeLevel[i].forward
 		}
 
-		if x != nil && x.score == score {
+		if x != nil && s.compareKey(x.score, score) == 0 {
 			elem = x.elem
 			return
 		}
@@ -294,7 +388,7 @@ func (s *SkipList[K, T]) GetWithMeta(score K) (elem T, number Number[K], ok bool
 	}
 
 	x = x.NodeLevel[0].forward
-	if x != nil && score == x.score {
+	if x != nil && s.compareKey(score, x.score) == 0 {
 		return x.elem, number, true
 	}
 
@@ -302,12 +396,12 @@ func (s *SkipList[K, T]) GetWithMeta(score K) (elem T, number Number[K], ok bool
 }
 
 // 根据score获取value值
-func (s *SkipList[K, T]) Get(score K) (elem T) {
+func (s *SkipListFunc[K, T]) Get(score K) (elem T) {
 	elem, _ = s.GetWithBool(score)
 	return elem
 }
 
-func (s *SkipList[K, T]) removeNode(x *Node[K, T], update []*Node[K, T]) {
+func (s *SkipListFunc[K, T]) removeNode(x *Node[K, T], update []*Node[K, T]) {
 	for i := 0; i < s.level; i++ {
 		if update[i].NodeLevel[i].forward == x {
 			update[i].NodeLevel[i].span += x.NodeLevel[i].span - 1
@@ -329,27 +423,47 @@ func (s *SkipList[K, T]) removeNode(x *Node[K, T], update []*Node[K, T]) {
 		s.level--
 	}
 	s.length--
+	s.version++
 }
 
 // 根据score删除
-func (s *SkipList[K, T]) Delete(score K) {
+func (s *SkipListFunc[K, T]) Delete(score K) {
 	s.Remove(score)
 }
 
 // 根据score删除元素
 func (s *SkipList[K, T]) Remove(score K) *SkipList[K, T] {
+	var update [SKIPLIST_MAXLEVEL]*Node[K, T]
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.NodeLevel[i].forward != nil && x.NodeLevel[i].forward.score < score {
+			x = x.NodeLevel[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.NodeLevel[0].forward
+	if x != nil && x.score == score {
+		s.removeNode(x, update[:])
+	}
+
+	return s
+}
+
+// 根据score删除元素
+func (s *SkipListFunc[K, T]) Remove(score K) *SkipListFunc[K, T] {
 
 	var update [SKIPLIST_MAXLEVEL]*Node[K, T]
 	x := s.head
 	for i := s.level - 1; i >= 0; i-- {
-		for x.NodeLevel[i].forward != nil && (x.NodeLevel[i].forward.score < score) {
+		for x.NodeLevel[i].forward != nil && (s.compareKey(x.NodeLevel[i].forward.score, score) < 0) {
 			x = x.NodeLevel[i].forward
 		}
 		update[i] = x
 	}
 
 	x = x.NodeLevel[0].forward
-	if x != nil && score == x.score {
+	if x != nil && s.compareKey(score, x.score) == 0 {
 		s.removeNode(x, update[:])
 		return s
 	}
@@ -357,7 +471,197 @@ func (s *SkipList[K, T]) Remove(score K) *SkipList[K, T] {
 	return s
 }
 
+// 根据score和elem删除元素, 对应redis的zslDelete
+func (s *SkipListFunc[K, T]) deleteElem(score K, elem T) bool {
+
+	var update [SKIPLIST_MAXLEVEL]*Node[K, T]
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.NodeLevel[i].forward != nil && s.cmpNode(x.NodeLevel[i].forward, score, elem) < 0 {
+			x = x.NodeLevel[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.NodeLevel[0].forward
+	if x != nil && s.cmpNode(x, score, elem) == 0 {
+		s.removeNode(x, update[:])
+		return true
+	}
+
+	return false
+}
+
+// 返回第一个score >= min的节点, 对应redis的zslFirstInRange
+func (s *SkipListFunc[K, T]) firstInRange(min K) *Node[K, T] {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.NodeLevel[i].forward != nil && s.compareKey(x.NodeLevel[i].forward.score, min) < 0 {
+			x = x.NodeLevel[i].forward
+		}
+	}
+
+	return x.NodeLevel[0].forward
+}
+
+// 返回最后一个score <= max的节点, 对应redis的zslLastInRange
+func (s *SkipListFunc[K, T]) lastInRange(max K) *Node[K, T] {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.NodeLevel[i].forward != nil && s.compareKey(x.NodeLevel[i].forward.score, max) <= 0 {
+			x = x.NodeLevel[i].forward
+		}
+	}
+
+	if x == s.head {
+		return nil
+	}
+	return x
+}
+
+// 返回(score, elem)的排名, 从1开始, 找不到返回0
+// 对应redis的zslGetRank
+func (s *SkipListFunc[K, T]) getRank(score K, elem T) int {
+	rank := 0
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.NodeLevel[i].forward != nil && s.cmpNode(x.NodeLevel[i].forward, score, elem) <= 0 {
+			rank += x.NodeLevel[i].span
+			x = x.NodeLevel[i].forward
+		}
+
+		if x != s.head && s.cmpNode(x, score, elem) == 0 {
+			return rank
+		}
+	}
+
+	return 0
+}
+
+// 根据排名(从1开始)返回节点, 对应redis的zslGetElementByRank
+func (s *SkipListFunc[K, T]) getByRank(rank int) *Node[K, T] {
+	if rank <= 0 || rank > s.length {
+		return nil
+	}
+
+	traversed := 0
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.NodeLevel[i].forward != nil && traversed+x.NodeLevel[i].span <= rank {
+			traversed += x.NodeLevel[i].span
+			x = x.NodeLevel[i].forward
+		}
+
+		if traversed == rank {
+			return x
+		}
+	}
+
+	return nil
+}
+
+// 把[start, end]转成合法的排名区间, 从0开始
+// start和end可以是负数, -1表示最后一个元素, -2表示倒数第二个, 以此类推
+func (s *SkipListFunc[K, T]) rankRange(start, end int) (int, int, bool) {
+	if start < 0 {
+		start = s.length + start
+	}
+	if end < 0 {
+		end = s.length + end
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	if start > end || start >= s.length {
+		return 0, 0, false
+	}
+
+	if end >= s.length {
+		end = s.length - 1
+	}
+	return start, end, true
+}
+
+// 返回score的排名, 从0开始, O(log n)
+func (s *SkipListFunc[K, T]) Rank(score K) (rank int, ok bool) {
+	var elem T
+	rank = s.getRank(score, elem)
+	if rank == 0 {
+		return
+	}
+	return rank - 1, true
+}
+
+// 根据排名获取元素, 排名从0开始, O(log n)
+func (s *SkipListFunc[K, T]) GetByRank(rank int) (score K, elem T, ok bool) {
+	x := s.getByRank(rank + 1)
+	if x == nil {
+		return
+	}
+	return x.score, x.elem, true
+}
+
+// 升序遍历排名在[start, end]的元素, start和end可以是负数
+// 定位start是O(log n)
+func (s *SkipListFunc[K, T]) RangeByRank(start, end int, callback func(score K, v T) bool) {
+	start, end, ok := s.rankRange(start, end)
+	if !ok {
+		return
+	}
+
+	x := s.getByRank(start + 1)
+	for n := end - start + 1; x != nil && n > 0; n-- {
+		if !callback(x.score, x.elem) {
+			return
+		}
+		x = x.NodeLevel[0].forward
+	}
+}
+
+// 删除排名在[start, end]的元素, start和end可以是负数
+// 返回删除元素的个数
+func (s *SkipListFunc[K, T]) DeleteRangeByRank(start, end int) (removed int) {
+	return s.deleteRangeByRank(start, end, nil)
+}
+
+// 对应redis的zslDeleteRangeByRank, 每删除一个节点回调一次
+func (s *SkipListFunc[K, T]) deleteRangeByRank(start, end int, callback func(x *Node[K, T])) (removed int) {
+	start, end, ok := s.rankRange(start, end)
+	if !ok {
+		return
+	}
+
+	var update [SKIPLIST_MAXLEVEL]*Node[K, T]
+	traversed := 0
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.NodeLevel[i].forward != nil && traversed+x.NodeLevel[i].span <= start {
+			traversed += x.NodeLevel[i].span
+			x = x.NodeLevel[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.NodeLevel[0].forward
+	for n := end - start + 1; x != nil && n > 0; n-- {
+		next := x.NodeLevel[0].forward
+		s.removeNode(x, update[:])
+		if callback != nil {
+			callback(x)
+		}
+		removed++
+		x = next
+	}
+	return
+}
+
 func (s *SkipList[K, T]) Draw() *SkipList[K, T] {
+	s.SkipListFunc.Draw()
+	return s
+}
+
+func (s *SkipListFunc[K, T]) Draw() *SkipListFunc[K, T] {
 	if s.head == nil {
 		return s
 	}
@@ -377,7 +681,7 @@ func (s *SkipList[K, T]) Draw() *SkipList[K, T] {
 }
 
 // 遍历
-func (s *SkipList[K, T]) Range(callback func(score K, v T) bool) {
+func (s *SkipListFunc[K, T]) Range(callback func(score K, v T) bool) {
 	if s.head == nil {
 		return
 	}
@@ -391,7 +695,7 @@ func (s *SkipList[K, T]) Range(callback func(score K, v T) bool) {
 }
 
 // 返回最小的n个值, 升序返回, 比如0,1,2,3
-func (s *SkipList[K, T]) TopMin(limit int, callback func(score K, v T) bool) {
+func (s *SkipListFunc[K, T]) TopMin(limit int, callback func(score K, v T) bool) {
 	s.Range(func(score K, v T) bool {
 		if limit <= 0 {
 			return false
@@ -403,12 +707,18 @@ func (s *SkipList[K, T]) TopMin(limit int, callback func(score K, v T) bool) {
 }
 
 // 返回长度
-func (s *SkipList[K, T]) Len() int {
+func (s *SkipListFunc[K, T]) Len() int {
 	return s.length
 }
 
 // 从后向前倒序遍历b tree
 func (s *SkipList[K, T]) RangePrev(callback func(k K, v T) bool) *SkipList[K, T] {
+	s.SkipListFunc.RangePrev(callback)
+	return s
+}
+
+// 从后向前倒序遍历b tree
+func (s *SkipListFunc[K, T]) RangePrev(callback func(k K, v T) bool) *SkipListFunc[K, T] {
 	// 遍历
 	if s.tail == nil {
 		return s
@@ -424,7 +734,7 @@ func (s *SkipList[K, T]) RangePrev(callback func(k K, v T) bool) *SkipList[K, T]
 }
 
 // 返回最大的n个值, 降序返回, 10, 9, 8, 7
-func (s *SkipList[K, T]) TopMax(limit int, callback func(k K, v T) bool) {
+func (s *SkipListFunc[K, T]) TopMax(limit int, callback func(k K, v T) bool) {
 	s.RangePrev(func(k K, v T) bool {
 		if limit <= 0 {
 			return false
@@ -434,3 +744,160 @@ func (s *SkipList[K, T]) TopMax(limit int, callback func(k K, v T) bool) {
 		return true
 	})
 }
+
+// 返回小于等于score的最大元素
+func (s *SkipListFunc[K, T]) Floor(score K) (k K, elem T, ok bool) {
+	return s.lastInRange(score).result()
+}
+
+// 返回大于等于score的最小元素
+func (s *SkipListFunc[K, T]) Ceiling(score K) (k K, elem T, ok bool) {
+	return s.firstInRange(score).result()
+}
+
+// 返回小于score的最大元素
+func (s *SkipListFunc[K, T]) Lower(score K) (k K, elem T, ok bool) {
+	return s.lowerNode(score).result()
+}
+
+// 返回大于score的最小元素
+func (s *SkipListFunc[K, T]) Higher(score K) (k K, elem T, ok bool) {
+	return s.higherNode(score).result()
+}
+
+// 返回最后一个score < max的节点
+func (s *SkipListFunc[K, T]) lowerNode(max K) *Node[K, T] {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.NodeLevel[i].forward != nil && s.compareKey(x.NodeLevel[i].forward.score, max) < 0 {
+			x = x.NodeLevel[i].forward
+		}
+	}
+
+	if x == s.head {
+		return nil
+	}
+	return x
+}
+
+// 返回第一个score > min的节点
+func (s *SkipListFunc[K, T]) higherNode(min K) *Node[K, T] {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.NodeLevel[i].forward != nil && s.compareKey(x.NodeLevel[i].forward.score, min) <= 0 {
+			x = x.NodeLevel[i].forward
+		}
+	}
+
+	return x.NodeLevel[0].forward
+}
+
+// 返回第一个大于(score, elem)的节点, score相同时按elem比较, 给迭代器重新定位使用
+// 没有设置compareElem时和higherNode一样
+func (s *SkipListFunc[K, T]) afterNode(score K, elem T) *Node[K, T] {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.NodeLevel[i].forward != nil && s.cmpNode(x.NodeLevel[i].forward, score, elem) <= 0 {
+			x = x.NodeLevel[i].forward
+		}
+	}
+
+	return x.NodeLevel[0].forward
+}
+
+// 返回最后一个小于(score, elem)的节点, 没有设置compareElem时和lowerNode一样
+func (s *SkipListFunc[K, T]) beforeNode(score K, elem T) *Node[K, T] {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.NodeLevel[i].forward != nil && s.cmpNode(x.NodeLevel[i].forward, score, elem) < 0 {
+			x = x.NodeLevel[i].forward
+		}
+	}
+
+	if x == s.head {
+		return nil
+	}
+	return x
+}
+
+// 升序遍历lo到hi之间的元素, loInclusive和hiInclusive控制是否包含lo和hi
+// 定位lo是O(log n), callback 返回false就停止遍历
+func (s *SkipListFunc[K, T]) RangeBetween(lo, hi K, loInclusive, hiInclusive bool, callback func(score K, v T) bool) {
+	var x *Node[K, T]
+	if loInclusive {
+		x = s.firstInRange(lo)
+	} else {
+		x = s.higherNode(lo)
+	}
+
+	for ; x != nil; x = x.NodeLevel[0].forward {
+		if c := s.compareKey(x.score, hi); c > 0 || !hiInclusive && c == 0 {
+			return
+		}
+
+		if !callback(x.score, x.elem) {
+			return
+		}
+	}
+}
+
+// 从hi到lo降序遍历, loInclusive和hiInclusive控制是否包含lo和hi
+// 定位hi是O(log n), callback 返回false就停止遍历
+func (s *SkipListFunc[K, T]) RangePrevBetween(lo, hi K, loInclusive, hiInclusive bool, callback func(score K, v T) bool) {
+	var x *Node[K, T]
+	if hiInclusive {
+		x = s.lastInRange(hi)
+	} else {
+		x = s.lowerNode(hi)
+	}
+
+	for ; x != nil; x = x.backward {
+		if c := s.compareKey(x.score, lo); c < 0 || !loInclusive && c == 0 {
+			return
+		}
+
+		if !callback(x.score, x.elem) {
+			return
+		}
+	}
+}
+
+// 把节点转成返回值, 空节点ok为false
+func (x *Node[K, T]) result() (score K, elem T, ok bool) {
+	if x == nil {
+		return
+	}
+	return x.score, x.elem, true
+}
+
+// 返回key和value的迭代器, 可以配合for range使用
+func (s *SkipListFunc[K, T]) All() iter.Seq2[K, T] {
+	return func(yield func(K, T) bool) {
+		s.Range(yield)
+	}
+}
+
+// 返回key的迭代器
+func (s *SkipListFunc[K, T]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		s.Range(func(k K, _ T) bool {
+			return yield(k)
+		})
+	}
+}
+
+// 返回value的迭代器
+func (s *SkipListFunc[K, T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		s.Range(func(_ K, v T) bool {
+			return yield(v)
+		})
+	}
+}
+
+// 从大到小返回key和value的迭代器
+func (s *SkipListFunc[K, T]) Backward() iter.Seq2[K, T] {
+	return func(yield func(K, T) bool) {
+		s.RangePrev(yield)
+	}
+}
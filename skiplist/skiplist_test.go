@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/antlabs/gstl/cmp"
+	"github.com/antlabs/gstl/internal/sortedtest"
+	"github.com/antlabs/gstl/vec"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -257,3 +259,342 @@ func Test_Skiplist_TopMax(t *testing.T) {
 	}
 
 }
+
+// 测试Rank和GetByRank
+func Test_Skiplist_RankAndGetByRank(t *testing.T) {
+	sl := New[int, int]()
+	max := 1000
+	// 乱序插入
+	for i := 0; i < max; i++ {
+		k := (i * 7) % max
+		sl.Set(k*2, k)
+	}
+
+	for i := 0; i < max; i++ {
+		rank, ok := sl.Rank(i * 2)
+		assert.True(t, ok)
+		assert.Equal(t, rank, i)
+
+		score, v, ok := sl.GetByRank(i)
+		assert.True(t, ok)
+		assert.Equal(t, score, i*2)
+		assert.Equal(t, v, i)
+
+		_, ok = sl.Rank(i*2 + 1)
+		assert.False(t, ok)
+	}
+
+	_, _, ok := sl.GetByRank(max)
+	assert.False(t, ok)
+	_, _, ok = sl.GetByRank(-1)
+	assert.False(t, ok)
+}
+
+// 测试RangeByRank
+func Test_Skiplist_RangeByRank(t *testing.T) {
+	sl := New[int, int]()
+	for i := 0; i < 100; i++ {
+		sl.Set(i, i)
+	}
+
+	for _, tc := range []struct {
+		start, end int
+		need       []int
+	}{
+		{0, 2, []int{0, 1, 2}},
+		{50, 52, []int{50, 51, 52}},
+		{-3, -1, []int{97, 98, 99}},
+		{98, 1000, []int{98, 99}},
+		{3, 2, nil},
+		{100, 101, nil},
+	} {
+		var got []int
+		sl.RangeByRank(tc.start, tc.end, func(score int, v int) bool {
+			got = append(got, v)
+			return true
+		})
+		assert.Equal(t, got, tc.need, fmt.Sprintf("start:%d, end:%d", tc.start, tc.end))
+	}
+}
+
+// 测试DeleteRangeByRank
+func Test_Skiplist_DeleteRangeByRank(t *testing.T) {
+	sl := New[int, int]()
+	max := 100
+	for i := 0; i < max; i++ {
+		sl.Set(i, i)
+	}
+
+	assert.Equal(t, sl.DeleteRangeByRank(10, 19), 10)
+	assert.Equal(t, sl.Len(), max-10)
+	for i := 0; i < max; i++ {
+		_, ok := sl.GetWithBool(i)
+		assert.Equal(t, ok, i < 10 || i >= 20, fmt.Sprintf("index:%d", i))
+	}
+
+	// 删除后排名要连续
+	for i := 0; i < sl.Len(); i++ {
+		score, _, ok := sl.GetByRank(i)
+		assert.True(t, ok)
+		rank, ok := sl.Rank(score)
+		assert.True(t, ok)
+		assert.Equal(t, rank, i)
+	}
+
+	assert.Equal(t, sl.DeleteRangeByRank(-5, -1), 5)
+	assert.Equal(t, sl.Len(), max-15)
+	_, ok := sl.GetWithBool(99)
+	assert.False(t, ok)
+
+	assert.Equal(t, sl.DeleteRangeByRank(0, -1), max-15)
+	assert.Equal(t, sl.Len(), 0)
+}
+
+// 测试Floor, Ceiling, Lower, Higher
+func Test_Skiplist_FloorCeiling(t *testing.T) {
+	sortedtest.FloorCeiling(t, New[int, int])
+}
+
+// 随机插入删除之后, 迭代器和查询结果都和参考结果一样, 并且结构没有被破坏
+func Test_Skiplist_SetDeleteRandom(t *testing.T) {
+	sortedtest.SetDeleteRandom(t, New[int, int], func(s *SkipList[int, int]) {
+		checkLinks(t, &s.SkipListFunc)
+	})
+}
+
+// NewWithCompare创建的跳表, Set和Delete走的是另一份查找代码, 同样检查链接
+func Test_Skiplist_SetDeleteRandomFunc(t *testing.T) {
+	sortedtest.SetDeleteRandom(t, func() *SkipListFunc[int, int] {
+		return NewWithCompare[int, int](cmp.Compare[int])
+	}, func(s *SkipListFunc[int, int]) {
+		checkLinks(t, s)
+	})
+}
+
+// zset模式下score可以重复, score相同时按elem排序
+// score是0, 2, 4...每个score有3个elem
+func newDupScore(max int) *SkipList[int, int] {
+	s := New[int, int]()
+	s.compareElem = cmp.Compare[int]
+	for i := max*3 - 1; i >= 0; i-- {
+		s.Set(i/3*2, i)
+	}
+	return s
+}
+
+// score重复时, 区间包含score相同的所有元素, 按elem排序
+func Test_Skiplist_RangeBetweenDupScore(t *testing.T) {
+	max := 100
+	s := newDupScore(max)
+	for _, tc := range [][2]int{{-10, 1000}, {10, 20}, {11, 19}, {6, 6}, {7, 7}} {
+		for _, inc := range [][2]bool{{true, true}, {true, false}, {false, true}, {false, false}} {
+			lo, hi := tc[0], tc[1]
+			var need []int
+			for i := 0; i < max*3; i++ {
+				k := i / 3 * 2
+				if k < lo || !inc[0] && k == lo || k > hi || !inc[1] && k == hi {
+					continue
+				}
+				need = append(need, i)
+			}
+
+			msg := fmt.Sprintf("lo:%d, hi:%d, inclusive:%v", lo, hi, inc)
+			var got []int
+			s.RangeBetween(lo, hi, inc[0], inc[1], func(score, elem int) bool {
+				assert.Equal(t, score, elem/3*2, msg)
+				got = append(got, elem)
+				return true
+			})
+			assert.Equal(t, got, need, msg)
+
+			got = nil
+			s.RangePrevBetween(lo, hi, inc[0], inc[1], func(score, elem int) bool {
+				got = append(got, elem)
+				return true
+			})
+			assert.Equal(t, got, vec.New(need...).Rev().ToSlice(), msg)
+		}
+	}
+}
+
+// score重复时, 迭代器按(score, elem)的顺序访问, 修改之后也不会跳过score相同的元素
+func Test_Skiplist_IteratorDupScore(t *testing.T) {
+	max := 100
+	s := newDupScore(max)
+	it := s.Iterator()
+
+	// Seek定位到score相同的第一个元素
+	assert.True(t, it.Seek(11))
+	assert.Equal(t, it.Key(), 12)
+	assert.Equal(t, it.Value(), 18)
+
+	// 边遍历边删除score相同的第一个元素
+	var got []int
+	for ok := it.First(); ok; ok = it.Next() {
+		got = append(got, it.Value())
+		if it.Value()%3 == 0 {
+			assert.True(t, s.deleteElem(it.Key(), it.Value()))
+		}
+	}
+	assert.Equal(t, len(got), max*3)
+	for i, elem := range got {
+		assert.Equal(t, elem, i)
+	}
+	assert.Equal(t, s.Len(), max*2)
+	checkLinks(t, &s.SkipListFunc)
+
+	// 反向遍历, 边遍历边删除score相同的最后一个元素
+	got = got[:0]
+	for ok := it.Last(); ok; ok = it.Prev() {
+		got = append(got, it.Value())
+		if it.Value()%3 == 2 {
+			assert.True(t, s.deleteElem(it.Key(), it.Value()))
+		}
+	}
+	assert.Equal(t, len(got), max*2)
+	for i, elem := range got {
+		assert.Equal(t, elem, (max-1-i/2)*3+2-i%2)
+	}
+	assert.Equal(t, s.Len(), max)
+
+	// 插入一个score相同, 排在当前元素后面的元素, 也会被访问到
+	assert.True(t, it.Seek(10))
+	assert.Equal(t, it.Value(), 16)
+	s.Set(10, 17)
+	assert.True(t, it.Next())
+	assert.Equal(t, it.Key(), 10)
+	assert.Equal(t, it.Value(), 17)
+}
+
+// score重复时, All和Backward按(score, elem)的顺序返回所有元素
+func Test_Skiplist_IterDupScore(t *testing.T) {
+	max := 100
+	s := newDupScore(max)
+
+	need := 0
+	for score, elem := range s.All() {
+		assert.Equal(t, score, need/3*2)
+		assert.Equal(t, elem, need)
+		need++
+	}
+	assert.Equal(t, need, max*3)
+
+	for score, elem := range s.Backward() {
+		need--
+		assert.Equal(t, score, need/3*2)
+		assert.Equal(t, elem, need)
+	}
+	assert.Equal(t, need, 0)
+}
+
+// score重复时, Floor和Lower返回score相同的最后一个元素, Ceiling和Higher返回第一个
+func Test_Skiplist_FloorCeilingDupScore(t *testing.T) {
+	max := 100
+	s := newDupScore(max)
+	assert.Equal(t, s.Len(), max*3)
+	checkLinks(t, &s.SkipListFunc)
+
+	last := (max - 1) * 2
+	for k := -1; k <= last+1; k++ {
+		msg := fmt.Sprintf("k:%d", k)
+		// 小于等于k的最大score和大于等于k的最小score
+		floor, ceiling := k-k%2, k+k%2
+		if k < 0 {
+			floor, ceiling = -2, 0
+		}
+
+		score, elem, ok := s.Floor(k)
+		assert.Equal(t, ok, floor >= 0, msg)
+		if ok {
+			assert.Equal(t, score, floor, msg)
+			assert.Equal(t, elem, floor/2*3+2, msg)
+		}
+
+		score, elem, ok = s.Ceiling(k)
+		assert.Equal(t, ok, ceiling <= last, msg)
+		if ok {
+			assert.Equal(t, score, ceiling, msg)
+			assert.Equal(t, elem, ceiling/2*3, msg)
+		}
+
+		lower, higher := floor, ceiling
+		if k%2 == 0 {
+			lower, higher = k-2, k+2
+		}
+
+		score, elem, ok = s.Lower(k)
+		assert.Equal(t, ok, lower >= 0, msg)
+		if ok {
+			assert.Equal(t, score, lower, msg)
+			assert.Equal(t, elem, lower/2*3+2, msg)
+		}
+
+		score, elem, ok = s.Higher(k)
+		assert.Equal(t, ok, higher <= last, msg)
+		if ok {
+			assert.Equal(t, score, higher, msg)
+			assert.Equal(t, elem, higher/2*3, msg)
+		}
+	}
+}
+
+// 测试RangeBetween和RangePrevBetween
+func Test_Skiplist_RangeBetween(t *testing.T) {
+	sortedtest.RangeBetween(t, New[int, int])
+}
+
+// 测试迭代器
+func Test_Skiplist_Iterator(t *testing.T) {
+	sortedtest.Iterator(t, New[int, int])
+}
+
+// 迭代的过程中修改容器
+func Test_Skiplist_IteratorMutation(t *testing.T) {
+	sortedtest.IteratorMutation(t, New[int, int])
+}
+
+// 使用两个迭代器合并两个有序容器
+func Test_Skiplist_IteratorMerge(t *testing.T) {
+	sortedtest.IteratorMerge(t, New[int, int])
+}
+
+// 测试range over func
+func Test_Skiplist_Iter(t *testing.T) {
+	sortedtest.Iter(t, New[int, int])
+}
+
+// key不是Ordered类型, 使用自定义的比较函数
+func Test_Skiplist_NewWithCompare(t *testing.T) {
+	sortedtest.NewWithCompare(t, NewWithCompare[sortedtest.Point, int])
+}
+
+// 反过来的比较函数, 和Ordered版本的结果顺序相反
+func Test_Skiplist_NewWithCompareReverse(t *testing.T) {
+	sortedtest.NewWithCompareReverse(t, NewWithCompare[int, int])
+}
+
+// 检查skiplist的链接: 第0层是有序的, backward和tail正确, 每一层的span加起来等于节点的排名
+func checkLinks[K any, T any](t *testing.T, s *SkipListFunc[K, T]) {
+	rank := map[*Node[K, T]]int{}
+	var prev *Node[K, T]
+	n := 0
+	for x := s.head.NodeLevel[0].forward; x != nil; x = x.NodeLevel[0].forward {
+		n++
+		rank[x] = n
+		assert.Equal(t, x.backward, prev, fmt.Sprintf("rank:%d", n))
+		if prev != nil {
+			assert.Less(t, s.cmpNode(prev, x.score, x.elem), 0, fmt.Sprintf("rank:%d", n))
+		}
+		prev = x
+	}
+	assert.Equal(t, s.tail, prev)
+	assert.Equal(t, s.length, n)
+
+	for i := 0; i < s.level; i++ {
+		r := 0
+		for x := s.head; x.NodeLevel[i].forward != nil; x = x.NodeLevel[i].forward {
+			r += x.NodeLevel[i].span
+			assert.Equal(t, rank[x.NodeLevel[i].forward], r, fmt.Sprintf("level:%d", i))
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package skiplist
+
+// apache 2.0 antlabs
+
+// 参考文档如下
+// https://github.com/redis/redis/blob/unstable/src/t_zset.c
+// https://redis.io/commands/zcount/
+// zset由两部分组成, 和redis一样
+// 1. skiplist按(score, member)排序, 负责范围查询和排名
+// 2. hash表保存member->score, 负责O(1)查找member的score
+// 已经实现的命令(标了done的)和对应的方法
+// ZADD -->done, ZSet.ZAdd
+// ZCARD -->done, ZSet.ZCard
+// ZCOUNT -->done, ZSet.ZCount
+// ZDIFFSTORE
+// ZINCRBY -->done, ZSet.ZIncrBy
+// ZINTER
+// ZINTERCARD
+// ZINTERSTORE
+// ZLEXCOUNT
+// ZMPOP
+// ZMSCORE
+// ZPOPMAX -->done, ZSet.ZPopMax
+// ZPOPMIN -->done, ZSet.ZPopMin
+// ZRANDMEMBER
+// ZRANGE -->done, ZSet.ZRangeByRank
+// ZRANGEBYLEX
+// ZRANGEBYSCORE -->done, ZSet.ZRangeByScore
+// ZRANGESTORE
+// ZRANK -->done, ZSet.ZRank
+// ZREM -->done, ZSet.ZRem
+// ZREMRANGEBYLEX
+// ZREMRANGEBYRANK -->done, ZSet.ZRemRangeByRank
+// ZREMRANGEBYSCORE
+// ZREVRANGE
+// ZREVRANGEBYLEX
+// ZREVRANGEBYSCORE
+// ZREVRANK -->done, ZSet.ZRevRank
+// ZSCAN
+// ZSCORE -->done, ZSet.ZScore
+// ZUNION
+// ZUNIONSTORE
+import (
+	"github.com/antlabs/gstl/cmp"
+	"golang.org/x/exp/constraints"
+)
+
+// zset里面的一个成员
+type Z[M comparable, S constraints.Ordered] struct {
+	Member M
+	Score  S
+}
+
+// 有序集合, score可以重复, score相同时按member排序
+// member只要求comparable, 但是score相同时需要member的比较函数才能确定顺序,
+// member是Ordered类型时用NewZSet, 其它类型(比如struct)用NewZSetWithCompare
+// score是浮点数时不能是NaN, NaN和任何数都不能比较, 会破坏skiplist的顺序, ZAdd和ZIncrBy会拒绝NaN
+// 和SkipList一样, 必须使用NewZSet或者NewZSetWithCompare创建, 零值不能使用
+type ZSet[M comparable, S constraints.Ordered] struct {
+	dict map[M]S
+	zsl  *SkipList[S, M]
+}
+
+// 初始化zset, score相同时按member的自然顺序排序
+func NewZSet[M constraints.Ordered, S constraints.Ordered]() *ZSet[M, S] {
+	return NewZSetWithCompare[M, S](cmp.Compare[M])
+}
+
+// 使用自定义的member比较函数初始化zset, score相同时按compare排序
+// compare(a, b) a < b 返回负数, a == b 返回0, a > b 返回正数, 只有a == b时才能返回0
+func NewZSetWithCompare[M comparable, S constraints.Ordered](compare func(a, b M) int) *ZSet[M, S] {
+	zsl := New[S, M]()
+	zsl.compareElem = compare
+	return &ZSet[M, S]{
+		dict: make(map[M]S),
+		zsl:  zsl,
+	}
+}
+
+// 类似redis zadd命令
+// 新加的成员返回true, 如果成员已经存在, 只更新score, 返回false
+// score是NaN时什么也不做, 返回false
+func (z *ZSet[M, S]) ZAdd(score S, member M) (added bool) {
+	if isNaN(score) {
+		return false
+	}
+
+	old, ok := z.dict[member]
+	if ok {
+		if old != score {
+			z.zsl.deleteElem(old, member)
+			z.zsl.Insert(score, member)
+			z.dict[member] = score
+		}
+		return false
+	}
+
+	z.zsl.Insert(score, member)
+	z.dict[member] = score
+	return true
+}
+
+// 类似redis zincrby命令, 成员不存在时相当于ZAdd(increment, member)
+// 返回新的score, 新的score是NaN(比如+Inf加-Inf)时不修改zset, 返回NaN
+func (z *ZSet[M, S]) ZIncrBy(increment S, member M) (newScore S) {
+	old, ok := z.dict[member]
+	if !ok {
+		z.ZAdd(increment, member)
+		return increment
+	}
+
+	newScore = old + increment
+	z.ZAdd(newScore, member)
+	return newScore
+}
+
+// 只有浮点数的NaN不等于自己
+func isNaN[S constraints.Ordered](score S) bool {
+	return score != score
+}
+
+// 类似redis zscore命令
+func (z *ZSet[M, S]) ZScore(member M) (score S, ok bool) {
+	score, ok = z.dict[member]
+	return
+}
+
+// 类似redis zrem命令, 返回被删除成员的个数
+func (z *ZSet[M, S]) ZRem(members ...M) (removed int) {
+	for _, member := range members {
+		score, ok := z.dict[member]
+		if !ok {
+			continue
+		}
+
+		z.zsl.deleteElem(score, member)
+		delete(z.dict, member)
+		removed++
+	}
+	return
+}
+
+// 类似redis zrank命令, 返回升序排名, 从0开始
+func (z *ZSet[M, S]) ZRank(member M) (rank int, ok bool) {
+	score, ok := z.dict[member]
+	if !ok {
+		return
+	}
+
+	return z.zsl.getRank(score, member) - 1, true
+}
+
+// 类似redis zrevrank命令, 返回降序排名, 从0开始
+func (z *ZSet[M, S]) ZRevRank(member M) (rank int, ok bool) {
+	score, ok := z.dict[member]
+	if !ok {
+		return
+	}
+
+	return z.zsl.length - z.zsl.getRank(score, member), true
+}
+
+// 类似redis zrangebyscore命令, 升序返回min <= score <= max的成员
+// callback 返回false就停止遍历
+func (z *ZSet[M, S]) ZRangeByScore(min, max S, callback func(member M, score S) bool) {
+	for x := z.zsl.firstInRange(min); x != nil && z.zsl.compareKey(x.score, max) <= 0; x = x.NodeLevel[0].forward {
+		if !callback(x.elem, x.score) {
+			return
+		}
+	}
+}
+
+// 类似redis zrange命令, 升序返回排名在[start, stop]的成员
+// start和stop可以是负数, -1表示最后一个成员, -2表示倒数第二个, 以此类推
+func (z *ZSet[M, S]) ZRangeByRank(start, stop int, callback func(member M, score S) bool) {
+	z.zsl.RangeByRank(start, stop, func(score S, member M) bool {
+		return callback(member, score)
+	})
+}
+
+// 类似redis zremrangebyrank命令, 删除排名在[start, stop]的成员
+// 返回删除成员的个数
+func (z *ZSet[M, S]) ZRemRangeByRank(start, stop int) int {
+	return z.zsl.deleteRangeByRank(start, stop, func(x *Node[S, M]) {
+		delete(z.dict, x.elem)
+	})
+}
+
+// 类似redis zcount命令, 返回min <= score <= max的成员个数
+func (z *ZSet[M, S]) ZCount(min, max S) int {
+	first := z.zsl.firstInRange(min)
+	if first == nil || z.zsl.compareKey(first.score, max) > 0 {
+		return 0
+	}
+
+	last := z.zsl.lastInRange(max)
+	if last == nil {
+		return 0
+	}
+
+	return z.zsl.getRank(last.score, last.elem) - z.zsl.getRank(first.score, first.elem) + 1
+}
+
+// 类似redis zpopmin命令, 删除并返回score最小的count个成员
+func (z *ZSet[M, S]) ZPopMin(count int) (rv []Z[M, S]) {
+	for ; count > 0 && z.zsl.length > 0; count-- {
+		x := z.zsl.head.NodeLevel[0].forward
+		rv = append(rv, Z[M, S]{Member: x.elem, Score: x.score})
+		z.ZRem(x.elem)
+	}
+	return
+}
+
+// 类似redis zpopmax命令, 删除并返回score最大的count个成员
+func (z *ZSet[M, S]) ZPopMax(count int) (rv []Z[M, S]) {
+	for ; count > 0 && z.zsl.length > 0; count-- {
+		x := z.zsl.tail
+		rv = append(rv, Z[M, S]{Member: x.elem, Score: x.score})
+		z.ZRem(x.elem)
+	}
+	return
+}
+
+// 类似redis zcard命令, 返回成员个数
+func (z *ZSet[M, S]) ZCard() int {
+	return z.zsl.length
+}
+
+// 返回成员个数, ZCard的同义词
+func (z *ZSet[M, S]) Len() int {
+	return z.zsl.length
+}
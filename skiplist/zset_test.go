@@ -0,0 +1,243 @@
+package skiplist
+
+// apache 2.0 antlabs
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试ZAdd和ZScore, 相同的score可以保存多个成员
+func Test_ZSet_ZAddZScore(t *testing.T) {
+	z := NewZSet[string, int]()
+	assert.True(t, z.ZAdd(1, "a"))
+	assert.True(t, z.ZAdd(1, "b"))
+	assert.True(t, z.ZAdd(1, "c"))
+	assert.False(t, z.ZAdd(2, "a"))
+
+	assert.Equal(t, z.ZCard(), 3)
+
+	score, ok := z.ZScore("a")
+	assert.True(t, ok)
+	assert.Equal(t, score, 2)
+
+	_, ok = z.ZScore("d")
+	assert.False(t, ok)
+}
+
+// score相同时按member排序
+func Test_ZSet_SameScoreOrderByMember(t *testing.T) {
+	z := NewZSet[string, int]()
+	for _, m := range []string{"d", "b", "a", "c"} {
+		z.ZAdd(1, m)
+	}
+	z.ZAdd(0, "z")
+
+	var got []string
+	z.ZRangeByRank(0, -1, func(member string, score int) bool {
+		got = append(got, member)
+		return true
+	})
+	assert.Equal(t, got, []string{"z", "a", "b", "c", "d"})
+}
+
+func Test_ZSet_ZIncrBy(t *testing.T) {
+	z := NewZSet[string, float64]()
+	assert.Equal(t, z.ZIncrBy(1.5, "a"), 1.5)
+	assert.Equal(t, z.ZIncrBy(1.5, "a"), 3.0)
+	z.ZAdd(2, "b")
+
+	rank, ok := z.ZRank("a")
+	assert.True(t, ok)
+	assert.Equal(t, rank, 1)
+
+	z.ZIncrBy(-2, "a")
+	rank, ok = z.ZRank("a")
+	assert.True(t, ok)
+	assert.Equal(t, rank, 0)
+}
+
+// 测试NaN的score不会写进zset
+func Test_ZSet_NaN(t *testing.T) {
+	z := NewZSet[string, float64]()
+	assert.False(t, z.ZAdd(math.NaN(), "a"))
+	assert.Equal(t, z.Len(), 0)
+
+	z.ZAdd(math.Inf(1), "a")
+	z.ZAdd(1, "b")
+	assert.True(t, math.IsNaN(z.ZIncrBy(math.Inf(-1), "a")))
+	score, ok := z.ZScore("a")
+	assert.True(t, ok)
+	assert.Equal(t, score, math.Inf(1))
+
+	var got []string
+	z.ZRangeByScore(math.Inf(-1), math.Inf(1), func(member string, score float64) bool {
+		got = append(got, member)
+		return true
+	})
+	assert.Equal(t, got, []string{"b", "a"})
+}
+
+// 测试member是struct, 使用自定义的比较函数
+func Test_ZSet_NewZSetWithCompare(t *testing.T) {
+	type player struct {
+		server int
+		name   string
+	}
+
+	z := NewZSetWithCompare[player, int](func(a, b player) int {
+		if a.server != b.server {
+			return a.server - b.server
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	z.ZAdd(10, player{2, "a"})
+	z.ZAdd(10, player{1, "b"})
+	z.ZAdd(5, player{3, "c"})
+	assert.False(t, z.ZAdd(10, player{1, "b"}))
+
+	rank, ok := z.ZRank(player{1, "b"})
+	assert.True(t, ok)
+	assert.Equal(t, rank, 1)
+
+	assert.Equal(t, z.ZPopMax(3), []Z[player, int]{
+		{Member: player{2, "a"}, Score: 10},
+		{Member: player{1, "b"}, Score: 10},
+		{Member: player{3, "c"}, Score: 5},
+	})
+}
+
+func Test_ZSet_ZRem(t *testing.T) {
+	z := NewZSet[int, int]()
+	max := 100
+	for i := 0; i < max; i++ {
+		z.ZAdd(i%10, i)
+	}
+
+	assert.Equal(t, z.ZRem(1, 2, 3, 1000), 3)
+	assert.Equal(t, z.ZCard(), max-3)
+
+	_, ok := z.ZScore(1)
+	assert.False(t, ok)
+	_, ok = z.ZRank(1)
+	assert.False(t, ok)
+}
+
+func Test_ZSet_ZRankZRevRank(t *testing.T) {
+	z := NewZSet[string, int]()
+	max := 1000
+	for i := 0; i < max; i++ {
+		z.ZAdd(i, fmt.Sprint(i))
+	}
+
+	for i := 0; i < max; i++ {
+		rank, ok := z.ZRank(fmt.Sprint(i))
+		assert.True(t, ok)
+		assert.Equal(t, rank, i)
+
+		rank, ok = z.ZRevRank(fmt.Sprint(i))
+		assert.True(t, ok)
+		assert.Equal(t, rank, max-1-i)
+	}
+}
+
+func Test_ZSet_ZRangeByScore(t *testing.T) {
+	z := NewZSet[int, int]()
+	for i := 0; i < 100; i++ {
+		z.ZAdd(i/2, i)
+	}
+
+	var got []int
+	z.ZRangeByScore(10, 12, func(member int, score int) bool {
+		got = append(got, member)
+		return true
+	})
+	assert.Equal(t, got, []int{20, 21, 22, 23, 24, 25})
+
+	got = got[:0]
+	z.ZRangeByScore(10, 12, func(member int, score int) bool {
+		got = append(got, member)
+		return len(got) < 2
+	})
+	assert.Equal(t, got, []int{20, 21})
+
+	got = got[:0]
+	z.ZRangeByScore(100, 200, func(member int, score int) bool {
+		got = append(got, member)
+		return true
+	})
+	assert.Equal(t, len(got), 0)
+}
+
+func Test_ZSet_ZRangeByRank(t *testing.T) {
+	z := NewZSet[int, int]()
+	for i := 0; i < 10; i++ {
+		z.ZAdd(i, i)
+	}
+
+	for _, tc := range []struct {
+		start, stop int
+		need        []int
+	}{
+		{0, 2, []int{0, 1, 2}},
+		{-3, -1, []int{7, 8, 9}},
+		{8, 100, []int{8, 9}},
+		{-100, 1, []int{0, 1}},
+		{5, 4, nil},
+		{10, 11, nil},
+	} {
+		var got []int
+		z.ZRangeByRank(tc.start, tc.stop, func(member int, score int) bool {
+			got = append(got, member)
+			return true
+		})
+		assert.Equal(t, got, tc.need, fmt.Sprintf("start:%d, stop:%d", tc.start, tc.stop))
+	}
+}
+
+func Test_ZSet_ZCount(t *testing.T) {
+	z := NewZSet[int, int]()
+	for i := 0; i < 100; i++ {
+		z.ZAdd(i/2, i)
+	}
+
+	assert.Equal(t, z.ZCount(0, 49), 100)
+	assert.Equal(t, z.ZCount(10, 12), 6)
+	assert.Equal(t, z.ZCount(-10, 0), 2)
+	assert.Equal(t, z.ZCount(49, 100), 2)
+	assert.Equal(t, z.ZCount(50, 100), 0)
+	assert.Equal(t, z.ZCount(12, 10), 0)
+}
+
+func Test_ZSet_ZPopMinMax(t *testing.T) {
+	z := NewZSet[string, int]()
+	z.ZAdd(3, "c")
+	z.ZAdd(1, "a")
+	z.ZAdd(2, "b")
+	z.ZAdd(4, "d")
+
+	assert.Equal(t, z.ZPopMin(1), []Z[string, int]{{Member: "a", Score: 1}})
+	assert.Equal(t, z.ZPopMax(2), []Z[string, int]{{Member: "d", Score: 4}, {Member: "c", Score: 3}})
+	assert.Equal(t, z.ZPopMin(10), []Z[string, int]{{Member: "b", Score: 2}})
+	assert.Nil(t, z.ZPopMax(1))
+	assert.Equal(t, z.Len(), 0)
+}
+
+func Test_ZSet_ZRemRangeByRank(t *testing.T) {
+	z := NewZSet[int, int]()
+	for i := 0; i < 10; i++ {
+		z.ZAdd(i, i)
+	}
+
+	assert.Equal(t, z.ZRemRangeByRank(0, 2), 3)
+	assert.Equal(t, z.ZCard(), 7)
+	_, ok := z.ZScore(0)
+	assert.False(t, ok)
+
+	rank, ok := z.ZRank(3)
+	assert.True(t, ok)
+	assert.Equal(t, rank, 0)
+}
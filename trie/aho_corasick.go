@@ -0,0 +1,279 @@
+package trie
+
+// apache 2.0 antlabs
+// 参考资料
+// https://en.wikipedia.org/wiki/Aho%E2%80%93Corasick_algorithm
+// 在trie的基础上加上失败指针, 扫描一遍文本就可以找到所有的模式串
+// 1. 失败指针指向当前节点最长的(在trie里面存在的)真后缀
+// 2. 输出指针指向失败链上第一个有值的节点, 用来找到在同一个位置结束的短的模式串
+// 和Trie一样按rune转移状态
+import (
+	"iter"
+	"sync"
+
+	"github.com/antlabs/gstl/internal/label"
+)
+
+// 匹配模式
+type MatchMode int
+
+const (
+	// 返回所有的匹配, 匹配之间可以重叠
+	Overlapping MatchMode = iota
+	// 从左往右, 每次返回起点最靠左的匹配里面最长的一个, 匹配之间不重叠
+	LeftmostLongest
+)
+
+// 多模式匹配的自动机, 用Set加入模式串, 调用Build之后用FindAll查找
+// 空字符串不会当成模式串, Set会忽略它, 也不计入Len
+// 修改(Set, Delete)和查找不能同时进行, 没有修改的时候可以在多个goroutine里面同时调用FindAll
+type AhoCorasick[V any] struct {
+	trie   Trie[V]
+	mode   MatchMode
+	states []acState[V]
+	// 最长的模式串的rune个数
+	maxDepth int
+	// 保证只Build一次, 修改模式串之后重置
+	once sync.Once
+}
+
+type acEdge struct {
+	r    rune
+	next int
+}
+
+// 自动机的状态, 下标0是根节点
+type acState[V any] struct {
+	// 和trie一样按rune排序
+	edges []acEdge
+	fail  int
+	// 失败链上第一个有值的状态, -1表示没有
+	output int
+	// 从根节点到这个状态的rune个数
+	depth int
+	isSet bool
+	v     V
+}
+
+// 新建一个Aho-Corasick自动机
+func NewAhoCorasick[V any](mode MatchMode) *AhoCorasick[V] {
+	return &AhoCorasick[V]{mode: mode}
+}
+
+func (a *AhoCorasick[V]) Set(k string, v V) {
+	_, _ = a.SetWithPrev(k, v)
+}
+
+// 加入模式串, 修改之后需要重新Build, 空字符串会被忽略
+func (a *AhoCorasick[V]) SetWithPrev(k string, v V) (prev V, replaced bool) {
+	if k == "" {
+		return
+	}
+
+	a.once = sync.Once{}
+	return a.trie.SetWithPrev(k, v)
+}
+
+// 删除模式串, 修改之后需要重新Build
+func (a *AhoCorasick[V]) Delete(k string) {
+	a.once = sync.Once{}
+	a.trie.Delete(k)
+}
+
+// 获取模式串对应的值
+func (a *AhoCorasick[V]) Get(k string) (v V) {
+	return a.trie.Get(k)
+}
+
+// 获取模式串对应的值, found表示模式串是否存在
+func (a *AhoCorasick[V]) GetWithBool(k string) (v V, found bool) {
+	return a.trie.GetWithBool(k)
+}
+
+// 返回模式串的个数
+func (a *AhoCorasick[V]) Len() int {
+	return a.trie.Len()
+}
+
+// 按字典序遍历所有的模式串
+func (a *AhoCorasick[V]) Range(callback func(k string, v V) bool) {
+	a.trie.Range(callback)
+}
+
+// 返回模式串和值的迭代器
+func (a *AhoCorasick[V]) All() iter.Seq2[string, V] {
+	return a.trie.All()
+}
+
+// 按层遍历trie, 计算失败指针和输出指针, 没有修改过模式串时不会重复计算
+// 可以和FindAll同时调用
+func (a *AhoCorasick[V]) Build() {
+	a.once.Do(a.build)
+}
+
+func (a *AhoCorasick[V]) build() {
+	a.states = append(a.states[:0], acState[V]{output: -1})
+	a.maxDepth = 0
+
+	type pending struct {
+		n     *Trie[V]
+		state int
+	}
+	queue := []pending{{n: &a.trie, state: 0}}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		for _, c := range p.n.children {
+			idx := len(a.states)
+			depth := a.states[p.state].depth + 1
+			a.maxDepth = max(a.maxDepth, depth)
+			a.states = append(a.states, acState[V]{depth: depth, isSet: c.n.isSet, v: c.n.v})
+			a.states[p.state].edges = append(a.states[p.state].edges, acEdge{r: c.r, next: idx})
+
+			// 父节点的失败指针已经计算好了, 沿着失败链找到有r转移的状态
+			fail := 0
+			if p.state != 0 {
+				f := a.states[p.state].fail
+				for {
+					if next, ok := a.next(f, c.r); ok {
+						fail = next
+						break
+					}
+					if f == 0 {
+						break
+					}
+					f = a.states[f].fail
+				}
+			}
+
+			s := &a.states[idx]
+			s.fail = fail
+			s.output = a.states[fail].output
+			if a.states[fail].isSet {
+				s.output = fail
+			}
+			queue = append(queue, pending{n: c.n, state: idx})
+		}
+	}
+}
+
+// 状态s读入r之后的状态, 没有转移返回false
+func (a *AhoCorasick[V]) next(s int, r rune) (int, bool) {
+	edges := a.states[s].edges
+	lo, hi := 0, len(edges)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if edges[mid].r < r {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if lo < len(edges) && edges[lo].r == r {
+		return edges[lo].next, true
+	}
+	return 0, false
+}
+
+// 沿着失败链转移, 最多回到根节点
+func (a *AhoCorasick[V]) step(s int, r rune) int {
+	for {
+		if next, ok := a.next(s, r); ok {
+			return next
+		}
+		if s == 0 {
+			return 0
+		}
+		s = a.states[s].fail
+	}
+}
+
+// 在text里面查找所有的模式串, start和end是text里面的字节下标, text[start:end]就是匹配到的模式串
+// Overlapping模式按结束位置从小到大返回, 同一个位置结束的先返回长的
+// 没有调用Build时会先调用Build, callback 返回false就停止查找
+func (a *AhoCorasick[V]) FindAll(text string, callback func(start, end int, v V) bool) {
+	a.Build()
+
+	if a.mode == LeftmostLongest {
+		a.findLeftmostLongest(text, callback)
+		return
+	}
+
+	// 最近maxDepth个rune的起始下标, 用来计算匹配的起点
+	offsets := make([]int, a.maxDepth+1)
+	s := 0
+	for i, k := 0, 0; i < len(text); k++ {
+		offsets[k%len(offsets)] = i
+		r, size := label.Decode(text[i:])
+		i += size
+		s = a.step(s, r)
+
+		o := s
+		if !a.states[o].isSet {
+			o = a.states[o].output
+		}
+		for ; o > 0; o = a.states[o].output {
+			start := offsets[(k-a.states[o].depth+1)%len(offsets)]
+			if !callback(start, i, a.states[o].v) {
+				return
+			}
+		}
+	}
+}
+
+type acMatch[V any] struct {
+	start, end int
+	v          V
+}
+
+// 记录起点最靠左的匹配里面最长的一个, 后面不可能有起点更靠左的匹配时返回它, 再从它的结束位置重新开始
+func (a *AhoCorasick[V]) findLeftmostLongest(text string, callback func(start, end int, v V) bool) {
+	offsets := make([]int, a.maxDepth+1)
+	var cand *acMatch[V]
+	s := 0
+	for i, k := 0, 0; ; k++ {
+		if i >= len(text) {
+			if cand == nil {
+				return
+			}
+			if !callback(cand.start, cand.end, cand.v) {
+				return
+			}
+			i, s, cand = cand.end, 0, nil
+			continue
+		}
+
+		offsets[k%len(offsets)] = i
+		r, size := label.Decode(text[i:])
+		i += size
+		s = a.step(s, r)
+
+		o := s
+		if !a.states[o].isSet {
+			o = a.states[o].output
+		}
+		for ; o > 0; o = a.states[o].output {
+			start := offsets[(k-a.states[o].depth+1)%len(offsets)]
+			if cand == nil || start < cand.start || start == cand.start && i > cand.end {
+				cand = &acMatch[V]{start: start, end: i, v: a.states[o].v}
+			}
+		}
+
+		if cand == nil {
+			continue
+		}
+
+		// 后面的匹配的起点不会早于当前状态对应的起点
+		earliest := i
+		if d := a.states[s].depth; d > 0 {
+			earliest = offsets[(k-d+1)%len(offsets)]
+		}
+		if earliest > cand.start {
+			if !callback(cand.start, cand.end, cand.v) {
+				return
+			}
+			i, s, cand = cand.end, 0, nil
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package trie
+
+// apache 2.0 antlabs
+// 参考资料
+// http://stevehanov.ca/blog/?id=114
+// 从根节点往下走的时候维护编辑距离(Levenshtein)的一行, 相同前缀的key共用前面的计算
+// 一行里面的最小值超过maxDist时, 下面的子树都不可能满足, 直接跳过
+
+// 按字典序返回所有和query的编辑距离小于等于maxDist的key, 距离按rune计算
+// callback 返回false就停止搜索
+func (t *Trie[V]) FuzzySearch(query string, maxDist int, callback func(k string, v V, dist int) bool) {
+	if maxDist < 0 {
+		return
+	}
+
+	q := labels(query)
+	row := make([]int, len(q)+1)
+	for i := range row {
+		row[i] = i
+	}
+
+	if t.isSet && row[len(q)] <= maxDist && !callback("", t.v, row[len(q)]) {
+		return
+	}
+
+	f := fuzzy[V]{query: q, maxDist: maxDist, callback: callback}
+	for _, c := range t.children {
+		if !f.search(c.n, c.r, row, make([]rune, 0, len(q)+maxDist)) {
+			return
+		}
+	}
+}
+
+type fuzzy[V any] struct {
+	query    []rune
+	maxDist  int
+	callback func(k string, v V, dist int) bool
+	// 每一层的行, 下标是深度, 复用内存
+	rows [][]int
+}
+
+// 根据父节点的行prev计算节点n的行, 返回false表示停止搜索
+func (f *fuzzy[V]) search(n *Trie[V], r rune, prev []int, key []rune) bool {
+	key = append(key, r)
+	row := f.row(len(key))
+	row[0] = prev[0] + 1
+	minDist := row[0]
+	for j := 1; j < len(row); j++ {
+		cost := 1
+		if f.query[j-1] == r {
+			cost = 0
+		}
+		// 删除, 插入, 替换
+		row[j] = min(prev[j]+1, row[j-1]+1, prev[j-1]+cost)
+		minDist = min(minDist, row[j])
+	}
+
+	dist := row[len(f.query)]
+	if n.isSet && dist <= f.maxDist && !f.callback(keyString(key), n.v, dist) {
+		return false
+	}
+
+	if minDist > f.maxDist {
+		return true
+	}
+
+	for _, c := range n.children {
+		if !f.search(c.n, c.r, row, key) {
+			return false
+		}
+	}
+	return true
+}
+
+// 返回第depth层的行
+func (f *fuzzy[V]) row(depth int) []int {
+	for len(f.rows) < depth {
+		f.rows = append(f.rows, make([]int, len(f.query)+1))
+	}
+	return f.rows[depth-1]
+}
@@ -0,0 +1,41 @@
+package trie
+
+// apache 2.0 antlabs
+import "github.com/antlabs/gstl/internal/glob"
+
+// 按字典序返回所有匹配pattern的key, 和redis的KEYS命令一样支持?, *, [abc], [a-z], [^a-z]和\转义
+// 从根节点往下走的时候同时匹配模式, 不可能匹配的子树直接跳过, callback 返回false就停止遍历
+func (t *Trie[V]) Match(pattern string, callback func(k string, v V) bool) {
+	m := matcher[V]{pattern: glob.Compile(pattern), callback: callback}
+	m.match(t, m.pattern.Start(), make([]rune, 0, 16))
+}
+
+type matcher[V any] struct {
+	pattern  *glob.Pattern
+	callback func(k string, v V) bool
+	// 每一层的状态, 下标是深度, 复用内存
+	states []glob.State
+}
+
+func (m *matcher[V]) match(n *Trie[V], s glob.State, key []rune) bool {
+	if n.isSet && m.pattern.Accept(s) && !m.callback(keyString(key), n.v) {
+		return false
+	}
+
+	for len(m.states) <= len(key) {
+		m.states = append(m.states, m.pattern.NewState())
+	}
+
+	next := m.states[len(key)]
+	for _, c := range n.children {
+		next = m.pattern.Step(s, c.r, next)
+		if next.Empty() {
+			continue
+		}
+
+		if !m.match(c.n, next, append(key, c.r)) {
+			return false
+		}
+	}
+	return true
+}
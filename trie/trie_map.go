@@ -1,9 +1,10 @@
 package trie
 
 import (
-	"unicode/utf8"
+	"iter"
 
 	"github.com/antlabs/gstl/api"
+	"github.com/antlabs/gstl/internal/label"
 )
 
 // apache 2.0 antlabs
@@ -12,126 +13,295 @@ var _ api.Trie[int] = (*Trie[int])(nil)
 
 type Trie[V any] struct {
 	v V
-	// 这里也可以换成别的数据结构, btree, avltree, skiplist, slice(搜索就二分搜索，插入也是，并且维护有序)
-	// 压测下性能 TODO
-	children map[rune]*Trie[V]
+	// 子节点按rune从小到大排序, 查找用二分搜索, 遍历的时候就是字典序
+	children []child[V]
 	isSet    bool
-	length   int
+	// 以这个节点为前缀的key的个数(包含自己), 根节点就是元素个数
+	length int
+}
+
+type child[V any] struct {
+	r rune
+	n *Trie[V]
+}
+
+// 把k拆成label, 和[]rune(k)不同的是不合法的utf8字节各自是一个label
+func labels(k string) []rune {
+	rv := make([]rune, 0, len(k))
+	for i := 0; i < len(k); {
+		r, size := label.Decode(k[i:])
+		i += size
+		rv = append(rv, r)
+	}
+	return rv
+}
+
+// 用label还原key, 是labels的逆操作, 不合法的utf8字节还原成原来的字节
+func keyString(labels []rune) string {
+	key := make([]byte, 0, len(labels))
+	for _, r := range labels {
+		key = label.Append(key, r)
+	}
+	return string(key)
 }
 
 func New[V any]() *Trie[V] {
 	return &Trie[V]{}
 }
 
+// 二分搜索r, 找不到时返回插入的位置
+func (t *Trie[V]) search(r rune) (i int, found bool) {
+	lo, hi := 0, len(t.children)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if t.children[mid].r < r {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, lo < len(t.children) && t.children[lo].r == r
+}
+
+// 返回r对应的子节点
+func (t *Trie[V]) child(r rune) *Trie[V] {
+	i, found := t.search(r)
+	if !found {
+		return nil
+	}
+	return t.children[i].n
+}
+
+// 返回r对应的子节点, 没有就创建
+func (t *Trie[V]) childOrCreate(r rune) *Trie[V] {
+	i, found := t.search(r)
+	if found {
+		return t.children[i].n
+	}
+
+	c := &Trie[V]{}
+	t.children = append(t.children, child[V]{})
+	copy(t.children[i+1:], t.children[i:])
+	t.children[i] = child[V]{r: r, n: c}
+	return c
+}
+
+// 删除r对应的子节点
+func (t *Trie[V]) removeChild(r rune) {
+	if i, found := t.search(r); found {
+		t.children = append(t.children[:i], t.children[i+1:]...)
+	}
+}
+
+// 返回k对应的节点
+func (t *Trie[V]) find(k string) *Trie[V] {
+	n := t
+	for i := 0; i < len(k); {
+		r, size := label.Decode(k[i:])
+		i += size
+		n = n.child(r)
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
 func (t *Trie[V]) Set(k string, v V) {
 	_, _ = t.SetWithPrev(k, v)
 }
 
 func (t *Trie[V]) SetWithPrev(k string, v V) (prev V, replaced bool) {
-	n := t
-	for _, r := range k {
-		c := n.children[r]
-		if c == nil {
-			if n.children == nil {
-				n.children = map[rune]*Trie[V]{}
-			}
-			c = &Trie[V]{}
-			n.children[r] = c
-		}
+	if n := t.find(k); n != nil && n.isSet {
+		prev = n.v
+		n.v = v
+		return prev, true
+	}
 
-		n = c
+	// 新的key, 路径上每个节点的计数都加1
+	n := t
+	n.length++
+	for i := 0; i < len(k); {
+		r, size := label.Decode(k[i:])
+		i += size
+		n = n.childOrCreate(r)
+		n.length++
 	}
 
-	prev = n.v
 	n.v = v
-
-	replaced = n.isSet
-	if !replaced {
-		t.length++
-	}
 	n.isSet = true
 	return
 }
 
 func (t *Trie[V]) HasPrefix(k string) bool {
+	return t.find(k) != nil
+}
+
+func (t *Trie[V]) GetWithBool(k string) (v V, found bool) {
+	n := t.find(k)
+	if n == nil {
+		return
+	}
+	return n.v, n.isSet
+}
+
+func (t *Trie[V]) Get(k string) (v V) {
+	v, _ = t.GetWithBool(k)
+	return
+}
+
+// 删除的时候路径上每个节点的计数都减1, 计数为0的子树直接从父节点删除
+func (t *Trie[V]) Delete(k string) {
+	if n := t.find(k); n == nil || !n.isSet {
+		return
+	}
 
 	n := t
-	for _, r := range k {
-		n = n.children[r]
-		if n == nil {
-			return false
+	n.length--
+	for i := 0; i < len(k); {
+		r, size := label.Decode(k[i:])
+		i += size
+		c := n.child(r)
+		c.length--
+		if c.length == 0 {
+			n.removeChild(r)
+			return
 		}
+		n = c
 	}
 
+	var v V
+	n.v = v
+	n.isSet = false
+}
+
+func (t *Trie[V]) Len() int {
+	return t.length
+}
+
+// 遍历, 按rune的字典序, callback 返回false就停止遍历
+// key里面不是合法utf8的字节排在所有合法字符的后面
+func (t *Trie[V]) Range(callback func(k string, v V) bool) {
+	t.rangeInner(make([]rune, 0, 16), callback)
+}
+
+func (t *Trie[V]) rangeInner(prefix []rune, callback func(k string, v V) bool) bool {
+	if t.isSet && !callback(keyString(prefix), t.v) {
+		return false
+	}
+
+	for _, c := range t.children {
+		if !c.n.rangeInner(append(prefix, c.r), callback) {
+			return false
+		}
+	}
 	return true
 }
 
-func (t *Trie[V]) GetWithBool(k string) (v V, found bool) {
+// 按rune的字典序从大到小遍历, callback 返回false就停止遍历
+func (t *Trie[V]) RangePrev(callback func(k string, v V) bool) {
+	t.rangePrevInner(make([]rune, 0, 16), callback)
+}
 
-	n := t
-	for _, r := range k {
-		n = n.children[r]
-		if n == nil {
-			return
+// 先遍历子节点(从大到小), 再回调自己, 因为自己的key是所有子节点key的前缀
+func (t *Trie[V]) rangePrevInner(prefix []rune, callback func(k string, v V) bool) bool {
+	for i := len(t.children) - 1; i >= 0; i-- {
+		c := t.children[i]
+		if !c.n.rangePrevInner(append(prefix, c.r), callback) {
+			return false
 		}
 	}
-	return n.v, true && n.isSet
+
+	return !t.isSet || callback(keyString(prefix), t.v)
 }
 
-func (t *Trie[V]) Get(k string) (v V) {
-	v, _ = t.GetWithBool(k)
+// 按字典序遍历所有以prefix开头的key, callback 返回false就停止遍历
+func (t *Trie[V]) WalkPrefix(prefix string, callback func(k string, v V) bool) {
+	n := t.find(prefix)
+	if n == nil {
+		return
+	}
+	n.rangeInner(labels(prefix), callback)
+}
+
+// 按字典序返回以prefix开头的key, 最多返回limit个, limit小于等于0时返回全部
+func (t *Trie[V]) KeysWithPrefix(prefix string, limit int) (keys []string) {
+	t.WalkPrefix(prefix, func(k string, _ V) bool {
+		keys = append(keys, k)
+		return limit <= 0 || len(keys) < limit
+	})
 	return
 }
 
-func (t *Trie[V]) isLeaf() bool {
-	return len(t.children) == 0
+// 返回以prefix开头的key的个数, 时间复杂度是O(len(prefix))
+func (t *Trie[V]) CountPrefix(prefix string) int {
+	n := t.find(prefix)
+	if n == nil {
+		return 0
+	}
+	return n.length
 }
 
-// 记录删除的过程
-type recogNode[V any] struct {
-	r rune
-	n *Trie[V]
+// 返回key和value的迭代器, 可以配合for range使用
+func (t *Trie[V]) All() iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		t.Range(yield)
+	}
 }
 
-// 删除有两种方法, 这里先选择第1种，后面有时间再压测下第二种效率如何
-// 1.记录rune和节点，删除这个节点。如果是子节点，再回溯删除
-// 2.声明一个parent指针，不记录过程节点，直接p = n.parent; p != nil; p=p.parent 回溯删除
-func (t *Trie[V]) Delete(k string) {
-	recog := make([]recogNode[V], 0, utf8.RuneCountInString(k))
+// 返回key的迭代器
+func (t *Trie[V]) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		t.Range(func(k string, _ V) bool {
+			return yield(k)
+		})
+	}
+}
 
-	var v V
-	n := t
+// 返回value的迭代器
+func (t *Trie[V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		t.Range(func(_ string, v V) bool {
+			return yield(v)
+		})
+	}
+}
 
-	for _, r := range k {
-		recog = append(recog, recogNode[V]{r, n})
-		n = n.children[r]
-		if n == nil {
-			return
-		}
+// 从大到小返回key和value的迭代器
+func (t *Trie[V]) Backward() iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		t.RangePrev(yield)
 	}
+}
 
-	n.v = v
-	n.isSet = false
+// 返回是s前缀的key里面最长的一个
+// 比如树里面有/a, /a/b, LongestPrefixOf("/a/b/c")返回/a/b
+func (t *Trie[V]) LongestPrefixOf(s string) (key string, v V, ok bool) {
+	t.PrefixesOf(s, func(k string, val V) bool {
+		key, v, ok = k, val, true
+		return true
+	})
+	return
+}
 
-	n.length--
-	if !n.isLeaf() {
+// 从短到长遍历所有是s前缀的key, callback返回false就停止遍历
+// 只需要从根节点往下走一次, 不用对每个前缀调用GetWithBool
+func (t *Trie[V]) PrefixesOf(s string, callback func(k string, v V) bool) {
+	n := t
+	if n.isSet && !callback("", n.v) {
 		return
 	}
 
-	for last := len(recog) - 1; last >= 0; last-- {
-		p := recog[last].n
-		delete(p.children, recog[last].r)
-
-		if !p.isLeaf() {
+	for i := 0; i < len(s); {
+		r, size := label.Decode(s[i:])
+		i += size
+		n = n.child(r)
+		if n == nil {
 			return
 		}
 
-		if p.isSet {
+		if n.isSet && !callback(s[:i], n.v) {
 			return
 		}
 	}
 }
-
-func (t *Trie[V]) Len() int {
-	return t.length
-}
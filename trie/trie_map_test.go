@@ -2,8 +2,14 @@ package trie
 
 import (
 	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
 	"testing"
+	"unicode/utf8"
 
+	"github.com/antlabs/gstl/api"
+	"github.com/antlabs/gstl/internal/glob"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -150,3 +156,519 @@ func Test_TrieMap_Delete6(t *testing.T) {
 	assert.Equal(t, tm.Get("/1"), "/1")
 	assert.Equal(t, tm.Get("/13"), "/13")
 }
+
+// 测试Range和range over func
+func Test_TrieMap_Iter(t *testing.T) {
+	tm := New[int]()
+	keys := []string{"a", "ab", "abc", "b", "中文", "中"}
+	for i, k := range keys {
+		tm.Set(k, i)
+	}
+
+	all := map[string]int{}
+	for k, v := range tm.All() {
+		all[k] = v
+	}
+	assert.Equal(t, len(all), len(keys))
+	for i, k := range keys {
+		assert.Equal(t, all[k], i)
+	}
+
+	n := 0
+	for range tm.Keys() {
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	assert.Equal(t, n, 2)
+
+	sum := 0
+	for v := range tm.Values() {
+		sum += v
+	}
+	assert.Equal(t, sum, 0+1+2+3+4+5)
+
+	var back []string
+	for k := range tm.Backward() {
+		back = append(back, k)
+	}
+	assert.Equal(t, back, []string{"中文", "中", "b", "abc", "ab", "a"})
+
+	back = back[:0]
+	for k := range tm.Backward() {
+		if k == "b" {
+			break
+		}
+		back = append(back, k)
+	}
+	assert.Equal(t, back, []string{"中文", "中"})
+}
+
+// Backward的结果是All的倒序
+func Test_TrieMap_Backward(t *testing.T) {
+	tm := New[int]()
+	for i := 0; i < 1000; i++ {
+		tm.Set(fmt.Sprintf("/%d/%d", i%37, i), i)
+	}
+	tm.Set("", -1)
+
+	var forward, backward []string
+	for k := range tm.All() {
+		forward = append(forward, k)
+	}
+	for k, v := range tm.Backward() {
+		assert.Equal(t, tm.Get(k), v)
+		backward = append(backward, k)
+	}
+	assert.True(t, sort.StringsAreSorted(forward))
+	assert.Equal(t, len(backward), len(forward))
+	for i := range forward {
+		assert.Equal(t, backward[len(backward)-1-i], forward[i])
+	}
+}
+
+// 删除不存在的key, 长度不变
+func Test_TrieMap_DeleteLen(t *testing.T) {
+	tm := New[int]()
+	tm.Set("ab", 1)
+	tm.Set("abc", 2)
+	tm.Delete("a")
+	tm.Delete("abcd")
+	assert.Equal(t, tm.Len(), 2)
+
+	tm.Delete("abc")
+	tm.Delete("abc")
+	assert.Equal(t, tm.Len(), 1)
+	assert.False(t, tm.HasPrefix("abc"))
+	assert.True(t, tm.HasPrefix("ab"))
+
+	tm.Delete("ab")
+	assert.Equal(t, tm.Len(), 0)
+	assert.False(t, tm.HasPrefix("a"))
+	assert.Equal(t, len(tm.children), 0)
+}
+
+func Test_TrieMap_Prefix(t *testing.T) {
+	tm := New[int]()
+	keys := []string{"中文", "app", "apple", "b", "", "application", "apply", "ape", "中"}
+	for i, k := range keys {
+		tm.Set(k, i)
+	}
+
+	// 按rune的字典序返回
+	assert.Equal(t, tm.KeysWithPrefix("", 0), []string{"", "ape", "app", "apple", "application", "apply", "b", "中", "中文"})
+	assert.Equal(t, tm.KeysWithPrefix("app", 0), []string{"app", "apple", "application", "apply"})
+	assert.Equal(t, tm.KeysWithPrefix("app", 2), []string{"app", "apple"})
+	assert.Equal(t, tm.KeysWithPrefix("中", 0), []string{"中", "中文"})
+	assert.Nil(t, tm.KeysWithPrefix("x", 0))
+
+	assert.Equal(t, tm.CountPrefix(""), len(keys))
+	assert.Equal(t, tm.CountPrefix("ap"), 5)
+	assert.Equal(t, tm.CountPrefix("appl"), 3)
+	assert.Equal(t, tm.CountPrefix("中文"), 1)
+	assert.Equal(t, tm.CountPrefix("x"), 0)
+
+	got := map[string]int{}
+	tm.WalkPrefix("appl", func(k string, v int) bool {
+		got[k] = v
+		return true
+	})
+	assert.Equal(t, got, map[string]int{"apple": 2, "application": 5, "apply": 6})
+
+	// 重复设置和删除之后计数是对的
+	tm.Set("apple", 100)
+	tm.Delete("app")
+	tm.Delete("ap")
+	assert.Equal(t, tm.CountPrefix("ap"), 4)
+	assert.Equal(t, tm.CountPrefix("app"), 3)
+	assert.Equal(t, tm.Len(), len(keys)-1)
+}
+
+// key不是合法的utf8, 每个非法字节各自作为一个字符
+// Range, WalkPrefix, PrefixesOf和LongestPrefixOf返回的key都和Set的key一样
+func Test_TrieMap_InvalidUTF8(t *testing.T) {
+	tr := New[int]()
+	keys := []string{"\xff", "\xfe", "\xffa", "\xff\xfe", "\xe4\xb8", "\xe4\xb8a", "中", "中\xff",
+		"\ufffd", "a\x80\x81", "a\x80\x82", "a", "\x80"}
+	for i, k := range keys {
+		tr.Set(k, i)
+	}
+	assert.Equal(t, tr.Len(), len(keys))
+
+	for i, k := range keys {
+		assert.Equal(t, tr.Get(k), i, "key:%q", k)
+	}
+	_, ok := tr.GetWithBool("\xe4")
+	assert.False(t, ok)
+
+	got := make(map[string]int)
+	tr.Range(func(k string, v int) bool {
+		got[k] = v
+		return true
+	})
+	assert.Equal(t, len(got), len(keys))
+	for i, k := range keys {
+		assert.Equal(t, got[k], i, "key:%q", k)
+	}
+
+	assert.Equal(t, tr.KeysWithPrefix("\xff", 0), []string{"\xff", "\xffa", "\xff\xfe"})
+
+	var prefixes []string
+	tr.PrefixesOf("\xff\xfeb", func(k string, v int) bool {
+		assert.Equal(t, got[k], v, "key:%q", k)
+		prefixes = append(prefixes, k)
+		return true
+	})
+	assert.Equal(t, prefixes, []string{"\xff", "\xff\xfe"})
+
+	k, v, ok := tr.LongestPrefixOf("中\xff\xfe")
+	assert.True(t, ok)
+	assert.Equal(t, k, "中\xff")
+	assert.Equal(t, v, got[k])
+
+	for i, k := range keys {
+		tr.Delete(k)
+		assert.Equal(t, tr.Len(), len(keys)-i-1)
+		for _, k2 := range keys[i+1:] {
+			_, ok := tr.GetWithBool(k2)
+			assert.True(t, ok, "key:%q", k2)
+		}
+	}
+}
+
+func Test_TrieMap_PrefixesOf(t *testing.T) {
+	var tr api.Trie[int] = New[int]()
+	testPrefixesOf(t, tr)
+}
+
+// 和对每个前缀调用GetWithBool的结果一样
+func testPrefixesOf(t *testing.T, tr api.Trie[int]) {
+	keys := []string{"", "/a", "/a/b", "/a/b/cd", "中", "中文", "中文字"}
+	for i, k := range keys {
+		tr.SetWithPrev(k, i)
+	}
+
+	for _, s := range []string{"", "/", "/a", "/a/b/c", "/a/b/cd/e", "/ab", "中文", "中国", "x"} {
+		var need []string
+		for i := 0; i <= len(s); i++ {
+			if _, ok := tr.GetWithBool(s[:i]); ok {
+				need = append(need, s[:i])
+			}
+		}
+
+		var got []string
+		tr.PrefixesOf(s, func(k string, v int) bool {
+			assert.Equal(t, tr.Get(k), v)
+			got = append(got, k)
+			return true
+		})
+		assert.Equal(t, got, need, s)
+
+		k, v, ok := tr.LongestPrefixOf(s)
+		assert.True(t, ok)
+		assert.Equal(t, k, need[len(need)-1], s)
+		assert.Equal(t, v, tr.Get(k), s)
+	}
+
+	// 提前停止
+	n := 0
+	tr.PrefixesOf("/a/b/cd", func(string, int) bool {
+		n++
+		return n < 2
+	})
+	assert.Equal(t, n, 2)
+
+	tr.Delete("")
+	_, _, ok := tr.LongestPrefixOf("x")
+	assert.False(t, ok)
+}
+
+// 按rune计算的编辑距离, 用来验证FuzzySearch
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		row := make([]int, len(rb)+1)
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			row[j] = min(prev[j]+1, row[j-1]+1, prev[j-1]+cost)
+		}
+		prev = row
+	}
+	return prev[len(rb)]
+}
+
+func Test_TrieMap_FuzzySearch(t *testing.T) {
+	tm := New[int]()
+	keys := []string{"", "a", "book", "books", "boon", "cook", "cake", "look", "hello", "help", "中文", "中国", "文"}
+	for i, k := range keys {
+		tm.Set(k, i)
+	}
+
+	for _, query := range []string{"book", "bok", "helo", "中文", "", "zzzzzz"} {
+		for maxDist := 0; maxDist <= 3; maxDist++ {
+			need := map[string]int{}
+			for _, k := range keys {
+				if d := levenshtein(query, k); d <= maxDist {
+					need[k] = d
+				}
+			}
+
+			got := map[string]int{}
+			var order []string
+			tm.FuzzySearch(query, maxDist, func(k string, v int, dist int) bool {
+				assert.Equal(t, tm.Get(k), v)
+				got[k] = dist
+				order = append(order, k)
+				return true
+			})
+			assert.Equal(t, got, need, fmt.Sprintf("query:%s, maxDist:%d", query, maxDist))
+			assert.True(t, sort.StringsAreSorted(order))
+		}
+	}
+
+	var got []string
+	tm.FuzzySearch("book", 1, func(k string, _ int, _ int) bool {
+		got = append(got, k)
+		return len(got) < 2
+	})
+	assert.Equal(t, got, []string{"book", "books"})
+
+	tm.FuzzySearch("book", -1, func(string, int, int) bool {
+		t.Fatal("should not be called")
+		return true
+	})
+}
+
+func Test_TrieMap_Match(t *testing.T) {
+	tm := New[int]()
+	testMatch(t, tm, tm.Match)
+}
+
+// 和对每个key调用glob匹配的结果一样, 并且按字典序返回
+func testMatch(t *testing.T, tr api.Trie[int], match func(pattern string, callback func(k string, v int) bool)) {
+	keys := []string{"", "user:1:session", "user:2:session", "user:12:session", "user:1:profile",
+		"user:abc:session", "users", "hello", "hallo", "hxllo", "中文", "中国", "a*b"}
+	for i, k := range keys {
+		tr.SetWithPrev(k, i)
+	}
+
+	for _, pattern := range []string{"*", "", "user:*:session", "user:?:*", "user:[0-9]*:session",
+		"h[ae]llo", "h[^e]llo", "中?", "users*", "a\\*b", "x*", "*o"} {
+		var need []string
+		g := glob.Compile(pattern)
+		for _, k := range keys {
+			if g.Match(k) {
+				need = append(need, k)
+			}
+		}
+		sort.Strings(need)
+
+		var got []string
+		match(pattern, func(k string, v int) bool {
+			assert.Equal(t, tr.Get(k), v)
+			got = append(got, k)
+			return true
+		})
+		assert.Equal(t, got, need, pattern)
+	}
+
+	n := 0
+	match("user:*", func(string, int) bool {
+		n++
+		return false
+	})
+	assert.Equal(t, n, 1)
+}
+
+type acResult struct {
+	start, end int
+	v          int
+}
+
+// 暴力查找, 用来验证AhoCorasick
+func bruteFindAll(keys map[string]int, text string, mode MatchMode) (all []acResult) {
+	var starts []int
+	for i := range text {
+		starts = append(starts, i)
+	}
+
+	if mode == Overlapping {
+		for end := 1; end <= len(text); end++ {
+			// 同一个位置结束的先返回长的
+			for _, start := range starts {
+				if start >= end {
+					break
+				}
+				if v, ok := keys[text[start:end]]; ok && start < end {
+					all = append(all, acResult{start, end, v})
+				}
+			}
+		}
+		return
+	}
+
+	for i := 0; i < len(text); {
+		best := -1
+		for end := i + 1; end <= len(text); end++ {
+			if _, ok := keys[text[i:end]]; ok {
+				best = end
+			}
+		}
+		if best < 0 {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+		all = append(all, acResult{i, best, keys[text[i:best]]})
+		i = best
+	}
+	return
+}
+
+func Test_AhoCorasick(t *testing.T) {
+	keys := map[string]int{"he": 1, "she": 2, "his": 3, "hers": 4, "s": 5, "中文": 6, "文字": 7, "中": 8, "abcd": 9, "bc": 10}
+	for _, mode := range []MatchMode{Overlapping, LeftmostLongest} {
+		ac := NewAhoCorasick[int](mode)
+		for k, v := range keys {
+			ac.Set(k, v)
+		}
+		ac.Set("", 100)
+		ac.Build()
+
+		for _, text := range []string{"ushers", "ahishers", "中文字中文", "abcabcd", "", "xyz", "sss"} {
+			var got []acResult
+			ac.FindAll(text, func(start, end int, v int) bool {
+				got = append(got, acResult{start, end, v})
+				return true
+			})
+			assert.Equal(t, got, bruteFindAll(keys, text, mode), fmt.Sprintf("mode:%d, text:%s", mode, text))
+		}
+	}
+}
+
+func Test_AhoCorasick_Rebuild(t *testing.T) {
+	ac := NewAhoCorasick[string](LeftmostLongest)
+	ac.Set("error", "error")
+	ac.Set("err", "err")
+
+	var got []string
+	ac.FindAll("an error, err", func(start, end int, v string) bool {
+		got = append(got, v)
+		return true
+	})
+	assert.Equal(t, got, []string{"error", "err"})
+
+	// 修改之后FindAll会重新Build
+	ac.Delete("error")
+	ac.Set("timeout", "timeout")
+	got = got[:0]
+	ac.FindAll("an error, timeout", func(start, end int, v string) bool {
+		got = append(got, v)
+		return true
+	})
+	assert.Equal(t, got, []string{"err", "timeout"})
+
+	// 提前停止
+	n := 0
+	ac.FindAll("err err err", func(int, int, string) bool {
+		n++
+		return false
+	})
+	assert.Equal(t, n, 1)
+}
+
+// 空字符串不是模式串, Set忽略它, Len, Get和FindAll都看不到
+func Test_AhoCorasick_EmptyPattern(t *testing.T) {
+	ac := NewAhoCorasick[int](Overlapping)
+	ac.Set("", 1)
+	assert.Equal(t, ac.Len(), 0)
+	_, ok := ac.GetWithBool("")
+	assert.False(t, ok)
+
+	ac.Set("a", 2)
+	prev, replaced := ac.SetWithPrev("", 3)
+	assert.Equal(t, prev, 0)
+	assert.False(t, replaced)
+	assert.Equal(t, ac.Len(), 1)
+
+	var got []acResult
+	ac.FindAll("aba", func(start, end int, v int) bool {
+		got = append(got, acResult{start, end, v})
+		return true
+	})
+	assert.Equal(t, got, []acResult{{0, 1, 2}, {2, 3, 2}})
+
+	for k := range ac.All() {
+		assert.Equal(t, k, "a")
+	}
+}
+
+// 随机生成的模式串和文本, 和暴力查找的结果一样
+func Test_AhoCorasick_Random(t *testing.T) {
+	letters := []rune("ab中")
+	randStr := func(n int) string {
+		s := make([]rune, rand.IntN(n)+1)
+		for i := range s {
+			s[i] = letters[rand.IntN(len(letters))]
+		}
+		return string(s)
+	}
+
+	for round := 0; round < 50; round++ {
+		keys := map[string]int{}
+		for i := 0; i < 8; i++ {
+			keys[randStr(4)] = i
+		}
+		text := randStr(40)
+
+		for _, mode := range []MatchMode{Overlapping, LeftmostLongest} {
+			ac := NewAhoCorasick[int](mode)
+			for k, v := range keys {
+				ac.Set(k, v)
+			}
+
+			var got []acResult
+			ac.FindAll(text, func(start, end int, v int) bool {
+				got = append(got, acResult{start, end, v})
+				return true
+			})
+			assert.Equal(t, got, bruteFindAll(keys, text, mode), fmt.Sprintf("keys:%v, text:%s", keys, text))
+		}
+	}
+}
+
+// 没有Build的时候多个goroutine同时FindAll, 只会Build一次
+func Test_AhoCorasick_ConcurrentFindAll(t *testing.T) {
+	ac := NewAhoCorasick[int](Overlapping)
+	ac.Set("he", 1)
+	ac.Set("she", 2)
+	ac.Set("hers", 3)
+	assert.Equal(t, ac.Len(), 3)
+	assert.Equal(t, ac.Get("she"), 2)
+
+	var wg sync.WaitGroup
+	got := make([]int, 8)
+	for i := range got {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ac.FindAll("ushers", func(int, int, int) bool {
+				got[i]++
+				return true
+			})
+		}()
+	}
+	wg.Wait()
+	for _, n := range got {
+		assert.Equal(t, n, 3)
+	}
+}
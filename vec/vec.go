@@ -8,6 +8,7 @@ package vec
 import (
 	"errors"
 	"fmt"
+	"iter"
 
 	"github.com/antlabs/gstl/cmp"
 )
@@ -100,7 +101,8 @@ func (v *Vec[T]) PopFront() (e T, ok bool) {
 	return v.TakeFirst()
 }
 
-// 从尾巴弹出
+// 从尾巴弹出, v的长度减1, 弹出的位置会被清零, 方便gc
+// 长度小于容量的一半时会缩容
 func (v *Vec[T]) Pop() (e T, ok bool) {
 	l := v.Len()
 	if l == 0 {
@@ -109,15 +111,20 @@ func (v *Vec[T]) Pop() (e T, ok bool) {
 
 	slice := v.ToSlice()
 	e = slice[l-1]
-	v = New(slice[:l-1]...)
+	// 清掉引用, 方便gc
+	var zero T
+	slice[l-1] = zero
+	slice = slice[:l-1]
 
 	// 缩容
-	if v.Len()*2 < v.Cap() {
-		newSlice := make([]T, v.Len())
+	if len(slice)*2 < cap(slice) {
+		newSlice := make([]T, len(slice))
 		copy(newSlice, slice)
-		v = New(newSlice...)
+		slice = newSlice
 	}
 
+	// 修改的是v指向的值, 调用方才能看到弹出后的长度
+	*v = Vec[T](slice)
 	return e, true
 }
 
@@ -517,3 +524,33 @@ func (v *Vec[T]) Range(callback func(index int, v T) bool) *Vec[T] {
 func getCap(l int) int {
 	return int(float64(l) * coefficient)
 }
+
+// 返回索引和元素的迭代器, 可以配合for range使用
+func (v *Vec[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		v.Range(yield)
+	}
+}
+
+// 返回元素的迭代器
+func (v *Vec[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, val := range v.ToSlice() {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// 从后向前返回索引和元素的迭代器
+func (v *Vec[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		slice := v.ToSlice()
+		for i := len(slice) - 1; i >= 0; i-- {
+			if !yield(i, slice[i]) {
+				return
+			}
+		}
+	}
+}
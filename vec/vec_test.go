@@ -2,6 +2,7 @@ package vec
 
 // apache 2.0 antlabs
 import (
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,6 +15,8 @@ func Test_New_Push_Pop_Int(t *testing.T) {
 	v.Push(8)
 	n, _ := v.Pop()
 	assert.Equal(t, n, 8)
+	assert.Equal(t, v.Len(), 7)
+	assert.Equal(t, v.ToSlice(), []int{1, 2, 3, 4, 5, 6, 7})
 }
 
 // 每次push 1个, pop 1个, 测试string类型
@@ -23,6 +26,45 @@ func Test_New_Push_Pop_String(t *testing.T) {
 	v.Push("8")
 	n, _ := v.Pop()
 	assert.Equal(t, n, "8")
+	assert.Equal(t, v.Len(), 7)
+
+	for i := 7; i > 0; i-- {
+		n, ok := v.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, n, strconv.Itoa(i))
+	}
+	_, ok := v.Pop()
+	assert.False(t, ok)
+	assert.Equal(t, v.Len(), 0)
+}
+
+// Pop修改的是接收者, 弹出的位置清零, 长度小于容量的一半时缩容
+func Test_Pop_Receiver(t *testing.T) {
+	a, b := new(int), new(int)
+	v := New(a, b)
+	old := v.ToSlice()
+
+	e, ok := v.Pop()
+	assert.True(t, ok)
+	assert.Same(t, e, b)
+	assert.Equal(t, v.Len(), 1)
+	assert.Equal(t, v.ToSlice(), []*int{a})
+	// 没有缩容, 还是原来的底层数组, 弹出的位置已经清零
+	assert.Nil(t, old[1])
+
+	w := WithCapacity[int](8)
+	w.Push(1, 2, 3, 4, 5)
+	w.Pop()
+	w.Pop()
+	assert.Equal(t, w.ToSlice(), []int{1, 2, 3})
+	assert.Equal(t, w.Cap(), 3)
+
+	w.Pop()
+	w.Pop()
+	w.Pop()
+	_, ok = w.Pop()
+	assert.False(t, ok)
+	assert.Equal(t, w.Len(), 0)
 }
 
 // push一个slice, pop 1个, 测试string类型
@@ -272,3 +314,33 @@ func Test_Search(t *testing.T) {
 	assert.Equal(t, New(1, 2, 3, 4, 5, 6, 7).SearchFunc(func(e int) bool { return 2 <= e }), 1)
 	assert.Equal(t, New(1, 2, 3, 4, 5, 6, 7).SearchFunc(func(e int) bool { return 1 <= e }), 0)
 }
+
+// 测试range over func
+func Test_Vec_Iter(t *testing.T) {
+	v := New(1, 2, 3, 4, 5)
+
+	var idx, got []int
+	for i, e := range v.All() {
+		idx = append(idx, i)
+		got = append(got, e)
+	}
+	assert.Equal(t, idx, []int{0, 1, 2, 3, 4})
+	assert.Equal(t, got, []int{1, 2, 3, 4, 5})
+
+	got = got[:0]
+	for e := range v.Values() {
+		if e == 4 {
+			break
+		}
+		got = append(got, e)
+	}
+	assert.Equal(t, got, []int{1, 2, 3})
+
+	idx, got = idx[:0], got[:0]
+	for i, e := range v.Backward() {
+		idx = append(idx, i)
+		got = append(got, e)
+	}
+	assert.Equal(t, idx, []int{4, 3, 2, 1, 0})
+	assert.Equal(t, got, []int{5, 4, 3, 2, 1})
+}
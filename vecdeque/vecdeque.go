@@ -4,6 +4,7 @@ package vecdeque
 import (
 	"errors"
 	"fmt"
+	"iter"
 	"math"
 
 	"github.com/antlabs/gstl/cmp"
@@ -560,3 +561,36 @@ func (v *VecDeque[T]) MakeContiguous() []T {
 func (v *VecDeque[T]) BinarySearch() {
 
 }
+
+// 返回索引和元素的迭代器, 从front到back, 可以配合for range使用
+func (v *VecDeque[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i, l := 0, v.Len(); i < l; i++ {
+			if !yield(i, v.Get(uint(i))) {
+				return
+			}
+		}
+	}
+}
+
+// 返回元素的迭代器, 从front到back
+func (v *VecDeque[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i, l := 0, v.Len(); i < l; i++ {
+			if !yield(v.Get(uint(i))) {
+				return
+			}
+		}
+	}
+}
+
+// 从back到front返回索引和元素的迭代器
+func (v *VecDeque[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := v.Len() - 1; i >= 0; i-- {
+			if !yield(i, v.Get(uint(i))) {
+				return
+			}
+		}
+	}
+}
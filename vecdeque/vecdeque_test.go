@@ -1,7 +1,11 @@
 package vecdeque
 
 // apache 2.0 antlabs
-import "testing"
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
 
 func Test_PushBack(t *testing.T) {
 	v := New[int]()
@@ -21,3 +25,43 @@ func Test_PushBack(t *testing.T) {
 		got = append(got, v2)
 	}
 }
+
+// 测试range over func
+func Test_VecDeque_Iter(t *testing.T) {
+	v := New[int]()
+	// 让数据绕过环形缓冲区的尾巴
+	for i := 0; i < 5; i++ {
+		v.PushBack(i)
+		v.PopFront()
+	}
+	for i := 3; i <= 5; i++ {
+		v.PushBack(i)
+	}
+	v.PushFront(2)
+	v.PushFront(1)
+
+	var idx, got []int
+	for i, e := range v.All() {
+		idx = append(idx, i)
+		got = append(got, e)
+	}
+	assert.Equal(t, idx, []int{0, 1, 2, 3, 4})
+	assert.Equal(t, got, []int{1, 2, 3, 4, 5})
+
+	got = got[:0]
+	for e := range v.Values() {
+		if e == 4 {
+			break
+		}
+		got = append(got, e)
+	}
+	assert.Equal(t, got, []int{1, 2, 3})
+
+	idx, got = idx[:0], got[:0]
+	for i, e := range v.Backward() {
+		idx = append(idx, i)
+		got = append(got, e)
+	}
+	assert.Equal(t, idx, []int{4, 3, 2, 1, 0})
+	assert.Equal(t, got, []int{5, 4, 3, 2, 1})
+}